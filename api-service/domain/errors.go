@@ -0,0 +1,87 @@
+package domain
+
+import "errors"
+
+// Kinds of errors a storage returns. Specific errors wrap one of them, so callers can decide
+// how to answer with errors.Is(err, ErrConflict) without knowing every specific error.
+var (
+	// ErrNotFound is the kind of errors returned when the product or entity does not exist
+	ErrNotFound = NewError(nil, "not_found", "not found")
+	// ErrAlreadyExists is the kind of errors returned when a create hits an existing entity
+	ErrAlreadyExists = NewError(nil, "already_exists", "already exists")
+	// ErrConflict is the kind of errors returned when a write is not possible in the current state
+	ErrConflict = NewError(nil, "conflict", "conflict")
+	// ErrValidation is the kind of errors returned when the request itself is invalid
+	ErrValidation = NewError(nil, "bad_request", "invalid request")
+	// ErrUnavailable is the kind of errors returned when the backing store can not be reached
+	ErrUnavailable = NewError(nil, "service_unavailable", "unavailable")
+)
+
+// kindError is a specific error of one of the kinds above. Its code names it across the services,
+// an error without a code goes by the code of its kind.
+type kindError struct {
+	kind    error
+	code    string
+	message string
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// NewError returns an error with the code and message that errors.Is matches with the kind
+func NewError(kind error, code, message string) error {
+	return &kindError{kind: kind, code: code, message: message}
+}
+
+// ErrorCode returns the machine readable code of err, empty for errors outside the domain
+func ErrorCode(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case *kindError:
+			if e.code != "" {
+				return e.code
+			}
+		case *wrappedError:
+			return ErrorCode(e.kind)
+		}
+		err = errors.Unwrap(err)
+	}
+	return ""
+}
+
+// WrapError marks err as being of the kind, keeping err in the chain; nil stays nil
+func WrapError(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &wrappedError{kind: kind, err: err}
+}
+
+// wrappedError is an error of a kind that came from somewhere else, e.g. a database driver
+type wrappedError struct {
+	kind error
+	err  error
+}
+
+func (e *wrappedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+// ErrVersionMismatch is returned when a write expected a different version of the product than the stored one
+var ErrVersionMismatch = NewError(ErrConflict, "version_mismatch", "version mismatch")
+
+// ErrInsufficientStock is returned when a stock adjustment would take the stock below zero
+var ErrInsufficientStock = NewError(ErrConflict, "insufficient_stock", "insufficient stock")
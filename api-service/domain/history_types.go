@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+const (
+	// DefaultHistoryLimit is the number of revisions listed when the query names no limit
+	DefaultHistoryLimit = 100
+	// MaxHistoryLimit is the largest number of revisions listed at once
+	MaxHistoryLimit = 1000
+)
+
+// HistoryEvent tells what happened to a product in a revision
+type HistoryEvent string
+
+const (
+	HistoryCreated HistoryEvent = "created"
+	HistoryUpdated HistoryEvent = "updated"
+	HistoryDeleted HistoryEvent = "deleted"
+	// HistoryRestored records a product taken back out of the trash
+	HistoryRestored HistoryEvent = "restored"
+	// HistoryRenamed records a new name or manufacturer, which may have moved the product to another id
+	HistoryRenamed HistoryEvent = "renamed"
+)
+
+// ProductRevision is the state of a product right after it was created or changed.
+// A deleted revision holds the state the product was in when it was deleted.
+// Revisions are only ever appended, so they outlive the product itself.
+type ProductRevision struct {
+	ID         int64        `json:"id"`
+	ProductID  string       `json:"productId"`
+	Event      HistoryEvent `json:"event"`
+	Product    Product      `json:"product"`
+	RecordedAt time.Time    `json:"recordedAt"`
+}
+
+// ProductAsOf picks the state of a product at the given time out of its revisions, oldest first.
+// It returns false if the product did not exist then.
+func ProductAsOf(revisions []ProductRevision, at time.Time) (Product, bool) {
+	var current *ProductRevision
+	for i := range revisions {
+		if revisions[i].RecordedAt.After(at) {
+			break
+		}
+		current = &revisions[i]
+	}
+
+	if current == nil || current.Event == HistoryDeleted {
+		return Product{}, false
+	}
+	return current.Product, true
+}
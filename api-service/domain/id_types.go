@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Strategies products can be given their id by
+const (
+	// IDStrategySHA1 is the original sha1 over the name and manufacturer run together, see Product.GetHash
+	IDStrategySHA1 = "sha1"
+	// IDStrategySHA256 hashes the normalised name and manufacturer, each prefixed by its length
+	IDStrategySHA256 = "sha256"
+	// IDStrategyUUIDv7 gives every product a new time ordered UUID
+	IDStrategyUUIDv7 = "uuidv7"
+	// IDStrategyULID gives every product a new time ordered ULID
+	IDStrategyULID = "ulid"
+	// IDStrategySKU uses the SKU given by the caller
+	IDStrategySKU = "sku"
+
+	// DefaultIDStrategy keeps the ids products always had, the others are opted into with ID_STRATEGY
+	DefaultIDStrategy = IDStrategySHA1
+
+	// MaxSKULength is the length of the id column
+	MaxSKULength = 64
+)
+
+// ErrInvalidSKU is returned for a product whose SKU is malformed, or missing when the SKU is its id
+var ErrInvalidSKU = NewError(ErrValidation, "invalid_sku", fmt.Sprintf("sku must be 1 to %d letters, digits, dots, dashes or underscores", MaxSKULength))
+
+// IDGenerator assigns the id a product is created under
+type IDGenerator interface {
+	// NewID returns the id for a new product
+	NewID(p Product) (string, error)
+	// Derived reports whether the id only depends on the product,
+	// in which case the same product is always given the same id and it is known before the product is saved
+	Derived() bool
+	// PreviousIDs returns the ids the same product may already be stored under by an earlier strategy
+	PreviousIDs(p Product) []string
+	// RenamedID returns the id of the product stored under id once it is renamed to p,
+	// which is id itself unless the strategy derives it from the name and manufacturer
+	RenamedID(id string, p Product) (string, error)
+}
+
+// NewIDGenerator returns the generator of the named strategy
+func NewIDGenerator(strategy string) (IDGenerator, error) {
+	switch strategy {
+	case IDStrategySHA1:
+		return sha1IDs{}, nil
+	case IDStrategySHA256:
+		return sha256IDs{}, nil
+	case IDStrategyUUIDv7:
+		return uuidv7IDs{}, nil
+	case IDStrategyULID:
+		return ulidIDs{}, nil
+	case IDStrategySKU:
+		return skuIDs{}, nil
+	}
+	return nil, fmt.Errorf("unknown id strategy %q", strategy)
+}
+
+// KnownID returns the id the product will be saved under if ids can tell it before the save, empty otherwise
+func KnownID(ids IDGenerator, p Product) string {
+	if !ids.Derived() {
+		return ""
+	}
+	id, err := ids.NewID(p)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// ValidateSKU checks that sku is fit to be an id, an empty sku is only valid when it is not used as one
+func ValidateSKU(sku string) error {
+	if sku == "" || len(sku) > MaxSKULength {
+		return ErrInvalidSKU
+	}
+	for _, r := range sku {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return ErrInvalidSKU
+		}
+	}
+	return nil
+}
+
+type sha1IDs struct{}
+
+func (sha1IDs) NewID(p Product) (string, error) { return p.GetHash(), nil }
+func (sha1IDs) Derived() bool                   { return true }
+func (sha1IDs) PreviousIDs(Product) []string    { return nil }
+
+func (g sha1IDs) RenamedID(_ string, p Product) (string, error) { return g.NewID(p) }
+
+// sha256IDs keeps recognising products created under sha1,
+// so switching to it does not let a product already stored be created twice
+type sha256IDs struct{}
+
+func (sha256IDs) NewID(p Product) (string, error) {
+	h := sha256.New()
+	h.Write([]byte("v1"))
+	for _, field := range []string{p.Name, p.Manufacturer} {
+		field = normaliseIDField(field)
+		h.Write([]byte(strconv.Itoa(len(field)) + ":" + field))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (sha256IDs) Derived() bool { return true }
+
+func (sha256IDs) PreviousIDs(p Product) []string { return []string{p.GetHash()} }
+
+func (g sha256IDs) RenamedID(_ string, p Product) (string, error) { return g.NewID(p) }
+
+// normaliseIDField trims s, collapses its runs of white space and folds its case,
+// so that spellings told apart only by them identify the same product
+func normaliseIDField(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+type uuidv7IDs struct{}
+
+// NewID lays out the milliseconds since the epoch followed by random bits, as in RFC 9562
+func (uuidv7IDs) NewID(Product) (string, error) {
+	b, err := timeOrderedBytes()
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+func (uuidv7IDs) Derived() bool                { return false }
+func (uuidv7IDs) PreviousIDs(Product) []string { return nil }
+
+func (uuidv7IDs) RenamedID(id string, _ Product) (string, error) { return id, nil }
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulidIDs struct{}
+
+// NewID encodes the milliseconds since the epoch and 80 random bits as 26 base32 digits
+func (ulidIDs) NewID(Product) (string, error) {
+	b, err := timeOrderedBytes()
+	if err != nil {
+		return "", err
+	}
+
+	// 130 bits are encoded, the two leading ones are always zero
+	var id [26]byte
+	hi := uint64(binary.BigEndian.Uint16(b[0:2]))
+	mid := binary.BigEndian.Uint64(b[2:10])
+	lo := uint64(binary.BigEndian.Uint16(b[10:12]))<<32 | uint64(binary.BigEndian.Uint32(b[12:16]))
+	for i := 25; i >= 0; i-- {
+		id[i] = crockford[lo&0x1f]
+		lo = lo>>5 | (mid&0x1f)<<43
+		mid = mid>>5 | (hi&0x1f)<<59
+		hi >>= 5
+	}
+	return string(id[:]), nil
+}
+
+func (ulidIDs) Derived() bool                { return false }
+func (ulidIDs) PreviousIDs(Product) []string { return nil }
+
+func (ulidIDs) RenamedID(id string, _ Product) (string, error) { return id, nil }
+
+// timeOrderedBytes returns 16 bytes starting with the milliseconds since the epoch, the rest random
+func timeOrderedBytes() ([16]byte, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return b, err
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	return b, nil
+}
+
+type skuIDs struct{}
+
+func (skuIDs) NewID(p Product) (string, error) {
+	if err := ValidateSKU(p.SKU); err != nil {
+		return "", err
+	}
+	return p.SKU, nil
+}
+
+func (skuIDs) Derived() bool                { return true }
+func (skuIDs) PreviousIDs(Product) []string { return nil }
+
+func (skuIDs) RenamedID(id string, _ Product) (string, error) { return id, nil }
@@ -1,16 +1,134 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 //go:generate mockgen -source=interfaces.go -package=mocks -destination=../mocks/mock_interfaces.go
 
 // Queue represents a queue for passing batches of data to the store service
 type Queue interface {
 	Add(batch []Product) error
+	Update(batch []ProductDiff) error
+	Delete(ids []string) error
+	// Push queues a message, such as a batch of a job whose results the store service reports
+	Push(msg QueueMessage) error
+	// JobResults waits up to timeout for the store service to apply a batch of the job
+	// and returns the outcome of its items, or none if no batch was applied in time
+	JobResults(ctx context.Context, jobID string, timeout time.Duration) ([]BatchItemResult, error)
 }
 
-// Storage is used for storing objects
+// Storage is used for storing objects. Every call gives up once its ctx is done.
 type Storage interface {
-	Save(product Product) (string, bool, error)
-	Get(id string) (Product, bool, error)
-	Update(id string, diff ProductDiff) (bool, error)
-	Delete(id string) (bool, error)
+	Save(ctx context.Context, product Product) (string, bool, error)
+	Get(ctx context.Context, id string) (Product, bool, error)
+	// Update returns ErrVersionMismatch if diff.Version is set and differs from the stored version
+	// and ErrInsufficientStock if the new stock would leave DefaultWarehouse with less than it has reserved
+	Update(ctx context.Context, id string, diff ProductDiff) (bool, error)
+	// Delete moves the product to the trash and releases its pending reservations.
+	// Trashed products are left out of every other read and write until they are restored.
+	Delete(ctx context.Context, id string) (bool, error)
+	// DeleteIfVersion deletes the product only if it is at the given version,
+	// returning ErrVersionMismatch otherwise
+	DeleteIfVersion(ctx context.Context, id string, version int64) (bool, error)
+	// Rename changes the name or manufacturer of a product, moving it to the id derived from them.
+	// It returns ErrVersionMismatch if rename.Version is set and differs from the stored version
+	// and ErrIDTaken if the new id belongs to another product. Get, Update, Delete and DeleteIfVersion
+	// keep finding the product under the ids it had before.
+	Rename(ctx context.Context, rename ProductRename) (RenameResult, bool, error)
+	List(ctx context.Context, query ProductQuery) (ProductPage, error)
+	// Search matches text against the name, manufacturer and tags of every product,
+	// ignoring case and diacritics, and returns at most limit results best first
+	Search(ctx context.Context, text string, limit int) ([]SearchResult, error)
+	// AdjustStock atomically adds delta to the stock of the product and returns the updated product.
+	// It returns ErrInsufficientStock, leaving the product untouched, if the available stock
+	// of the warehouse would drop below zero and ErrUnknownWarehouse if the warehouse does not exist.
+	AdjustStock(ctx context.Context, adjustment StockAdjustment) (Product, bool, error)
+	// StockMovements returns at most limit entries of the product's stock ledger newest first,
+	// only those with the given reason if it is not empty
+	StockMovements(ctx context.Context, productID string, reason MovementReason, limit int) ([]StockMovement, bool, error)
+	// ReconcileStock compares the stock of the product with the sum of its ledger
+	ReconcileStock(ctx context.Context, productID string) (StockReconciliation, bool, error)
+	// History returns at most limit revisions of the product newest first, including those
+	// recorded before it was deleted. It returns false if the product never existed.
+	History(ctx context.Context, productID string, limit int) ([]ProductRevision, bool, error)
+	// GetAsOf returns the product as it was at the given time
+	GetAsOf(ctx context.Context, id string, at time.Time) (Product, bool, error)
+
+	// Trash lists the deleted products, most recently deleted first
+	Trash(ctx context.Context) ([]TrashedProduct, error)
+	// Restore takes the product out of the trash, returning false if it is not there
+	Restore(ctx context.Context, id string) (bool, error)
+	// Purge removes a product from the trash for good, along with its stock levels and ledger.
+	// It returns false if the product is not in the trash.
+	Purge(ctx context.Context, id string) (bool, error)
+	// PurgeTrash purges every product deleted before the given time and returns how many it purged
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+
+	// CreateWarehouse adds a warehouse, returning true if one with the same id already exists
+	CreateWarehouse(ctx context.Context, warehouse Warehouse) (bool, error)
+	GetWarehouse(ctx context.Context, id string) (Warehouse, bool, error)
+	ListWarehouses(ctx context.Context) ([]Warehouse, error)
+	// StockLevels returns the stock of the product in every warehouse holding or having held some
+	StockLevels(ctx context.Context, productID string) ([]StockLevel, bool, error)
+	// WarehouseStock returns the stock of every product the warehouse holds or has held
+	WarehouseStock(ctx context.Context, warehouseID string) ([]StockLevel, bool, error)
+	// TransferStock atomically moves available stock of the product between two warehouses.
+	// It returns ErrInsufficientStock if the source has too little available
+	// and ErrUnknownWarehouse if either warehouse does not exist.
+	TransferStock(ctx context.Context, transfer StockTransfer) ([]StockLevel, bool, error)
+
+	// Reserve holds the requested quantity of the product's available stock in the warehouse until
+	// the ttl of the request elapses. It returns ErrInsufficientStock if less than that is available
+	// and ErrUnknownWarehouse if the warehouse does not exist.
+	Reserve(ctx context.Context, request ReservationRequest) (Reservation, bool, error)
+	GetReservation(ctx context.Context, id string) (Reservation, bool, error)
+	// ConfirmReservation takes the reserved quantity out of the stock for good,
+	// ReleaseReservation hands it back. Both return ErrReservationClosed if the reservation is not pending.
+	ConfirmReservation(ctx context.Context, id string) (Reservation, bool, error)
+	ReleaseReservation(ctx context.Context, id string) (Reservation, bool, error)
+
+	// SchedulePrice schedules a change of the product's price, applied once its effective time has passed
+	SchedulePrice(ctx context.Context, request PriceChangeRequest) (PriceChange, bool, error)
+	GetPriceChange(ctx context.Context, id string) (PriceChange, bool, error)
+	// PriceSchedule returns every price change scheduled for the product, by effective time
+	PriceSchedule(ctx context.Context, productID string) ([]PriceChange, bool, error)
+	// CancelPriceChange drops a pending price change, returning ErrPriceChangeClosed if it is not pending
+	CancelPriceChange(ctx context.Context, id string) (PriceChange, bool, error)
+	// PriceHistory returns at most limit prices the product had newest first, however they were set.
+	// It returns false if the product never existed.
+	PriceHistory(ctx context.Context, productID string, limit int) ([]PricePoint, bool, error)
+}
+
+// BatchStorage is implemented by storages that can apply a whole batch in one call.
+// Results are returned in input order, with Index relative to the given slice.
+// With atomic set either every item is applied or none is.
+type BatchStorage interface {
+	SaveBatch(ctx context.Context, products []Product, atomic bool) ([]BatchItemResult, error)
+	GetBatch(ctx context.Context, ids []string) ([]BatchItemResult, error)
+	UpdateBatch(ctx context.Context, diffs []ProductDiff, atomic bool) ([]BatchItemResult, error)
+	DeleteBatch(ctx context.Context, ids []string, atomic bool) ([]BatchItemResult, error)
+	AdjustStockBatch(ctx context.Context, adjustments []StockAdjustment, atomic bool) ([]BatchItemResult, error)
+}
+
+// Transactional is implemented by storages that can apply several operations atomically
+type Transactional interface {
+	// Atomically runs fn against a view of the storage, keeping none of its changes if it returns an error
+	Atomically(fn func(tx Storage) error) error
+}
+
+// IdempotencyStore remembers the responses given to writes carrying an Idempotency-Key
+type IdempotencyStore interface {
+	// Begin claims key for the request with the given fingerprint for ttl. It returns nil once the key is
+	// claimed, and the request must then be finished with Complete or Release. For a key claimed before
+	// it returns the stored response, ErrIdempotencyKeyReused if it was claimed for another request
+	// and ErrIdempotencyKeyInProgress if that request has not been answered yet.
+	Begin(key, fingerprint string, ttl time.Duration) (*IdempotentResponse, error)
+	// Complete stores the response to the request the key was claimed for
+	Complete(key string, response IdempotentResponse) error
+	// Release gives up a claimed key without a response, so that the request can be retried
+	Release(key string) error
+	// ExpireIdempotencyKeys forgets keys claimed longer than their ttl ago and returns how many
+	ExpireIdempotencyKeys(now time.Time) (int, error)
 }
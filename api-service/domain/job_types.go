@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// JobStatus is the lifecycle state of a background batch job
+type JobStatus string
+
+const (
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// JobOperation is the batch operation a job performs
+type JobOperation string
+
+const (
+	JobOperationCreate JobOperation = "create"
+	JobOperationUpdate JobOperation = "update"
+	JobOperationDelete JobOperation = "delete"
+	JobOperationStock  JobOperation = "stock"
+)
+
+// BatchItemResult is the outcome of a single item of a batch.
+// Status holds the HTTP status code the item would have had as a single request.
+type BatchItemResult struct {
+	Index   int      `json:"index"`
+	ID      string   `json:"id,omitempty"`
+	Status  int      `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Product *Product `json:"product,omitempty"`
+}
+
+// Succeeded reports whether the item was applied
+func (r BatchItemResult) Succeeded() bool {
+	return r.Status < 400
+}
+
+// Job is the API representation of a background batch job
+type Job struct {
+	ID         string            `json:"id"`
+	Backend    string            `json:"backend"`
+	Operation  JobOperation      `json:"operation"`
+	Status     JobStatus         `json:"status"`
+	Total      int               `json:"total"`
+	Processed  int               `json:"processed"`
+	Succeeded  int               `json:"succeeded"`
+	Failed     int               `json:"failed"`
+	Results    []BatchItemResult `json:"results"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for a JSON Patch that cannot be applied to a product
+	ErrInvalidPatch = NewError(ErrValidation, "invalid_patch", "invalid patch")
+	// ErrPatchTestFailed is returned when a test operation of a JSON Patch does not hold
+	ErrPatchTestFailed = NewError(ErrConflict, "patch_test_failed", "patch test failed")
+)
+
+// PatchOperation is a single RFC 6902 JSON Patch operation.
+// Only /price, /stock, /tags and /tags/<index> are patchable, "-" appends to the tags.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies ops in order to product and returns the result.
+// Nothing is applied if any of the operations fails.
+func ApplyJSONPatch(product Product, ops []PatchOperation) (Product, error) {
+	product.Tags = append([]string(nil), product.Tags...)
+	for i, op := range ops {
+		if err := applyOperation(&product, op); err != nil {
+			return Product{}, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return product, nil
+}
+
+// PatchFrom returns a patch that sets every mutable field to the one of product
+func PatchFrom(product Product) ProductPatch {
+	return ProductPatch{
+		Price: Value(product.Price),
+		Stock: Value(product.Stock),
+		Tags:  Value(product.Tags),
+	}
+}
+
+func applyOperation(product *Product, op PatchOperation) error {
+	field, index, hasIndex := strings.Cut(strings.TrimPrefix(op.Path, "/"), "/")
+	if !strings.HasPrefix(op.Path, "/") || (hasIndex && field != "tags") {
+		return fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, op.Path)
+	}
+
+	switch field {
+	case "price":
+		return applyScalar(&product.Price, op)
+	case "stock":
+		return applyScalar(&product.Stock, op)
+	case "tags":
+		if hasIndex {
+			return applyTag(product, index, op)
+		}
+		return applyScalar(&product.Tags, op)
+	default:
+		return fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, op.Path)
+	}
+}
+
+// applyScalar applies op to a whole field, remove resets it to its zero value
+func applyScalar[T any](field *T, op PatchOperation) error {
+	switch op.Op {
+	case "add", "replace":
+		var value T
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return fmt.Errorf("%w: bad value for %s: %v", ErrInvalidPatch, op.Path, err)
+		}
+		*field = value
+	case "remove":
+		var zero T
+		*field = zero
+	case "test":
+		var value T
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return fmt.Errorf("%w: bad value for %s: %v", ErrInvalidPatch, op.Path, err)
+		}
+		if !reflect.DeepEqual(*field, value) {
+			return fmt.Errorf("%w: %s", ErrPatchTestFailed, op.Path)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported op %q", ErrInvalidPatch, op.Op)
+	}
+	return nil
+}
+
+// applyTag applies op to a single element of the tags
+func applyTag(product *Product, index string, op PatchOperation) error {
+	tags := product.Tags
+
+	position := len(tags)
+	if index != "-" {
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i > len(tags) {
+			return fmt.Errorf("%w: bad tag index %q", ErrInvalidPatch, index)
+		}
+		position = i
+	}
+	// only add may address the position past the last tag
+	if op.Op != "add" && position == len(tags) {
+		return fmt.Errorf("%w: bad tag index %q", ErrInvalidPatch, index)
+	}
+
+	var tag string
+	if op.Op != "remove" {
+		if err := json.Unmarshal(op.Value, &tag); err != nil {
+			return fmt.Errorf("%w: bad value for %s: %v", ErrInvalidPatch, op.Path, err)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		tags = append(tags, "")
+		copy(tags[position+1:], tags[position:])
+		tags[position] = tag
+	case "replace":
+		tags[position] = tag
+	case "remove":
+		tags = append(tags[:position], tags[position+1:]...)
+	case "test":
+		if tags[position] != tag {
+			return fmt.Errorf("%w: %s", ErrPatchTestFailed, op.Path)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported op %q", ErrInvalidPatch, op.Op)
+	}
+
+	product.Tags = tags
+	return nil
+}
@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency is the currency of a product created without one.
+// Products stored before prices carried a currency are priced in it, in bani.
+const DefaultCurrency = "RON"
+
+// ErrUnknownCurrency is returned for a currency code that is not a supported ISO 4217 currency
+var ErrUnknownCurrency = NewError(ErrValidation, "unknown_currency", "unknown currency")
+
+// ErrNoExchangeRate is returned when converting to or from a currency missing from the exchange rates
+var ErrNoExchangeRate = NewError(ErrValidation, "no_exchange_rate", "no exchange rate")
+
+// minorUnits holds the number of digits after the decimal point
+// of every ISO 4217 currency prices may be given in
+var minorUnits = map[string]int{
+	"AUD": 2, "BGN": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HUF": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3,
+	"MDL": 2, "NOK": 2, "PLN": 2, "RON": 2, "RSD": 2, "SEK": 2, "TND": 3, "TRY": 2,
+	"UAH": 2, "USD": 2,
+}
+
+// Money is an amount in the minor units of an ISO 4217 currency, 1550 RON is 15.50 lei
+type Money struct {
+	Amount   int    `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ValidateCurrency checks that code is a supported ISO 4217 currency, an empty code stands for DefaultCurrency
+func ValidateCurrency(code string) error {
+	if code == "" {
+		return nil
+	}
+	if _, ok := minorUnits[code]; !ok {
+		return fmt.Errorf("%w %q, currencies are upper case ISO 4217 codes", ErrUnknownCurrency, code)
+	}
+	return nil
+}
+
+// currencyOrDefault returns code, or DefaultCurrency if it is empty
+func currencyOrDefault(code string) string {
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// ExchangeRates converts money between currencies through Base.
+// Rates holds how many units of a currency one unit of Base is worth.
+type ExchangeRates struct {
+	Base  string
+	Rates map[string]*big.Rat
+}
+
+// ParseExchangeRates reads rates against base written as comma separated
+// currency=rate pairs, such as "EUR=0.2010,USD=0.2185"
+func ParseExchangeRates(base, spec string) (ExchangeRates, error) {
+	rates := ExchangeRates{Base: base, Rates: make(map[string]*big.Rat)}
+	if err := ValidateCurrency(base); err != nil {
+		return rates, err
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		code, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return rates, fmt.Errorf("exchange rate %q is not written as currency=rate", pair)
+		}
+		code = strings.TrimSpace(code)
+		if err := ValidateCurrency(code); err != nil {
+			return rates, err
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+		if !ok || rate.Sign() <= 0 {
+			return rates, fmt.Errorf("exchange rate of %s must be a positive number", code)
+		}
+		rates.Rates[code] = rate
+	}
+	return rates, nil
+}
+
+// rate returns how many units of currency one unit of the base is worth
+func (r ExchangeRates) rate(currency string) (*big.Rat, error) {
+	if currency == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+	rate, ok := r.Rates[currency]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoExchangeRate, currency)
+	}
+	return rate, nil
+}
+
+// Convert returns money in currency, rounded half away from zero to its minor units
+func (r ExchangeRates) Convert(money Money, currency string) (Money, error) {
+	from := currencyOrDefault(money.Currency)
+	if from == currency {
+		return Money{Amount: money.Amount, Currency: currency}, nil
+	}
+
+	fromRate, err := r.rate(from)
+	if err != nil {
+		return Money{}, err
+	}
+	toRate, err := r.rate(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// amount / 10^from digits / fromRate * toRate * 10^to digits
+	amount := new(big.Rat).SetInt64(int64(money.Amount))
+	amount.Mul(amount, toRate)
+	amount.Quo(amount, fromRate)
+	amount.Mul(amount, new(big.Rat).SetInt(pow10(minorUnits[currency])))
+	amount.Quo(amount, new(big.Rat).SetInt(pow10(minorUnits[from])))
+	return Money{Amount: round(amount), Currency: currency}, nil
+}
+
+// ConvertProduct returns the product with its price in currency
+func (r ExchangeRates) ConvertProduct(product Product, currency string) (Product, error) {
+	price, err := r.Convert(product.PriceMoney(), currency)
+	if err != nil {
+		return Product{}, err
+	}
+	product.Price, product.Currency = price.Amount, price.Currency
+	return product, nil
+}
+
+// ConvertPricePoint returns point with its price converted to currency
+func (r ExchangeRates) ConvertPricePoint(point PricePoint, currency string) (PricePoint, error) {
+	price, err := r.Convert(point.PriceMoney(), currency)
+	if err != nil {
+		return PricePoint{}, err
+	}
+	point.Price, point.Currency = price.Amount, price.Currency
+	return point, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// round rounds x half away from zero
+func round(x *big.Rat) int {
+	quotient, remainder := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if new(big.Int).Lsh(remainder.Abs(remainder), 1).Cmp(x.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(x.Num().Sign())))
+	}
+	return int(quotient.Int64())
+}
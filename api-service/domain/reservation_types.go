@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultReservationTTL is how long stock is held when the request names no ttl
+	DefaultReservationTTL = 5 * time.Minute
+	// MaxReservationTTL is the longest stock may be held by a single reservation
+	MaxReservationTTL = time.Hour
+)
+
+// ErrReservationClosed is returned when confirming or releasing a reservation that is no longer pending
+var ErrReservationClosed = NewError(ErrConflict, "reservation_closed", "reservation is no longer pending")
+
+// ReservationStatus is the lifecycle state of a stock reservation
+type ReservationStatus string
+
+const (
+	ReservationPending   ReservationStatus = "pending"
+	ReservationConfirmed ReservationStatus = "confirmed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds Quantity of a product's stock until it is confirmed, released or expires
+type Reservation struct {
+	ID        string            `json:"id"`
+	ProductID string            `json:"productId"`
+	Warehouse string            `json:"warehouse"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// ReservationRequest asks for Quantity of a product in Warehouse to be held for TTLSeconds.
+// Warehouse defaults to DefaultWarehouse.
+type ReservationRequest struct {
+	ProductID  string `json:"productId"`
+	Warehouse  string `json:"warehouse,omitempty"`
+	Quantity   int    `json:"quantity"`
+	TTLSeconds int    `json:"ttlSeconds"`
+}
+
+// Validate checks the request, returning a message fit for the caller
+func (r ReservationRequest) Validate() error {
+	switch {
+	case r.ProductID == "":
+		return errors.New("productId must be provided")
+	case r.Quantity < 1:
+		return errors.New("quantity must be a positive integer")
+	case r.TTLSeconds < 0 || r.TTL() > MaxReservationTTL:
+		return fmt.Errorf("ttlSeconds must be between 0 (the default of %d) and %d",
+			int(DefaultReservationTTL.Seconds()), int(MaxReservationTTL.Seconds()))
+	}
+	return nil
+}
+
+// TTL returns how long the reservation should hold the stock
+func (r ReservationRequest) TTL() time.Duration {
+	if r.TTLSeconds == 0 {
+		return DefaultReservationTTL
+	}
+	return time.Duration(r.TTLSeconds) * time.Second
+}
+
+// WarehouseID returns the warehouse the stock is held in
+func (r ReservationRequest) WarehouseID() string {
+	return warehouseOrDefault(r.Warehouse)
+}
+
+// NewReservationID returns a random identifier for a reservation
+func NewReservationID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read only fails if the OS entropy source is unavailable
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
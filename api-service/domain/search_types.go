@@ -0,0 +1,14 @@
+package domain
+
+const (
+	// DefaultSearchLimit is the number of results returned when the caller does not ask for a limit
+	DefaultSearchLimit = 20
+	// MaxSearchLimit caps the number of results a caller can ask for
+	MaxSearchLimit = 100
+)
+
+// SearchResult is a product matching a search, ranked by Score (higher is better)
+type SearchResult struct {
+	ListedProduct
+	Score float64 `json:"score"`
+}
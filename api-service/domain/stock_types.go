@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMovementLimit is the number of stock movements listed when the query names no limit
+	DefaultMovementLimit = 100
+	// MaxMovementLimit is the largest number of stock movements listed at once
+	MaxMovementLimit = 1000
+)
+
+// MovementReason tells why the stock of a product changed
+type MovementReason string
+
+const (
+	MovementSale       MovementReason = "sale"
+	MovementRestock    MovementReason = "restock"
+	MovementAdjustment MovementReason = "adjustment"
+	MovementReturn     MovementReason = "return"
+	// MovementTransfer records stock moving between warehouses and is never given by callers
+	MovementTransfer MovementReason = "transfer"
+	// MovementInitial records the stock a product was created with and is never given by callers
+	MovementInitial MovementReason = "initial"
+)
+
+// StockAdjustment adds Delta to the stock of the product with the given id in Warehouse,
+// a negative Delta takes stock away. Reason defaults to adjustment and Warehouse to DefaultWarehouse.
+type StockAdjustment struct {
+	ID        string         `json:"id"`
+	Delta     int            `json:"delta"`
+	Warehouse string         `json:"warehouse,omitempty"`
+	Reason    MovementReason `json:"reason,omitempty"`
+	Actor     string         `json:"actor,omitempty"`
+	Reference string         `json:"reference,omitempty"`
+}
+
+// Validate checks the reason of the adjustment, returning a message fit for the caller
+func (a StockAdjustment) Validate() error {
+	switch a.Reason {
+	case "", MovementSale, MovementRestock, MovementAdjustment, MovementReturn:
+		return nil
+	}
+	return fmt.Errorf("reason must be one of %s, %s, %s or %s", MovementSale, MovementRestock, MovementAdjustment, MovementReturn)
+}
+
+// WarehouseID returns the warehouse the adjustment applies to
+func (a StockAdjustment) WarehouseID() string {
+	return warehouseOrDefault(a.Warehouse)
+}
+
+// MovementReason returns the reason the adjustment is recorded with in the ledger
+func (a StockAdjustment) MovementReason() MovementReason {
+	if a.Reason == "" {
+		return MovementAdjustment
+	}
+	return a.Reason
+}
+
+// StockMovement is an entry of the stock ledger, every change to the stock of a product
+// is recorded as one. Summing the deltas of a product gives its current stock.
+type StockMovement struct {
+	ID        int64          `json:"id"`
+	ProductID string         `json:"productId"`
+	Warehouse string         `json:"warehouse"`
+	Delta     int            `json:"delta"`
+	Reason    MovementReason `json:"reason"`
+	Actor     string         `json:"actor,omitempty"`
+	Reference string         `json:"reference,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// StockReconciliation compares the stock of a product with the one derived from its ledger
+type StockReconciliation struct {
+	ProductID   string `json:"productId"`
+	Stock       int    `json:"stock"`
+	LedgerStock int    `json:"ledgerStock"`
+	Difference  int    `json:"difference"`
+	Consistent  bool   `json:"consistent"`
+}
+
+// NewStockReconciliation compares stock with the stock derived from the ledger
+func NewStockReconciliation(productID string, stock, ledgerStock int) StockReconciliation {
+	return StockReconciliation{
+		ProductID:   productID,
+		Stock:       stock,
+		LedgerStock: ledgerStock,
+		Difference:  stock - ledgerStock,
+		Consistent:  stock == ledgerStock,
+	}
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"exam-api/domain"
+	"exam-api/gateways/jobs"
+	"exam-api/gateways/pool"
 	"exam-api/gateways/remote"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 )
@@ -13,42 +16,149 @@ const (
 	redisRootPath  = "/redis"
 
 	productPath = "/product"
+	searchPath  = "/search"
+	stockPath   = "/stock"
+	ledgerPath  = "/movements"
+	jobsPath    = "/jobs"
+	metricsPath = "/metrics"
 
 	versionSingle = "/single"
 	versionBatch  = "/batch"
+
+	reservationsPath = "/reservations"
+	warehousesPath   = "/warehouses"
+	transferPath     = "/transfer"
+	historyPath      = "/history"
+	trashPath        = "/trash"
+	pricesPath       = "/prices"
+	renamePath       = "/rename"
 )
 
 type API struct {
 	storage domain.Storage
-	client  remote.Client
+	// client calls the store service for the http routes
+	client *remote.Client
+	queue  domain.Queue
+	jobs   *jobs.Manager
+	pool   *pool.Pool
+	// rates convert prices on reads asking for another currency
+	rates domain.ExchangeRates
+	// ids tells the id of a product before it is created, for batches that report it ahead of the save
+	ids domain.IDGenerator
+	// keys holds the responses to writes carrying an Idempotency-Key for keyTTL
+	keys   domain.IdempotencyStore
+	keyTTL time.Duration
+	// jobTimeout is how long a redis job waits for the store service to apply its items
+	jobTimeout time.Duration
 }
 
-func NewAPI(store domain.Storage) *API {
+func NewAPI(store domain.Storage, client *remote.Client, queue domain.Queue, workers *pool.Pool, rates domain.ExchangeRates, ids domain.IDGenerator,
+	keys domain.IdempotencyStore, keyTTL time.Duration, jobTimeout time.Duration) *API {
 	return &API{
-		storage: store,
+		storage:    store,
+		client:     client,
+		queue:      queue,
+		jobs:       jobs.NewManager(),
+		pool:       workers,
+		rates:      rates,
+		ids:        ids,
+		keys:       keys,
+		keyTTL:     keyTTL,
+		jobTimeout: jobTimeout,
 	}
 }
 
 func (api *API) RegisterRoutes(ws *restful.WebService) {
 	ws.Path("/store")
+	ws.Filter(api.idempotent)
 	ws.Route(ws.POST(memoryRootPath + productPath + versionSingle).To(api.createProductMemorySingle))
 	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle).To(api.getProductMemorySingle))
 	ws.Route(ws.PATCH(memoryRootPath + productPath + versionSingle).To(api.updateProductMemorySingle))
 	ws.Route(ws.DELETE(memoryRootPath + productPath + versionSingle).To(api.deleteProductMemorySingle))
+	ws.Route(ws.POST(memoryRootPath + productPath + versionSingle + renamePath).To(api.renameProductMemory))
+
+	ws.Route(ws.GET(memoryRootPath + productPath).To(api.listProductsMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + searchPath).To(api.searchProductsMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + trashPath).To(api.listTrashMemory))
+	ws.Route(ws.POST(memoryRootPath + productPath + trashPath + "/restore").To(api.restoreProductMemory))
+	ws.Route(ws.DELETE(memoryRootPath + productPath + trashPath).To(api.purgeTrashMemory))
 
 	ws.Route(ws.POST(memoryRootPath + productPath + versionBatch).To(api.createProductMemoryBatch))
 	ws.Route(ws.GET(memoryRootPath + productPath + versionBatch).To(api.getProductMemoryBatch))
 	ws.Route(ws.PATCH(memoryRootPath + productPath + versionBatch).To(api.updateProductMemoryBatch))
 	ws.Route(ws.DELETE(memoryRootPath + productPath + versionBatch).To(api.deleteProductMemoryBatch))
 
+	ws.Route(ws.POST(memoryRootPath + productPath + versionSingle + stockPath).To(api.adjustStockMemorySingle))
+	ws.Route(ws.POST(memoryRootPath + productPath + versionBatch + stockPath).To(api.adjustStockMemoryBatch))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + ledgerPath).To(api.listStockMovementsMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + stockPath + "/reconcile").To(api.reconcileStockMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + historyPath).To(api.listProductHistoryMemory))
+
+	ws.Route(ws.POST(memoryRootPath + reservationsPath).To(api.createReservationMemory))
+	ws.Route(ws.GET(memoryRootPath + reservationsPath + "/{id}").To(api.getReservationMemory))
+	ws.Route(ws.POST(memoryRootPath + reservationsPath + "/{id}/confirm").To(api.confirmReservationMemory))
+	ws.Route(ws.POST(memoryRootPath + reservationsPath + "/{id}/release").To(api.releaseReservationMemory))
+
+	ws.Route(ws.POST(memoryRootPath + pricesPath).To(api.schedulePriceMemory))
+	ws.Route(ws.GET(memoryRootPath + pricesPath + "/{id}").To(api.getPriceChangeMemory))
+	ws.Route(ws.POST(memoryRootPath + pricesPath + "/{id}/cancel").To(api.cancelPriceChangeMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + pricesPath).To(api.listPriceScheduleMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + pricesPath + historyPath).To(api.listPriceHistoryMemory))
+
+	ws.Route(ws.POST(memoryRootPath + warehousesPath).To(api.createWarehouseMemory))
+	ws.Route(ws.GET(memoryRootPath + warehousesPath).To(api.listWarehousesMemory))
+	ws.Route(ws.GET(memoryRootPath + warehousesPath + "/{id}").To(api.getWarehouseMemory))
+	ws.Route(ws.GET(memoryRootPath + warehousesPath + "/{id}" + stockPath).To(api.warehouseStockMemory))
+	ws.Route(ws.GET(memoryRootPath + productPath + versionSingle + warehousesPath).To(api.stockLevelsMemory))
+	ws.Route(ws.POST(memoryRootPath + productPath + versionSingle + transferPath).To(api.transferStockMemory))
+
 	ws.Route(ws.POST(httpRootPath + productPath + versionSingle).To(api.createProductHTTPSingle))
 	ws.Route(ws.GET(httpRootPath + productPath + versionSingle).To(api.getProductHTTPSingle))
 	ws.Route(ws.PATCH(httpRootPath + productPath + versionSingle).To(api.updateProductHTTPSingle))
 	ws.Route(ws.DELETE(httpRootPath + productPath + versionSingle).To(api.deleteProductHTTPSingle))
+	ws.Route(ws.POST(httpRootPath + productPath + versionSingle + renamePath).To(api.renameProductHTTP))
+
+	ws.Route(ws.GET(httpRootPath + productPath).To(api.listProductsHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + searchPath).To(api.searchProductsHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + trashPath).To(api.listTrashHTTP))
+	ws.Route(ws.POST(httpRootPath + productPath + trashPath + "/restore").To(api.restoreProductHTTP))
+	ws.Route(ws.DELETE(httpRootPath + productPath + trashPath).To(api.purgeTrashHTTP))
 
 	ws.Route(ws.POST(httpRootPath + productPath + versionBatch).To(api.createProductHTTPBatch))
 	ws.Route(ws.GET(httpRootPath + productPath + versionBatch).To(api.getProductHTTPBatch))
 	ws.Route(ws.PATCH(httpRootPath + productPath + versionBatch).To(api.updateProductHTTPBatch))
 	ws.Route(ws.DELETE(httpRootPath + productPath + versionBatch).To(api.deleteProductHTTPBatch))
 
+	ws.Route(ws.POST(httpRootPath + productPath + versionSingle + stockPath).To(api.adjustStockHTTPSingle))
+	ws.Route(ws.POST(httpRootPath + productPath + versionBatch + stockPath).To(api.adjustStockHTTPBatch))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + ledgerPath).To(api.listStockMovementsHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + stockPath + "/reconcile").To(api.reconcileStockHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + historyPath).To(api.listProductHistoryHTTP))
+
+	ws.Route(ws.POST(httpRootPath + reservationsPath).To(api.createReservationHTTP))
+	ws.Route(ws.GET(httpRootPath + reservationsPath + "/{id}").To(api.getReservationHTTP))
+	ws.Route(ws.POST(httpRootPath + reservationsPath + "/{id}/confirm").To(api.confirmReservationHTTP))
+	ws.Route(ws.POST(httpRootPath + reservationsPath + "/{id}/release").To(api.releaseReservationHTTP))
+
+	ws.Route(ws.POST(httpRootPath + pricesPath).To(api.schedulePriceHTTP))
+	ws.Route(ws.GET(httpRootPath + pricesPath + "/{id}").To(api.getPriceChangeHTTP))
+	ws.Route(ws.POST(httpRootPath + pricesPath + "/{id}/cancel").To(api.cancelPriceChangeHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + pricesPath).To(api.listPriceScheduleHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + pricesPath + historyPath).To(api.listPriceHistoryHTTP))
+
+	ws.Route(ws.POST(httpRootPath + warehousesPath).To(api.createWarehouseHTTP))
+	ws.Route(ws.GET(httpRootPath + warehousesPath).To(api.listWarehousesHTTP))
+	ws.Route(ws.GET(httpRootPath + warehousesPath + "/{id}").To(api.getWarehouseHTTP))
+	ws.Route(ws.GET(httpRootPath + warehousesPath + "/{id}" + stockPath).To(api.warehouseStockHTTP))
+	ws.Route(ws.GET(httpRootPath + productPath + versionSingle + warehousesPath).To(api.stockLevelsHTTP))
+	ws.Route(ws.POST(httpRootPath + productPath + versionSingle + transferPath).To(api.transferStockHTTP))
+
+	ws.Route(ws.POST(redisRootPath + productPath + versionBatch).To(api.createProductRedisBatch))
+	ws.Route(ws.PATCH(redisRootPath + productPath + versionBatch).To(api.updateProductRedisBatch))
+	ws.Route(ws.DELETE(redisRootPath + productPath + versionBatch).To(api.deleteProductRedisBatch))
+
+	ws.Route(ws.GET(jobsPath + "/{id}").To(api.getJob))
+	ws.Route(ws.DELETE(jobsPath + "/{id}").To(api.cancelJob))
+
+	ws.Route(ws.GET(metricsPath + "/pool").To(api.getPoolMetrics))
 }
@@ -0,0 +1,494 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"exam-api/gateways/jobs"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	memoryBackend = "memory"
+	httpBackend   = "http"
+	redisBackend  = "redis"
+
+	// redisChunkSize is the number of items pushed on the queue per message by async jobs
+	redisChunkSize = 100
+	// bulkChunkSize is the number of items sent per call to storages supporting bulk operations
+	bulkChunkSize = 500
+
+	// jobResultsWait bounds each wait for the results of a redis job so cancellation is noticed promptly
+	jobResultsWait = 5 * time.Second
+	// jobResultsRetryDelay is how long a redis job backs off after failing to read its results
+	jobResultsRetryDelay = time.Second
+)
+
+// errRolledBack aborts the transaction of an atomic batch in which an item failed
+var errRolledBack = errors.New("batch rolled back")
+
+// batchProcessor describes how to apply a batch, one item at a time or,
+// when chunk is set, bulkChunkSize items at a time
+type batchProcessor struct {
+	storage domain.Storage
+	item    func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult
+	chunk   func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error)
+	// atomic applies the whole batch in a single all-or-nothing call
+	atomic func(ctx context.Context) ([]domain.BatchItemResult, error)
+	// id and failure describe the items of a call that failed as a whole
+	id      func(i int) string
+	failure string
+}
+
+// supportsAtomic reports whether the batch can be applied all-or-nothing
+func (p batchProcessor) supportsAtomic() bool {
+	if p.atomic != nil {
+		return true
+	}
+	_, ok := p.storage.(domain.Transactional)
+	return ok
+}
+
+// failed reports every item in [start, end) as failed with the processor's failure message
+func (p batchProcessor) failed(start, end int) []domain.BatchItemResult {
+	results := make([]domain.BatchItemResult, 0, end-start)
+	for i := start; i < end; i++ {
+		results = append(results, domain.BatchItemResult{Index: i, ID: p.id(i), Status: http.StatusInternalServerError, Error: p.failure})
+	}
+	return results
+}
+
+// rejected reports every item in [start, end) as failed because the worker pool did not take it
+func (p batchProcessor) rejected(start, end int) []domain.BatchItemResult {
+	results := make([]domain.BatchItemResult, 0, end-start)
+	for i := start; i < end; i++ {
+		results = append(results, domain.BatchItemResult{Index: i, ID: p.id(i), Status: http.StatusServiceUnavailable, Error: "no worker available"})
+	}
+	return results
+}
+
+// isAsync reports whether the caller asked for the batch to run as a background job
+func isAsync(req *restful.Request) bool {
+	async, _ := strconv.ParseBool(req.QueryParameter("async"))
+	return async
+}
+
+// startJob runs the batch in the background and answers with the job that tracks it
+func (api *API) startJob(resp *restful.Response, backend string, operation domain.JobOperation, total int, run jobs.RunFunc) {
+	job := api.jobs.Start(backend, operation, total, run)
+	log.Infof("Started %s job %s for %d items on %s backend", operation, job.ID, total, backend)
+
+	resp.AddHeader("Location", "/store"+jobsPath+"/"+job.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusAccepted, job, restful.MIME_JSON)
+}
+
+// batchConcurrency reads the optional concurrency query parameter, which limits how many
+// items of a single batch are in flight at once. It defaults to and is capped at the pool size.
+// On a malformed value it writes a 400 response and returns false.
+func (api *API) batchConcurrency(req *restful.Request, resp *restful.Response) (int, bool) {
+	limit := api.pool.Workers()
+
+	param := req.QueryParameter("concurrency")
+	if param == "" {
+		return limit, true
+	}
+
+	concurrency, err := strconv.Atoi(param)
+	if err != nil || concurrency < 1 {
+		log.Infof("Invalid concurrency %q in request", param)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("concurrency must be a positive integer"))
+		return 0, false
+	}
+
+	if concurrency > limit {
+		concurrency = limit
+	}
+	return concurrency, true
+}
+
+// runTasks calls task for every index in [0, n) on the shared worker pool.
+// At most concurrency tasks of the batch are in flight at once.
+// Tasks that have not started when ctx is cancelled are skipped, and once the pool
+// turns a task down rejected is called for it and every task after it.
+func (api *API) runTasks(ctx context.Context, n, concurrency int, task func(i int), rejected func(i int)) {
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		i := i
+		err := api.pool.Submit(ctx, func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if ctx.Err() != nil {
+				return
+			}
+			task(i)
+		})
+		if err != nil {
+			<-sem
+			wg.Done()
+			if ctx.Err() != nil {
+				break
+			}
+			log.Errorf("Failed to submit batch tasks %d-%d, err=%v", i, n, err)
+			for ; i < n; i++ {
+				rejected(i)
+			}
+			break
+		}
+	}
+	wg.Wait()
+}
+
+// runBatch applies every item in [0, n) with p and reports each result
+func (api *API) runBatch(ctx context.Context, n, concurrency int, p batchProcessor, report func(domain.BatchItemResult)) {
+	if p.chunk == nil {
+		api.runTasks(ctx, n, concurrency, func(i int) {
+			report(p.item(ctx, p.storage, i))
+		}, func(i int) {
+			report(p.rejected(i, i+1)[0])
+		})
+		return
+	}
+
+	bounds := func(c int) (int, int) {
+		start := c * bulkChunkSize
+		end := start + bulkChunkSize
+		if end > n {
+			end = n
+		}
+		return start, end
+	}
+
+	chunks := (n + bulkChunkSize - 1) / bulkChunkSize
+	api.runTasks(ctx, chunks, concurrency, func(c int) {
+		start, end := bounds(c)
+		results, err := p.chunk(ctx, start, end)
+		if err == nil && len(results) != end-start {
+			err = fmt.Errorf("got %d results for %d items", len(results), end-start)
+		}
+		if err != nil {
+			log.Errorf("Failed to apply items %d-%d, err=%v", start, end, err)
+			for _, result := range p.failed(start, end) {
+				report(result)
+			}
+			return
+		}
+
+		for _, result := range results {
+			result.Index += start
+			report(result)
+		}
+	}, func(c int) {
+		for _, result := range p.rejected(bounds(c)) {
+			report(result)
+		}
+	})
+}
+
+// collectBatch applies every item in [0, n) and returns the results ordered by index.
+// Items that have not started when ctx is cancelled are reported as failed.
+func (api *API) collectBatch(ctx context.Context, n, concurrency int, p batchProcessor) []domain.BatchItemResult {
+	results := cancelledResults(n)
+
+	// every index is written by exactly one task so no lock is needed
+	api.runBatch(ctx, n, concurrency, p, func(result domain.BatchItemResult) {
+		results[result.Index] = result
+	})
+	return results
+}
+
+// runAtomic applies the whole batch as a single all-or-nothing unit, through the storage's
+// atomic bulk call or inside a storage transaction. If any item fails none is applied
+// and the items that would have succeeded are reported as 424 Failed Dependency.
+// A batch cancelled with ctx before it is committed is not applied either.
+func (api *API) runAtomic(ctx context.Context, n int, p batchProcessor) []domain.BatchItemResult {
+	if ctx.Err() != nil {
+		return cancelledResults(n)
+	}
+
+	if p.atomic != nil {
+		results, err := p.atomic(ctx)
+		if err == nil && len(results) != n {
+			err = fmt.Errorf("got %d results for %d items", len(results), n)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return cancelledResults(n)
+			}
+			log.Errorf("Failed to apply atomic batch, err=%v", err)
+			return p.failed(0, n)
+		}
+		return results
+	}
+
+	results := make([]domain.BatchItemResult, n)
+	err := p.storage.(domain.Transactional).Atomically(func(tx domain.Storage) error {
+		// every item is tried so the caller learns about all failures at once
+		failed := false
+		for i := 0; i < n; i++ {
+			results[i] = p.item(ctx, tx, i)
+			if !results[i].Succeeded() {
+				failed = true
+			}
+		}
+		if failed {
+			return errRolledBack
+		}
+		return ctx.Err()
+	})
+
+	switch {
+	case errors.Is(err, errRolledBack):
+		markRolledBack(results)
+	case err != nil && ctx.Err() != nil:
+		log.Infof("Atomic batch cancelled before it was committed")
+		return cancelledResults(n)
+	case err != nil:
+		log.Errorf("Failed to apply atomic batch, err=%v", err)
+		return p.failed(0, n)
+	}
+	return results
+}
+
+// cancelledResults reports every item in [0, n) as not applied because the batch was cancelled
+func cancelledResults(n int) []domain.BatchItemResult {
+	results := make([]domain.BatchItemResult, n)
+	for i := range results {
+		results[i] = domain.BatchItemResult{Index: i, Status: http.StatusServiceUnavailable, Error: "request cancelled"}
+	}
+	return results
+}
+
+// applyBatch runs the batch as a background job if asked to, otherwise it waits
+// for every item and answers with the per-item results
+func (api *API) applyBatch(req *restful.Request, resp *restful.Response, backend string, operation domain.JobOperation, n, concurrency int, p batchProcessor) {
+	atomic := isAtomic(req)
+	if atomic && !p.supportsAtomic() {
+		log.Infof("Atomic batch requested on %s backend", backend)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("atomic batches are not supported by the %s backend", backend))
+		return
+	}
+
+	var run jobs.RunFunc = func(ctx context.Context, _ string, report func(domain.BatchItemResult)) {
+		api.runBatch(ctx, n, concurrency, p, report)
+	}
+	if atomic {
+		run = func(ctx context.Context, _ string, report func(domain.BatchItemResult)) {
+			for _, result := range api.runAtomic(ctx, n, p) {
+				report(result)
+			}
+		}
+	}
+
+	if isAsync(req) {
+		api.startJob(resp, backend, operation, n, run)
+		return
+	}
+
+	if atomic {
+		writeBatchResults(resp, api.runAtomic(req.Request.Context(), n, p))
+		return
+	}
+	writeBatchResults(resp, api.collectBatch(req.Request.Context(), n, concurrency, p))
+}
+
+// isAtomic reports whether the caller asked for an all-or-nothing batch
+func isAtomic(req *restful.Request) bool {
+	atomic, _ := strconv.ParseBool(req.QueryParameter("atomic"))
+	return atomic
+}
+
+// markRolledBack flags the items that had succeeded as not applied,
+// since the transaction they were part of was rolled back
+func markRolledBack(results []domain.BatchItemResult) {
+	for i := range results {
+		if results[i].Succeeded() {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "rolled back"
+			results[i].Product = nil
+		}
+	}
+}
+
+// runQueued pushes [0, n) on the queue in chunks for the job and reports the outcome of every item
+// once the store service has applied it. Items of a chunk that could not be queued are reported as failed,
+// and so are those the store service has not applied within the job timeout.
+func (api *API) runQueued(ctx context.Context, jobID string, n int, chunk func(start, end int) domain.QueueMessage, id func(i int) string, report func(domain.BatchItemResult)) {
+	// a batch is delivered at least once, so an item may be reported more than once
+	reported := make([]bool, n)
+	pending := n
+	reportOnce := func(result domain.BatchItemResult) {
+		if result.Index < 0 || result.Index >= n || reported[result.Index] {
+			return
+		}
+		reported[result.Index] = true
+		pending--
+		report(result)
+	}
+
+	for start := 0; start < n; start += redisChunkSize {
+		if ctx.Err() != nil {
+			return
+		}
+
+		end := start + redisChunkSize
+		if end > n {
+			end = n
+		}
+
+		msg := chunk(start, end)
+		msg.JobID = jobID
+		msg.Offset = start
+		if err := api.queue.Push(msg); err != nil {
+			log.Errorf("Failed to enqueue items %d-%d, err=%v", start, end, err)
+			for i := start; i < end; i++ {
+				reportOnce(domain.BatchItemResult{Index: i, ID: id(i), Status: http.StatusInternalServerError, Error: "failed to enqueue item"})
+			}
+		}
+	}
+
+	deadline := time.Now().Add(api.jobTimeout)
+	for pending > 0 && ctx.Err() == nil {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		if wait > jobResultsWait {
+			wait = jobResultsWait
+		}
+
+		results, err := api.queue.JobResults(ctx, jobID, wait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to read results of job %s, err=%v", jobID, err)
+			time.Sleep(jobResultsRetryDelay)
+			continue
+		}
+
+		for _, result := range results {
+			reportOnce(result)
+		}
+	}
+
+	if pending > 0 && ctx.Err() == nil {
+		log.Errorf("Store service did not apply %d items of job %s within %s", pending, jobID, api.jobTimeout)
+		for i := 0; i < n; i++ {
+			reportOnce(domain.BatchItemResult{Index: i, ID: id(i), Status: http.StatusGatewayTimeout, Error: "item not applied in time"})
+		}
+	}
+}
+
+func saveItem(ctx context.Context, storage domain.Storage, ids domain.IDGenerator, index int, product domain.Product) domain.BatchItemResult {
+	id, alreadyExists, err := storage.Save(ctx, product)
+	if err != nil {
+		return failedItem(index, domain.KnownID(ids, product), err, "failed to save product")
+	}
+
+	if alreadyExists {
+		log.Infof("Product %s already in store", id)
+		return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusConflict, Error: "product already exists"}
+	}
+
+	log.Infof("Product %s saved in store", id)
+
+	// the stored product carries what the store filled in, such as its version and currency
+	saved, found, err := storage.Get(ctx, id)
+	if err != nil || !found {
+		log.Errorf("Failed to read product %s back after saving it, err=%v", id, err)
+		return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusCreated}
+	}
+	return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusCreated, Product: &saved}
+}
+
+func getItem(ctx context.Context, storage domain.Storage, index int, id string) domain.BatchItemResult {
+	product, exists, err := storage.Get(ctx, id)
+	if err != nil {
+		return failedItem(index, id, err, "failed to get product")
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", id)
+		return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusNotFound, Error: "product not found"}
+	}
+
+	return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusOK, Product: &product}
+}
+
+func updateItem(ctx context.Context, storage domain.Storage, index int, productDiff domain.ProductDiff) domain.BatchItemResult {
+	if productDiff.ID == "" {
+		return domain.BatchItemResult{Index: index, Status: http.StatusBadRequest, Error: "id must be provided"}
+	}
+	if err := productDiff.Diff.Validate(); err != nil {
+		return domain.BatchItemResult{Index: index, ID: productDiff.ID, Status: http.StatusBadRequest, Error: err.Error()}
+	}
+
+	updated, err := storage.Update(ctx, productDiff.ID, productDiff)
+	if err != nil {
+		return failedItem(index, productDiff.ID, err, "failed to update product")
+	}
+
+	if !updated {
+		log.Infof("Product %s not in store", productDiff.ID)
+		return domain.BatchItemResult{Index: index, ID: productDiff.ID, Status: http.StatusNotFound, Error: "product not found"}
+	}
+
+	log.Infof("Product %s updated in store", productDiff.ID)
+	return domain.BatchItemResult{Index: index, ID: productDiff.ID, Status: http.StatusOK}
+}
+
+func deleteItem(ctx context.Context, storage domain.Storage, index int, id string) domain.BatchItemResult {
+	deleted, err := storage.Delete(ctx, id)
+	if err != nil {
+		return failedItem(index, id, err, "failed to delete product")
+	}
+
+	if !deleted {
+		log.Infof("Product %s not in store", id)
+		return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusNotFound, Error: "product not found"}
+	}
+
+	log.Infof("Product %s deleted from store", id)
+	return domain.BatchItemResult{Index: index, ID: id, Status: http.StatusOK}
+}
+
+func adjustStockItem(ctx context.Context, storage domain.Storage, index int, adjustment domain.StockAdjustment) domain.BatchItemResult {
+	if adjustment.ID == "" {
+		return domain.BatchItemResult{Index: index, Status: http.StatusBadRequest, Error: "id must be provided"}
+	}
+	if err := adjustment.Validate(); err != nil {
+		return domain.BatchItemResult{Index: index, ID: adjustment.ID, Status: http.StatusBadRequest, Error: err.Error()}
+	}
+
+	product, exists, err := storage.AdjustStock(ctx, adjustment)
+	if err != nil {
+		return failedItem(index, adjustment.ID, err, "failed to adjust stock")
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", adjustment.ID)
+		return domain.BatchItemResult{Index: index, ID: adjustment.ID, Status: http.StatusNotFound, Error: "product not found"}
+	}
+
+	log.Infof("Stock of product %s adjusted by %d", adjustment.ID, adjustment.Delta)
+	return domain.BatchItemResult{Index: index, ID: adjustment.ID, Status: http.StatusOK, Product: &product}
+}
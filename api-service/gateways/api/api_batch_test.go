@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"exam-api/domain"
+	"exam-api/gateways/memory"
+	"exam-api/gateways/pool"
+	"exam-api/mocks"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func newTestStore(t *testing.T) (*memory.Store, domain.IDGenerator) {
+	t.Helper()
+
+	ids, err := domain.NewIDGenerator(domain.DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	return memory.NewStore(ids), ids
+}
+
+func TestRunAtomicDoesNotCommitCancelledBatches(t *testing.T) {
+	store, ids := newTestStore(t)
+	products := []domain.Product{
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999},
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := batchProcessor{
+		storage: store,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			result := saveItem(ctx, storage, ids, i, products[i])
+			// the job is cancelled while the last item is applied
+			if i == len(products)-1 {
+				cancel()
+			}
+			return result
+		},
+	}
+
+	results := (&API{}).runAtomic(ctx, len(products), p)
+
+	for _, result := range results {
+		if result.Status != http.StatusServiceUnavailable {
+			t.Errorf("item %d has status %d, want %d", result.Index, result.Status, http.StatusServiceUnavailable)
+		}
+	}
+	for i := range products {
+		if _, found, _ := store.Get(context.Background(), domain.KnownID(ids, products[i])); found {
+			t.Errorf("product %d was saved by a cancelled atomic batch", i)
+		}
+	}
+}
+
+func TestRunAtomicSkipsBatchesCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	p := batchProcessor{
+		atomic: func(ctx context.Context) ([]domain.BatchItemResult, error) {
+			called = true
+			return nil, nil
+		},
+	}
+
+	results := (&API{}).runAtomic(ctx, 3, p)
+	if called {
+		t.Error("atomic bulk call made for a cancelled batch")
+	}
+	if len(results) != 3 || results[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("results %+v, want 3 cancelled items", results)
+	}
+}
+
+func TestSaveItemReturnsTheStoredProduct(t *testing.T) {
+	store, ids := newTestStore(t)
+
+	result := saveItem(context.Background(), store, ids, 0, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+
+	if result.Status != http.StatusCreated {
+		t.Fatalf("status %d, want %d", result.Status, http.StatusCreated)
+	}
+	stored, _, _ := store.Get(context.Background(), result.ID)
+	if result.Product == nil || !reflect.DeepEqual(*result.Product, stored) {
+		t.Errorf("product %+v, want the stored %+v", result.Product, stored)
+	}
+	if result.Product != nil && (result.Product.Version != 1 || result.Product.Currency == "") {
+		t.Errorf("product %+v, want its version and currency filled in", result.Product)
+	}
+}
+
+func TestUpdateItemRejectsPatchesProductsCouldNotBeCreatedWith(t *testing.T) {
+	store, _ := newTestStore(t)
+	id, _, err := store.Save(context.Background(), domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 3})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	for _, patch := range []domain.ProductPatch{
+		{Price: domain.Value(-1)},
+		{Stock: domain.Value(-1)},
+	} {
+		result := updateItem(context.Background(), store, 0, domain.ProductDiff{ID: id, Diff: patch})
+		if result.Status != http.StatusBadRequest {
+			t.Errorf("patch %+v has status %d, want %d", patch, result.Status, http.StatusBadRequest)
+		}
+	}
+
+	if product, _, _ := store.Get(context.Background(), id); product.Price != 499999 || product.Stock != 3 {
+		t.Errorf("product %+v, want it untouched", product)
+	}
+}
+
+func TestRunQueuedFailsItemsNotAppliedInTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	queue := mocks.NewMockQueue(ctrl)
+	queue.EXPECT().Push(gomock.Any()).Return(nil)
+	// the store applies the first item, and reports it twice as its batch was delivered twice
+	queue.EXPECT().JobResults(gomock.Any(), "job", gomock.Any()).Return([]domain.BatchItemResult{
+		{Index: 0, ID: "laptop", Status: http.StatusOK},
+		{Index: 0, ID: "laptop", Status: http.StatusOK},
+	}, nil)
+	queue.EXPECT().JobResults(gomock.Any(), "job", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, timeout time.Duration) ([]domain.BatchItemResult, error) {
+		time.Sleep(timeout)
+		return nil, nil
+	}).AnyTimes()
+
+	ids := []string{"laptop", "mouse", "keyboard"}
+	var reported []domain.BatchItemResult
+	api := &API{queue: queue, jobTimeout: 50 * time.Millisecond}
+	api.runQueued(context.Background(), "job", len(ids), func(start, end int) domain.QueueMessage {
+		return domain.QueueMessage{Operation: domain.QueueOperationDelete, IDs: ids[start:end]}
+	}, func(i int) string {
+		return ids[i]
+	}, func(result domain.BatchItemResult) {
+		reported = append(reported, result)
+	})
+
+	want := []domain.BatchItemResult{
+		{Index: 0, ID: "laptop", Status: http.StatusOK},
+		{Index: 1, ID: "mouse", Status: http.StatusGatewayTimeout, Error: "item not applied in time"},
+		{Index: 2, ID: "keyboard", Status: http.StatusGatewayTimeout, Error: "item not applied in time"},
+	}
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("reported %+v, want %+v", reported, want)
+	}
+}
+
+func TestCollectBatchReportsItemsThePoolTurnsDown(t *testing.T) {
+	workers := pool.NewPool(1, 0)
+	workers.Close()
+
+	ids := []string{"laptop", "mouse", "keyboard"}
+	p := batchProcessor{
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			t.Errorf("item %d ran on a closed pool", i)
+			return domain.BatchItemResult{Index: i, Status: http.StatusOK}
+		},
+		id: func(i int) string {
+			return ids[i]
+		},
+	}
+
+	results := (&API{pool: workers}).collectBatch(context.Background(), len(ids), 2, p)
+	for i, result := range results {
+		if result.ID != ids[i] || result.Status != http.StatusServiceUnavailable {
+			t.Errorf("item %d = %+v, want %s turned down with %d", i, result, ids[i], http.StatusServiceUnavailable)
+		}
+	}
+}
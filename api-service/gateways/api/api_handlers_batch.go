@@ -1,407 +1,278 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"exam-api/domain"
 	"fmt"
-	"github.com/emicklei/go-restful/v3"
-	"io/ioutil"
-	"log"
 	"net/http"
-	"sync"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
 )
 
 func (api *API) createProductMemoryBatch(req *restful.Request, resp *restful.Response) {
-	body := req.Request.Body
-	if body == nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, "nil body\n"))
-		return
+	api.createProductBatch(req, resp, memoryBackend, api.storage)
+}
 
-	}
-	defer body.Close()
-	var err error
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-		return
-	}
-	data, err := ioutil.ReadAll(body)
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-		return
-	}
+func (api *API) createProductHTTPBatch(req *restful.Request, resp *restful.Response) {
+	api.createProductBatch(req, resp, httpBackend, api.client)
+}
 
-	// parse list of products from data
-	var products []*domain.Product
-	if err := json.Unmarshal(data, &products); err != nil {
-		log.Printf("[ERROR] Couldn't parse request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-		return
-	}
+func (api *API) getProductMemoryBatch(req *restful.Request, resp *restful.Response) {
+	api.getProductBatch(req, resp, api.storage)
+}
 
-	wg := &sync.WaitGroup{}
-
-	// iterate over products
-	for _, product := range products {
-		wg.Add(1)
-		go func(product *domain.Product) {
-			defer wg.Done()
-			id, alreadyExists, err := api.storage.Save(*product)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't save product in storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
+func (api *API) getProductHTTPBatch(req *restful.Request, resp *restful.Response) {
+	api.getProductBatch(req, resp, api.client)
+}
 
-			if alreadyExists {
-				log.Printf("[ERROR] Product %s already in store", id)
-				resp.WriteError(http.StatusConflict, restful.NewError(http.StatusConflict, "product already exists\n"))
-				return
-			}
+func (api *API) updateProductMemoryBatch(req *restful.Request, resp *restful.Response) {
+	api.updateProductBatch(req, resp, memoryBackend, api.storage)
+}
 
-			log.Printf("[INFO] Product %s saved in store", id)
-		}(product)
-	}
+func (api *API) updateProductHTTPBatch(req *restful.Request, resp *restful.Response) {
+	api.updateProductBatch(req, resp, httpBackend, api.client)
+}
 
-	wg.Wait()
-	resp.WriteAsJson(products)
+func (api *API) deleteProductMemoryBatch(req *restful.Request, resp *restful.Response) {
+	api.deleteProductBatch(req, resp, memoryBackend, api.storage)
 }
 
-func (api *API) createProductHTTPBatch(req *restful.Request, resp *restful.Response) {
-	body := req.Request.Body
-	if body == nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, "nil body\n"))
-		return
+func (api *API) deleteProductHTTPBatch(req *restful.Request, resp *restful.Response) {
+	api.deleteProductBatch(req, resp, httpBackend, api.client)
+}
 
-	}
-	defer body.Close()
-	var err error
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-		return
-	}
-	data, err := ioutil.ReadAll(body)
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+func (api *API) createProductBatch(req *restful.Request, resp *restful.Response, backend string, storage domain.Storage) {
+	products, ok := readBatch[domain.Product](req, resp)
+	if !ok || !validateProducts(req, resp, api.ids, products) {
 		return
 	}
 
-	// parse list of products from data
-	var products []*domain.Product
-	if err := json.Unmarshal(data, &products); err != nil {
-		log.Printf("[ERROR] Couldn't parse request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+	concurrency, ok := api.batchConcurrency(req, resp)
+	if !ok {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-
-	// iterate over products
-	for _, product := range products {
-		wg.Add(1)
-		go func(product *domain.Product) {
-			defer wg.Done()
-			id, alreadyExists, err := api.client.Save(*product)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't save product in storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
-
-			if alreadyExists {
-				log.Printf("[ERROR] Product %s already in store", id)
-				resp.WriteError(http.StatusConflict, restful.NewError(http.StatusConflict, "product already exists\n"))
-				return
-			}
-
-			log.Printf("[INFO] Product %s saved in store", id)
-		}(product)
+	p := batchProcessor{
+		storage: storage,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			return saveItem(ctx, storage, api.ids, i, products[i])
+		},
+		id: func(i int) string {
+			return domain.KnownID(api.ids, products[i])
+		},
+		failure: "failed to save product",
+	}
+	if bulk, ok := storage.(domain.BatchStorage); ok {
+		p.chunk = func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error) {
+			return bulk.SaveBatch(ctx, products[start:end], false)
+		}
+		p.atomic = func(ctx context.Context) ([]domain.BatchItemResult, error) {
+			return bulk.SaveBatch(ctx, products, true)
+		}
 	}
 
-	wg.Wait()
-	resp.WriteAsJson(products)
+	api.applyBatch(req, resp, backend, domain.JobOperationCreate, len(products), concurrency, p)
 }
 
-func (api *API) getProductMemoryBatch(req *restful.Request, resp *restful.Response) {
+func (api *API) getProductBatch(req *restful.Request, resp *restful.Response, storage domain.Storage) {
 	ids := req.QueryParameters("id")
-	if ids == nil {
-		log.Printf("[ERROR] Failed to read id")
-		resp.WriteError(http.StatusBadRequest, fmt.Errorf("element id must be provided\n"))
+	if len(ids) == 0 {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	var mu sync.Mutex
-	var finalResponse string = ""
-
-	for _, id := range ids {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			product, ok, err := api.storage.Get(id)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't get product from storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
-
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", id)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
-			}
-
-			mu.Lock()
-
-			finalResponse += fmt.Sprintf("%v\n", product)
-			mu.Unlock()
-		}(id)
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
 	}
 
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
-}
-
-func (api *API) getProductHTTPBatch(req *restful.Request, resp *restful.Response) {
-	ids := req.QueryParameters("id")
-	if ids == nil {
-		log.Printf("[ERROR] Failed to read id")
-		resp.WriteError(http.StatusBadRequest, fmt.Errorf("element id must be provided\n"))
+	concurrency, ok := api.batchConcurrency(req, resp)
+	if !ok {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	var mu sync.Mutex
-	var finalResponse string = ""
-
-	for _, id := range ids {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			product, ok, err := api.client.Get(id)
-
-			if err != nil {
-				log.Printf("[ERROR] Couldn't get product from storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
-
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", id)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
-			}
-
-			mu.Lock()
-
-			finalResponse += fmt.Sprintf("%v\n", product)
-			mu.Unlock()
-		}(id)
+	p := batchProcessor{
+		storage: storage,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			return getItem(ctx, storage, i, ids[i])
+		},
+		id: func(i int) string {
+			return ids[i]
+		},
+		failure: "failed to get product",
+	}
+	if bulk, ok := storage.(domain.BatchStorage); ok {
+		p.chunk = func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error) {
+			return bulk.GetBatch(ctx, ids[start:end])
+		}
 	}
 
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
-}
-
-func (api *API) updateProductMemoryBatch(req *restful.Request, resp *restful.Response) {
-	body := req.Request.Body
-	if body == nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, "nil body\n"))
-		return
-
+	results := api.collectBatch(req.Request.Context(), len(ids), concurrency, p)
+	products := make([]*domain.Product, 0, len(results))
+	for _, result := range results {
+		if result.Product != nil {
+			products = append(products, result.Product)
+		}
 	}
-	defer body.Close()
-	var err error
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+	if !api.convertPrices(req, resp, currency, products...) {
 		return
 	}
-	data, err := ioutil.ReadAll(body)
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+	writeBatchResults(resp, results)
+}
+
+func (api *API) updateProductBatch(req *restful.Request, resp *restful.Response, backend string, storage domain.Storage) {
+	productDiffs, ok := readBatch[domain.ProductDiff](req, resp)
+	if !ok {
 		return
 	}
 
-	// parse list of productDiff from data
-	var productDiffs []*domain.ProductDiff
-	if err := json.Unmarshal(data, &productDiffs); err != nil {
-		log.Printf("[ERROR] Couldn't parse request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+	concurrency, ok := api.batchConcurrency(req, resp)
+	if !ok {
 		return
 	}
-	var finalResponse string = ""
-	wg := &sync.WaitGroup{}
-
-	// iterate over productDiffs
-	for _, productDiff := range productDiffs {
-		wg.Add(1)
-		go func(productDiff *domain.ProductDiff) {
-			defer wg.Done()
-			ok, err := api.storage.Update(productDiff.ID, *productDiff)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't update product in storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
-
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", productDiff.ID)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
-			}
 
-			finalResponse += fmt.Sprintf("%v\n", productDiff)
-			log.Printf("[INFO] Product %s updated in store", productDiff.ID)
-		}(productDiff)
+	p := batchProcessor{
+		storage: storage,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			return updateItem(ctx, storage, i, productDiffs[i])
+		},
+		id: func(i int) string {
+			return productDiffs[i].ID
+		},
+		failure: "failed to update product",
+	}
+	if bulk, ok := storage.(domain.BatchStorage); ok {
+		p.chunk = func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error) {
+			return bulk.UpdateBatch(ctx, productDiffs[start:end], false)
+		}
+		p.atomic = func(ctx context.Context) ([]domain.BatchItemResult, error) {
+			return bulk.UpdateBatch(ctx, productDiffs, true)
+		}
 	}
 
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
+	api.applyBatch(req, resp, backend, domain.JobOperationUpdate, len(productDiffs), concurrency, p)
 }
 
-func (api *API) updateProductHTTPBatch(req *restful.Request, resp *restful.Response) {
-	body := req.Request.Body
-	if body == nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, "nil body\n"))
-		return
-
-	}
-	defer body.Close()
-	var err error
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-		return
-	}
-	data, err := ioutil.ReadAll(body)
-	if err != nil {
-		log.Printf("[ERROR] Couldn't read request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+func (api *API) deleteProductBatch(req *restful.Request, resp *restful.Response, backend string, storage domain.Storage) {
+	ids := req.QueryParameters("id")
+	if len(ids) == 0 {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
 		return
 	}
 
-	// parse list of productDiff from data
-	var productDiffs []*domain.ProductDiff
-	if err := json.Unmarshal(data, &productDiffs); err != nil {
-		log.Printf("[ERROR] Couldn't parse request body")
-		resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
+	concurrency, ok := api.batchConcurrency(req, resp)
+	if !ok {
 		return
 	}
-	var finalResponse string = ""
-	wg := &sync.WaitGroup{}
-
-	// iterate over productDiffs
-	for _, productDiff := range productDiffs {
-		wg.Add(1)
-		go func(productDiff *domain.ProductDiff) {
-			defer wg.Done()
-			ok, err := api.client.Update(productDiff.ID, *productDiff)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't update product in storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
 
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", productDiff.ID)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
-			}
-
-			finalResponse += fmt.Sprintf("%v\n", productDiff)
-			log.Printf("[INFO] Product %s updated in store", productDiff.ID)
-		}(productDiff)
+	p := batchProcessor{
+		storage: storage,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			return deleteItem(ctx, storage, i, ids[i])
+		},
+		id: func(i int) string {
+			return ids[i]
+		},
+		failure: "failed to delete product",
+	}
+	if bulk, ok := storage.(domain.BatchStorage); ok {
+		p.chunk = func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error) {
+			return bulk.DeleteBatch(ctx, ids[start:end], false)
+		}
+		p.atomic = func(ctx context.Context) ([]domain.BatchItemResult, error) {
+			return bulk.DeleteBatch(ctx, ids, true)
+		}
 	}
 
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
+	api.applyBatch(req, resp, backend, domain.JobOperationDelete, len(ids), concurrency, p)
 }
 
-func (api *API) deleteProductMemoryBatch(req *restful.Request, resp *restful.Response) {
-	ids := req.QueryParameters("id")
-	if ids == nil {
-		log.Printf("[ERROR] Failed to read id")
-		resp.WriteError(http.StatusBadRequest, fmt.Errorf("element id must be provided\n"))
-		return
+// validateProducts checks every product of a create batch.
+// On failure it writes a 400 response naming the first invalid product and returns false.
+func validateProducts(req *restful.Request, resp *restful.Response, ids domain.IDGenerator, products []domain.Product) bool {
+	for i := range products {
+		if err := products[i].Validate(ids); err != nil {
+			log.Infof("Invalid product %d in batch, err=%v", i, err)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("product %d: %w", i, err))
+			return false
+		}
 	}
+	return true
+}
 
-	wg := &sync.WaitGroup{}
-	var mu sync.Mutex
-	var finalResponse string = ""
-
-	for _, id := range ids {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			ok, err := api.storage.Delete(id)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't delete product from storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
-			}
-
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", id)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
-			}
+// readBatch decodes a non-empty JSON array from the request body.
+// On failure it writes a 400 response and returns false.
+func readBatch[T any](req *restful.Request, resp *restful.Response) ([]T, bool) {
+	if req.Request.Body == nil {
+		log.Infof("No body provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("request body must be provided"))
+		return nil, false
+	}
+	defer req.Request.Body.Close()
 
-			mu.Lock()
+	var batch []T
+	if err := json.NewDecoder(req.Request.Body).Decode(&batch); err != nil {
+		log.Errorf("Failed to read batch, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("malformed batch: %v", err))
+		return nil, false
+	}
 
-			finalResponse += fmt.Sprintf("%v\n", id)
-			mu.Unlock()
-		}(id)
+	if len(batch) == 0 {
+		log.Infof("Empty batch in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("at least one item must be provided"))
+		return nil, false
 	}
+	return batch, true
+}
 
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
+// writeBatchResults answers with the per-item results and an overall status:
+// 200 if every item succeeded, the status of the culprit if an atomic batch was rolled back,
+// the shared status if every item failed the same way and 207 Multi-Status otherwise
+func writeBatchResults(resp *restful.Response, results []domain.BatchItemResult) {
+	_ = resp.WriteHeaderAndJson(batchStatus(results), results, restful.MIME_JSON)
 }
 
-func (api *API) deleteProductHTTPBatch(req *restful.Request, resp *restful.Response) {
-	ids := req.QueryParameters("id")
-	if ids == nil {
-		log.Printf("[ERROR] Failed to read id")
-		resp.WriteError(http.StatusBadRequest, fmt.Errorf("element id must be provided\n"))
-		return
+func batchStatus(results []domain.BatchItemResult) int {
+	succeeded := 0
+	for _, result := range results {
+		if result.Succeeded() {
+			succeeded++
+		}
+	}
+
+	if succeeded == len(results) {
+		return http.StatusOK
 	}
 
-	wg := &sync.WaitGroup{}
-	var mu sync.Mutex
-	var finalResponse string = ""
-
-	for _, id := range ids {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
-			ok, err := api.client.Delete(id)
-			if err != nil {
-				log.Printf("[ERROR] Couldn't delete product from storage")
-				resp.WriteError(http.StatusInternalServerError, restful.NewError(http.StatusInternalServerError, err.Error()+"\n"))
-				return
+	if succeeded == 0 {
+		// a rolled back batch takes the status of the first item that caused the rollback
+		for _, result := range results {
+			if result.Status == http.StatusFailedDependency {
+				return rollbackStatus(results)
 			}
+		}
 
-			if !ok {
-				log.Printf("[ERROR] Product %s not found", id)
-				resp.WriteError(http.StatusNotFound, restful.NewError(http.StatusNotFound, "product not found\n"))
-				return
+		status := results[0].Status
+		for _, result := range results[1:] {
+			if result.Status != status {
+				return http.StatusMultiStatus
 			}
+		}
+		return status
+	}
 
-			mu.Lock()
+	return http.StatusMultiStatus
+}
 
-			finalResponse += fmt.Sprintf("%v\n", id)
-			mu.Unlock()
-		}(id)
+func rollbackStatus(results []domain.BatchItemResult) int {
+	for _, result := range results {
+		if result.Status != http.StatusFailedDependency {
+			return result.Status
+		}
 	}
-
-	wg.Wait()
-	resp.Write([]byte(finalResponse))
+	return http.StatusFailedDependency
 }
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listProductHistoryMemory(req *restful.Request, resp *restful.Response) {
+	listProductHistory(req, resp, api.storage)
+}
+
+func (api *API) listProductHistoryHTTP(req *restful.Request, resp *restful.Response) {
+	listProductHistory(req, resp, api.client)
+}
+
+// listProductHistory answers with the revisions of a product newest first, deleted products included
+func listProductHistory(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit, err := historyLimit(req.QueryParameter("limit"))
+	if err != nil {
+		log.Infof("Invalid history limit in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	revisions, exists, err := storage.History(req.Request.Context(), id, limit)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get product history")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s has no history", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(revisions)
+}
+
+// getProductAsOf answers with the product as it was at the RFC 3339 time in asOf.
+// Past states are not current, so unlike a plain read it sets no ETag.
+// A price converted to currency uses the exchange rates configured now.
+func (api *API) getProductAsOf(req *restful.Request, resp *restful.Response, storage domain.Storage, id, asOf, currency string) {
+	at, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		log.Infof("Invalid asOf %q in request", asOf)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("asOf must be an RFC 3339 timestamp"))
+		return
+	}
+
+	product, exists, err := storage.GetAsOf(req.Request.Context(), id, at)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get product from store")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store as of %s", id, asOf)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	if !api.convertPrices(req, resp, currency, &product) {
+		return
+	}
+	_ = resp.WriteAsJson(product)
+}
+
+// historyLimit reads the limit of a history listing, capped at MaxHistoryLimit
+func historyLimit(param string) (int, error) {
+	if param == "" {
+		return domain.DefaultHistoryLimit, nil
+	}
+
+	i, err := strconv.Atoi(param)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if i > domain.MaxHistoryLimit {
+		i = domain.MaxHistoryLimit
+	}
+	return i, nil
+}
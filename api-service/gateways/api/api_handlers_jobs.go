@@ -0,0 +1,41 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) getJob(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	job, exists := api.jobs.Get(id)
+	if !exists {
+		log.Infof("Job %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+	_ = resp.WriteAsJson(job)
+}
+
+func (api *API) cancelJob(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	job, exists := api.jobs.Get(id)
+	if !exists {
+		log.Infof("Job %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+
+	if job.Status != domain.JobStatusRunning {
+		log.Infof("Job %s already %s", id, job.Status)
+		writeProblem(req, resp, http.StatusConflict, fmt.Errorf("job already %s", job.Status))
+		return
+	}
+
+	job, _ = api.jobs.Cancel(id)
+	log.Infof("Job %s cancelled", id)
+	_ = resp.WriteAsJson(job)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listStockMovementsMemory(req *restful.Request, resp *restful.Response) {
+	listStockMovements(req, resp, api.storage)
+}
+
+func (api *API) listStockMovementsHTTP(req *restful.Request, resp *restful.Response) {
+	listStockMovements(req, resp, api.client)
+}
+
+func (api *API) reconcileStockMemory(req *restful.Request, resp *restful.Response) {
+	reconcileStock(req, resp, api.storage)
+}
+
+func (api *API) reconcileStockHTTP(req *restful.Request, resp *restful.Response) {
+	reconcileStock(req, resp, api.client)
+}
+
+// listStockMovements answers with the stock ledger of a product, newest first
+func listStockMovements(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit := domain.DefaultMovementLimit
+	if param := req.QueryParameter("limit"); param != "" {
+		i, err := strconv.Atoi(param)
+		if err != nil || i < 1 {
+			log.Infof("Invalid movement limit %q in request", param)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		if i > domain.MaxMovementLimit {
+			i = domain.MaxMovementLimit
+		}
+		limit = i
+	}
+
+	reason := domain.MovementReason(req.QueryParameter("reason"))
+	movements, exists, err := storage.StockMovements(req.Request.Context(), id, reason, limit)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get stock movements")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(movements)
+}
+
+// reconcileStock answers with the stock of a product next to the one derived from its ledger
+func reconcileStock(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	reconciliation, exists, err := storage.ReconcileStock(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to reconcile stock")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	if !reconciliation.Consistent {
+		log.Warnf("Stock of product %s is off its ledger by %d", id, reconciliation.Difference)
+	}
+	_ = resp.WriteAsJson(reconciliation)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"exam-api/domain"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listProductsMemory(req *restful.Request, resp *restful.Response) {
+	api.listProducts(req, resp, api.storage)
+}
+
+func (api *API) listProductsHTTP(req *restful.Request, resp *restful.Response) {
+	api.listProducts(req, resp, api.client)
+}
+
+func (api *API) listProducts(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	query, err := domain.ParseProductQuery(req.Request.URL.Query())
+	if err != nil {
+		log.Infof("Invalid listing query, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	page, err := storage.List(req.Request.Context(), query)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to list products")
+		return
+	}
+
+	products := make([]*domain.Product, len(page.Items))
+	for i := range page.Items {
+		products[i] = &page.Items[i].Product
+	}
+	if !api.convertPrices(req, resp, currency, products...) {
+		return
+	}
+
+	_ = resp.WriteAsJson(page)
+}
@@ -0,0 +1,9 @@
+package api
+
+import (
+	"github.com/emicklei/go-restful/v3"
+)
+
+func (api *API) getPoolMetrics(req *restful.Request, resp *restful.Response) {
+	_ = resp.WriteAsJson(api.pool.Stats())
+}
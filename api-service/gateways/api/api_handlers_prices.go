@@ -0,0 +1,183 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) schedulePriceMemory(req *restful.Request, resp *restful.Response) {
+	schedulePrice(req, resp, api.storage)
+}
+
+func (api *API) schedulePriceHTTP(req *restful.Request, resp *restful.Response) {
+	schedulePrice(req, resp, api.client)
+}
+
+func (api *API) getPriceChangeMemory(req *restful.Request, resp *restful.Response) {
+	getPriceChange(req, resp, api.storage)
+}
+
+func (api *API) getPriceChangeHTTP(req *restful.Request, resp *restful.Response) {
+	getPriceChange(req, resp, api.client)
+}
+
+func (api *API) cancelPriceChangeMemory(req *restful.Request, resp *restful.Response) {
+	cancelPriceChange(req, resp, api.storage)
+}
+
+func (api *API) cancelPriceChangeHTTP(req *restful.Request, resp *restful.Response) {
+	cancelPriceChange(req, resp, api.client)
+}
+
+func (api *API) listPriceScheduleMemory(req *restful.Request, resp *restful.Response) {
+	listPriceSchedule(req, resp, api.storage)
+}
+
+func (api *API) listPriceScheduleHTTP(req *restful.Request, resp *restful.Response) {
+	listPriceSchedule(req, resp, api.client)
+}
+
+func (api *API) listPriceHistoryMemory(req *restful.Request, resp *restful.Response) {
+	api.listPriceHistory(req, resp, api.storage)
+}
+
+func (api *API) listPriceHistoryHTTP(req *restful.Request, resp *restful.Response) {
+	api.listPriceHistory(req, resp, api.client)
+}
+
+func schedulePrice(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	request := domain.PriceChangeRequest{}
+	err := req.ReadEntity(&request)
+	if err != nil {
+		log.Errorf("Failed to read price change, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		log.Infof("Invalid price change in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	change, exists, err := storage.SchedulePrice(req.Request.Context(), request)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to schedule price change")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", request.ProductID)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	log.Infof("Price change %s sets product %s to %d from %s", change.ID, change.ProductID, change.Price, change.EffectiveFrom)
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+change.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, change, restful.MIME_JSON)
+}
+
+func getPriceChange(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.PathParameter("id")
+	change, exists, err := storage.GetPriceChange(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get price change")
+		return
+	}
+
+	if !exists {
+		log.Infof("Price change %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("price change not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(change)
+}
+
+// cancelPriceChange cancels the price change named in the path,
+// answering 409 Conflict if it is no longer pending
+func cancelPriceChange(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.PathParameter("id")
+	change, exists, err := storage.CancelPriceChange(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to cancel price change")
+		return
+	}
+
+	if !exists {
+		log.Infof("Price change %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("price change not found"))
+		return
+	}
+
+	log.Infof("Price change %s cancelled", id)
+	_ = resp.WriteAsJson(change)
+}
+
+// listPriceSchedule answers with every price change scheduled for a product by effective time
+func listPriceSchedule(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	changes, exists, err := storage.PriceSchedule(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get price schedule")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(changes)
+}
+
+// listPriceHistory answers with the prices a product had newest first, deleted products included,
+// converted to ?currency= if it is given
+func (api *API) listPriceHistory(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit, err := historyLimit(req.QueryParameter("limit"))
+	if err != nil {
+		log.Infof("Invalid history limit in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	points, exists, err := storage.PriceHistory(req.Request.Context(), id, limit)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get price history")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s has no history", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	if !api.convertPricePoints(req, resp, currency, points) {
+		return
+	}
+	_ = resp.WriteAsJson(points)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) createProductRedisBatch(req *restful.Request, resp *restful.Response) {
+	if isAtomic(req) {
+		log.Infof("Atomic batch requested on %s backend", redisBackend)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("atomic batches are not supported by the %s backend", redisBackend))
+		return
+	}
+
+	var products []domain.Product
+	err := req.ReadEntity(&products)
+	if err != nil {
+		log.Errorf("Failed to read products, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(products) == 0 {
+		log.Infof("Empty batch in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("at least one product must be provided"))
+		return
+	}
+
+	if !validateProducts(req, resp, api.ids, products) {
+		return
+	}
+
+	if isAsync(req) {
+		api.startJob(resp, redisBackend, domain.JobOperationCreate, len(products), func(ctx context.Context, jobID string, report func(domain.BatchItemResult)) {
+			api.runQueued(ctx, jobID, len(products), func(start, end int) domain.QueueMessage {
+				return domain.QueueMessage{Operation: domain.QueueOperationCreate, Products: products[start:end]}
+			}, func(i int) string {
+				return domain.KnownID(api.ids, products[i])
+			}, report)
+		})
+		return
+	}
+
+	if err := api.queue.Add(products); err != nil {
+		log.Errorf("Failed to enqueue products, err=%v", err)
+		writeProblem(req, resp, http.StatusInternalServerError, fmt.Errorf("failed to enqueue products"))
+		return
+	}
+
+	log.Infof("Queued %d products for creation", len(products))
+	_ = resp.WriteHeaderAndJson(http.StatusAccepted, map[string]int{
+		"queued": len(products),
+	}, restful.MIME_JSON)
+}
+
+func (api *API) updateProductRedisBatch(req *restful.Request, resp *restful.Response) {
+	if isAtomic(req) {
+		log.Infof("Atomic batch requested on %s backend", redisBackend)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("atomic batches are not supported by the %s backend", redisBackend))
+		return
+	}
+
+	var productDiffs []domain.ProductDiff
+	err := req.ReadEntity(&productDiffs)
+	if err != nil {
+		log.Errorf("Failed to read product diffs, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(productDiffs) == 0 {
+		log.Infof("Empty batch in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("at least one product diff must be provided"))
+		return
+	}
+
+	for _, productDiff := range productDiffs {
+		if productDiff.ID == "" {
+			log.Infof("Product diff without id in request")
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided for every product diff"))
+			return
+		}
+		if err := productDiff.Diff.Validate(); err != nil {
+			log.Infof("Invalid product diff in request, err=%v", err)
+			writeProblem(req, resp, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if isAsync(req) {
+		api.startJob(resp, redisBackend, domain.JobOperationUpdate, len(productDiffs), func(ctx context.Context, jobID string, report func(domain.BatchItemResult)) {
+			api.runQueued(ctx, jobID, len(productDiffs), func(start, end int) domain.QueueMessage {
+				return domain.QueueMessage{Operation: domain.QueueOperationUpdate, Diffs: productDiffs[start:end]}
+			}, func(i int) string {
+				return productDiffs[i].ID
+			}, report)
+		})
+		return
+	}
+
+	if err := api.queue.Update(productDiffs); err != nil {
+		log.Errorf("Failed to enqueue product diffs, err=%v", err)
+		writeProblem(req, resp, http.StatusInternalServerError, fmt.Errorf("failed to enqueue product diffs"))
+		return
+	}
+
+	log.Infof("Queued %d products for update", len(productDiffs))
+	_ = resp.WriteHeaderAndJson(http.StatusAccepted, map[string]int{
+		"queued": len(productDiffs),
+	}, restful.MIME_JSON)
+}
+
+func (api *API) deleteProductRedisBatch(req *restful.Request, resp *restful.Response) {
+	if isAtomic(req) {
+		log.Infof("Atomic batch requested on %s backend", redisBackend)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("atomic batches are not supported by the %s backend", redisBackend))
+		return
+	}
+
+	ids := req.QueryParameters("id")
+	if len(ids) == 0 {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	if isAsync(req) {
+		api.startJob(resp, redisBackend, domain.JobOperationDelete, len(ids), func(ctx context.Context, jobID string, report func(domain.BatchItemResult)) {
+			api.runQueued(ctx, jobID, len(ids), func(start, end int) domain.QueueMessage {
+				return domain.QueueMessage{Operation: domain.QueueOperationDelete, IDs: ids[start:end]}
+			}, func(i int) string {
+				return ids[i]
+			}, report)
+		})
+		return
+	}
+
+	if err := api.queue.Delete(ids); err != nil {
+		log.Errorf("Failed to enqueue ids, err=%v", err)
+		writeProblem(req, resp, http.StatusInternalServerError, fmt.Errorf("failed to enqueue ids"))
+		return
+	}
+
+	log.Infof("Queued %d products for deletion", len(ids))
+	_ = resp.WriteHeaderAndJson(http.StatusAccepted, map[string]int{
+		"queued": len(ids),
+	}, restful.MIME_JSON)
+}
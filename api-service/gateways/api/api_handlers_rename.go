@@ -0,0 +1,50 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) renameProductMemory(req *restful.Request, resp *restful.Response) {
+	renameProduct(req, resp, api.storage)
+}
+
+func (api *API) renameProductHTTP(req *restful.Request, resp *restful.Response) {
+	renameProduct(req, resp, api.client)
+}
+
+func renameProduct(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	rename := domain.ProductRename{}
+	err := req.ReadEntity(&rename)
+	if err != nil {
+		log.Errorf("Failed to read rename, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := rename.Validate(); err != nil {
+		log.Infof("Invalid rename in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	result, exists, err := storage.Rename(req.Request.Context(), rename)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to rename product")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", rename.ID)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	log.Infof("Product %s renamed, now stored as %s", rename.ID, result.ID)
+	resp.AddHeader("ETag", etag(result.Product.Version, ""))
+	_ = resp.WriteAsJson(result)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) createReservationMemory(req *restful.Request, resp *restful.Response) {
+	createReservation(req, resp, api.storage)
+}
+
+func (api *API) createReservationHTTP(req *restful.Request, resp *restful.Response) {
+	createReservation(req, resp, api.client)
+}
+
+func (api *API) getReservationMemory(req *restful.Request, resp *restful.Response) {
+	getReservation(req, resp, api.storage)
+}
+
+func (api *API) getReservationHTTP(req *restful.Request, resp *restful.Response) {
+	getReservation(req, resp, api.client)
+}
+
+func (api *API) confirmReservationMemory(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.storage.ConfirmReservation)
+}
+
+func (api *API) confirmReservationHTTP(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.client.ConfirmReservation)
+}
+
+func (api *API) releaseReservationMemory(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.storage.ReleaseReservation)
+}
+
+func (api *API) releaseReservationHTTP(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.client.ReleaseReservation)
+}
+
+func createReservation(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	request := domain.ReservationRequest{}
+	err := req.ReadEntity(&request)
+	if err != nil {
+		log.Errorf("Failed to read reservation, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		log.Infof("Invalid reservation in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	reservation, exists, err := storage.Reserve(req.Request.Context(), request)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to reserve stock")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", request.ProductID)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	log.Infof("Reservation %s holds %d of product %s", reservation.ID, reservation.Quantity, reservation.ProductID)
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+reservation.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, reservation, restful.MIME_JSON)
+}
+
+func getReservation(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.PathParameter("id")
+	reservation, exists, err := storage.GetReservation(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get reservation")
+		return
+	}
+
+	if !exists {
+		log.Infof("Reservation %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("reservation not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(reservation)
+}
+
+// closeReservation confirms or releases the reservation named in the path,
+// answering 409 Conflict if it is no longer pending
+func closeReservation(req *restful.Request, resp *restful.Response, close func(ctx context.Context, id string) (domain.Reservation, bool, error)) {
+	id := req.PathParameter("id")
+	reservation, exists, err := close(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to close reservation")
+		return
+	}
+
+	if !exists {
+		log.Infof("Reservation %s not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("reservation not found"))
+		return
+	}
+
+	log.Infof("Reservation %s %s", id, reservation.Status)
+	_ = resp.WriteAsJson(reservation)
+}
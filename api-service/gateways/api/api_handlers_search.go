@@ -0,0 +1,63 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) searchProductsMemory(req *restful.Request, resp *restful.Response) {
+	api.searchProducts(req, resp, api.storage)
+}
+
+func (api *API) searchProductsHTTP(req *restful.Request, resp *restful.Response) {
+	api.searchProducts(req, resp, api.client)
+}
+
+func (api *API) searchProducts(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	text := req.QueryParameter("q")
+	if text == "" {
+		log.Infof("No search text provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("q must be provided"))
+		return
+	}
+
+	limit := domain.DefaultSearchLimit
+	if param := req.QueryParameter("limit"); param != "" {
+		i, err := strconv.Atoi(param)
+		if err != nil || i < 1 {
+			log.Infof("Invalid search limit %q in request", param)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		if i > domain.MaxSearchLimit {
+			i = domain.MaxSearchLimit
+		}
+		limit = i
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	results, err := storage.Search(req.Request.Context(), text, limit)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to search products")
+		return
+	}
+
+	products := make([]*domain.Product, len(results))
+	for i := range results {
+		products[i] = &results[i].Product
+	}
+	if !api.convertPrices(req, resp, currency, products...) {
+		return
+	}
+
+	_ = resp.WriteAsJson(results)
+}
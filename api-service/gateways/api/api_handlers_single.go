@@ -15,19 +15,23 @@ func (api *API) createProductMemorySingle(req *restful.Request, resp *restful.Re
 
 	if err != nil {
 		log.Errorf("Failed to read product, err=%v", err)
-		_ = resp.WriteError(http.StatusBadRequest, err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
 		return
 	}
-	id, alreadyExists, err := api.storage.Save(*product)
+	if err := product.Validate(api.ids); err != nil {
+		log.Infof("Invalid product in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	id, alreadyExists, err := api.storage.Save(req.Request.Context(), *product)
 	if err != nil {
-		log.Errorf("Failed to save product in storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to save product"))
+		writeStorageError(req, resp, err, "failed to save product")
 		return
 	}
 
 	if alreadyExists {
 		log.Infof("Product %s already in store", id)
-		_ = resp.WriteError(http.StatusConflict, fmt.Errorf("product already exists"))
+		writeAlreadyExists(req, resp, id, domain.NewError(domain.ErrAlreadyExists, "", "product already exists"))
 		return
 	}
 
@@ -45,19 +49,23 @@ func (api *API) createProductHTTPSingle(req *restful.Request, resp *restful.Resp
 
 	if err != nil {
 		log.Errorf("Failed to read product, err=%v", err)
-		_ = resp.WriteError(http.StatusBadRequest, err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	if err := product.Validate(api.ids); err != nil {
+		log.Infof("Invalid product in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
 		return
 	}
-	id, alreadyExists, err := api.client.Save(*product)
+	id, alreadyExists, err := api.client.Save(req.Request.Context(), *product)
 	if err != nil {
-		log.Errorf("Failed to save product in storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to save product"))
+		writeStorageError(req, resp, err, "failed to save product")
 		return
 	}
 
 	if alreadyExists {
 		log.Infof("Product %s already in store", id)
-		_ = resp.WriteError(http.StatusConflict, fmt.Errorf("product already exists"))
+		writeAlreadyExists(req, resp, id, domain.NewError(domain.ErrAlreadyExists, "", "product already exists"))
 		return
 	}
 
@@ -73,20 +81,31 @@ func (api *API) getProductMemorySingle(req *restful.Request, resp *restful.Respo
 	id := req.QueryParameter("id")
 	if id == "" {
 		log.Infof("No id provided in request")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+	currency, ok := readCurrency(req, resp)
+	if !ok {
 		return
 	}
-	product, exists, err := api.storage.Get(id)
+	if asOf := req.QueryParameter("asOf"); asOf != "" {
+		api.getProductAsOf(req, resp, api.storage, id, asOf, currency)
+		return
+	}
+	product, exists, err := api.storage.Get(req.Request.Context(), id)
 	if err != nil {
-		log.Errorf("Failed to get product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to get product from store"))
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 	if !exists {
 		log.Infof("Product %s not in store", id)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	}
+	if !api.convertPrices(req, resp, currency, &product) {
+		return
+	}
+	resp.AddHeader("ETag", etag(product.Version, currency))
 	_ = resp.WriteAsJson(product)
 }
 
@@ -94,59 +113,76 @@ func (api *API) getProductHTTPSingle(req *restful.Request, resp *restful.Respons
 	id := req.QueryParameter("id")
 	if id == "" {
 		log.Infof("No id provided in request")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
 		return
 	}
-	product, exists, err := api.client.Get(id)
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+	if asOf := req.QueryParameter("asOf"); asOf != "" {
+		api.getProductAsOf(req, resp, api.client, id, asOf, currency)
+		return
+	}
+	product, exists, err := api.client.Get(req.Request.Context(), id)
 	if err != nil {
-		log.Errorf("Failed to get product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to get product from store"))
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 	if !exists {
 		log.Infof("Product %s not in store", id)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+	if !api.convertPrices(req, resp, currency, &product) {
 		return
 	}
+	resp.AddHeader("ETag", etag(product.Version, currency))
 	_ = resp.WriteAsJson(product)
 }
 
 func (api *API) updateProductMemorySingle(req *restful.Request, resp *restful.Response) {
-	productDiff := &domain.ProductDiff{}
-	err := req.ReadEntity(productDiff)
-
+	productDiff, status, err := readProductDiff(req, api.storage)
 	if err != nil {
 		log.Errorf("Failed to read product, err=%v", err)
-		_ = resp.WriteError(http.StatusBadRequest, err)
+		writeProblem(req, resp, status, err)
+		return
+	}
+
+	expected, err := ifMatchVersion(req.Request.Context(), req, api.storage, productDiff.ID)
+	if err != nil {
+		log.Infof("Failed to check If-Match of request, err=%v", err)
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
+	if expected != nil {
+		productDiff.Version = expected
+	}
 
 	// check if id exists in storage
-	_, exists, err := api.storage.Get(productDiff.ID)
+	_, exists, err := api.storage.Get(req.Request.Context(), productDiff.ID)
 	if err != nil {
-		log.Errorf("Failed to get product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to get product from store"))
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 
 	if !exists {
 		log.Infof("Product %s not in store", productDiff.ID)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	}
 
 	// update product in storage
-	updated, err := api.storage.Update(productDiff.ID, *productDiff)
+	updated, err := api.storage.Update(req.Request.Context(), productDiff.ID, productDiff)
 
 	if err != nil {
-		log.Errorf("Failed to update product in storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to update product"))
+		writeStorageError(req, resp, err, "failed to update product")
 		return
 	}
 
 	if !updated {
 		log.Infof("Product %s not in store", productDiff.ID)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	} else {
 		log.Infof("Product %s updated in store", productDiff.ID)
@@ -156,41 +192,47 @@ func (api *API) updateProductMemorySingle(req *restful.Request, resp *restful.Re
 }
 
 func (api *API) updateProductHTTPSingle(req *restful.Request, resp *restful.Response) {
-	productDiff := &domain.ProductDiff{}
-	err := req.ReadEntity(productDiff)
-
+	productDiff, status, err := readProductDiff(req, api.client)
 	if err != nil {
 		log.Errorf("Failed to read product, err=%v", err)
-		_ = resp.WriteError(http.StatusBadRequest, err)
+		writeProblem(req, resp, status, err)
 		return
 	}
 
+	expected, err := ifMatchVersion(req.Request.Context(), req, api.client, productDiff.ID)
+	if err != nil {
+		log.Infof("Failed to check If-Match of request, err=%v", err)
+		writeStorageError(req, resp, err, "failed to get product from store")
+		return
+	}
+	if expected != nil {
+		productDiff.Version = expected
+	}
+
 	// check if id exists in storage
-	_, exists, err := api.storage.Get(productDiff.ID)
+	_, exists, err := api.client.Get(req.Request.Context(), productDiff.ID)
 	if err != nil {
-		log.Errorf("Failed to get product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to get product from store"))
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 
 	if !exists {
 		log.Infof("Product %s not in store", productDiff.ID)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	}
 
 	// update product in storage
-	updated, err := api.client.Update(productDiff.ID, *productDiff)
+	updated, err := api.client.Update(req.Request.Context(), productDiff.ID, productDiff)
 
 	if err != nil {
-		log.Errorf("Failed to update product in storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to update product"))
+		writeStorageError(req, resp, err, "failed to update product")
 		return
 	}
 
 	if !updated {
 		log.Infof("Product %s not in store", productDiff.ID)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	} else {
 		log.Infof("Product %s updated in store", productDiff.ID)
@@ -203,21 +245,33 @@ func (api *API) deleteProductMemorySingle(req *restful.Request, resp *restful.Re
 	id := req.QueryParameter("id")
 	if id == "" {
 		log.Infof("No id provided in request")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	expected, err := ifMatchVersion(req.Request.Context(), req, api.storage, id)
+	if err != nil {
+		log.Infof("Failed to check If-Match of request, err=%v", err)
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 
 	// delete product from api storage
-	deleted, err := api.storage.Delete(id)
+	var deleted bool
+	if expected != nil {
+		deleted, err = api.storage.DeleteIfVersion(req.Request.Context(), id, *expected)
+	} else {
+		deleted, err = api.storage.Delete(req.Request.Context(), id)
+	}
+
 	if err != nil {
-		log.Errorf("Failed to delete product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to delete product from store"))
+		writeStorageError(req, resp, err, "failed to delete product from store")
 		return
 	}
 
 	if !deleted {
 		log.Infof("Product %s not in store", id)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	} else {
 		log.Infof("Product %s deleted from store", id)
@@ -230,21 +284,33 @@ func (api *API) deleteProductHTTPSingle(req *restful.Request, resp *restful.Resp
 	id := req.QueryParameter("id")
 	if id == "" {
 		log.Infof("No id provided in request")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	expected, err := ifMatchVersion(req.Request.Context(), req, api.client, id)
+	if err != nil {
+		log.Infof("Failed to check If-Match of request, err=%v", err)
+		writeStorageError(req, resp, err, "failed to get product from store")
 		return
 	}
 
 	// delete product from api storage
-	deleted, err := api.client.Delete(id)
+	var deleted bool
+	if expected != nil {
+		deleted, err = api.client.DeleteIfVersion(req.Request.Context(), id, *expected)
+	} else {
+		deleted, err = api.client.Delete(req.Request.Context(), id)
+	}
+
 	if err != nil {
-		log.Errorf("Failed to delete product from storage, err=%v", err)
-		_ = resp.WriteError(http.StatusInternalServerError, fmt.Errorf("failed to delete product from store"))
+		writeStorageError(req, resp, err, "failed to delete product from store")
 		return
 	}
 
 	if !deleted {
 		log.Infof("Product %s not in store", id)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	} else {
 		log.Infof("Product %s deleted from store", id)
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) adjustStockMemorySingle(req *restful.Request, resp *restful.Response) {
+	adjustStockSingle(req, resp, api.storage)
+}
+
+func (api *API) adjustStockHTTPSingle(req *restful.Request, resp *restful.Response) {
+	adjustStockSingle(req, resp, api.client)
+}
+
+func (api *API) adjustStockMemoryBatch(req *restful.Request, resp *restful.Response) {
+	api.adjustStockBatch(req, resp, memoryBackend, api.storage)
+}
+
+func (api *API) adjustStockHTTPBatch(req *restful.Request, resp *restful.Response) {
+	api.adjustStockBatch(req, resp, httpBackend, api.client)
+}
+
+// adjustStockSingle adds the delta of the request to the stock of a product and answers
+// with the updated product, or 409 Conflict if there is not enough stock to take away
+func adjustStockSingle(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	adjustment := domain.StockAdjustment{}
+	err := req.ReadEntity(&adjustment)
+	if err != nil {
+		log.Errorf("Failed to read stock adjustment, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	result := adjustStockItem(req.Request.Context(), storage, 0, adjustment)
+	if !result.Succeeded() {
+		writeProblem(req, resp, result.Status, errors.New(result.Error))
+		return
+	}
+
+	resp.AddHeader("ETag", etag(result.Product.Version, ""))
+	_ = resp.WriteAsJson(result.Product)
+}
+
+func (api *API) adjustStockBatch(req *restful.Request, resp *restful.Response, backend string, storage domain.Storage) {
+	adjustments, ok := readBatch[domain.StockAdjustment](req, resp)
+	if !ok {
+		return
+	}
+
+	concurrency, ok := api.batchConcurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	p := batchProcessor{
+		storage: storage,
+		item: func(ctx context.Context, storage domain.Storage, i int) domain.BatchItemResult {
+			return adjustStockItem(ctx, storage, i, adjustments[i])
+		},
+		id: func(i int) string {
+			return adjustments[i].ID
+		},
+		failure: "failed to adjust stock",
+	}
+	if bulk, ok := storage.(domain.BatchStorage); ok {
+		p.chunk = func(ctx context.Context, start, end int) ([]domain.BatchItemResult, error) {
+			return bulk.AdjustStockBatch(ctx, adjustments[start:end], false)
+		}
+		p.atomic = func(ctx context.Context) ([]domain.BatchItemResult, error) {
+			return bulk.AdjustStockBatch(ctx, adjustments, true)
+		}
+	}
+
+	api.applyBatch(req, resp, backend, domain.JobOperationStock, len(adjustments), concurrency, p)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listTrashMemory(req *restful.Request, resp *restful.Response) {
+	listTrash(req, resp, api.storage)
+}
+
+func (api *API) listTrashHTTP(req *restful.Request, resp *restful.Response) {
+	listTrash(req, resp, api.client)
+}
+
+func (api *API) restoreProductMemory(req *restful.Request, resp *restful.Response) {
+	restoreProduct(req, resp, api.storage)
+}
+
+func (api *API) restoreProductHTTP(req *restful.Request, resp *restful.Response) {
+	restoreProduct(req, resp, api.client)
+}
+
+func (api *API) purgeTrashMemory(req *restful.Request, resp *restful.Response) {
+	purgeTrash(req, resp, api.storage)
+}
+
+func (api *API) purgeTrashHTTP(req *restful.Request, resp *restful.Response) {
+	purgeTrash(req, resp, api.client)
+}
+
+func listTrash(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	products, err := storage.Trash(req.Request.Context())
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to list trash")
+		return
+	}
+
+	_ = resp.WriteAsJson(products)
+}
+
+func restoreProduct(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	restored, err := storage.Restore(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to restore product")
+		return
+	}
+
+	if !restored {
+		log.Infof("Product %s not in trash", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not in trash"))
+		return
+	}
+
+	log.Infof("Product %s restored from trash", id)
+	_ = resp.WriteAsJson("Product " + id + " restored from trash")
+}
+
+// purgeTrash removes the product named by id from the trash for good,
+// or every product deleted before the RFC 3339 time in before
+func purgeTrash(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id, before := req.QueryParameter("id"), req.QueryParameter("before")
+	switch {
+	case id != "" && before != "":
+		log.Infof("Both id and before provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("only one of id and before may be provided"))
+	case id != "":
+		purgeProduct(req, resp, storage, id)
+	case before != "":
+		purgeProductsBefore(req, resp, storage, before)
+	default:
+		log.Infof("Neither id nor before provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id or before must be provided"))
+	}
+}
+
+func purgeProduct(req *restful.Request, resp *restful.Response, storage domain.Storage, id string) {
+	purged, err := storage.Purge(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to purge product")
+		return
+	}
+
+	if !purged {
+		log.Infof("Product %s not in trash", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not in trash"))
+		return
+	}
+
+	log.Infof("Product %s purged from trash", id)
+	_ = resp.WriteAsJson("Product " + id + " purged from trash")
+}
+
+func purgeProductsBefore(req *restful.Request, resp *restful.Response, storage domain.Storage, before string) {
+	at, err := time.Parse(time.RFC3339Nano, before)
+	if err != nil {
+		log.Infof("Invalid before %q in request", before)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("before must be an RFC 3339 timestamp"))
+		return
+	}
+
+	purged, err := storage.PurgeTrash(req.Request.Context(), at)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to purge trash")
+		return
+	}
+
+	log.Infof("Purged %d products deleted before %s", purged, before)
+	_ = resp.WriteAsJson(domain.PurgeResult{Purged: purged})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) createWarehouseMemory(req *restful.Request, resp *restful.Response) {
+	createWarehouse(req, resp, api.storage)
+}
+
+func (api *API) createWarehouseHTTP(req *restful.Request, resp *restful.Response) {
+	createWarehouse(req, resp, api.client)
+}
+
+func (api *API) getWarehouseMemory(req *restful.Request, resp *restful.Response) {
+	getWarehouse(req, resp, api.storage)
+}
+
+func (api *API) getWarehouseHTTP(req *restful.Request, resp *restful.Response) {
+	getWarehouse(req, resp, api.client)
+}
+
+func (api *API) listWarehousesMemory(req *restful.Request, resp *restful.Response) {
+	listWarehouses(req, resp, api.storage)
+}
+
+func (api *API) listWarehousesHTTP(req *restful.Request, resp *restful.Response) {
+	listWarehouses(req, resp, api.client)
+}
+
+func (api *API) warehouseStockMemory(req *restful.Request, resp *restful.Response) {
+	warehouseStock(req, resp, api.storage)
+}
+
+func (api *API) warehouseStockHTTP(req *restful.Request, resp *restful.Response) {
+	warehouseStock(req, resp, api.client)
+}
+
+func (api *API) stockLevelsMemory(req *restful.Request, resp *restful.Response) {
+	stockLevels(req, resp, api.storage)
+}
+
+func (api *API) stockLevelsHTTP(req *restful.Request, resp *restful.Response) {
+	stockLevels(req, resp, api.client)
+}
+
+func (api *API) transferStockMemory(req *restful.Request, resp *restful.Response) {
+	transferStock(req, resp, api.storage)
+}
+
+func (api *API) transferStockHTTP(req *restful.Request, resp *restful.Response) {
+	transferStock(req, resp, api.client)
+}
+
+func createWarehouse(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	warehouse := domain.Warehouse{}
+	err := req.ReadEntity(&warehouse)
+	if err != nil {
+		log.Errorf("Failed to read warehouse, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := warehouse.Validate(); err != nil {
+		log.Infof("Invalid warehouse in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	alreadyExists, err := storage.CreateWarehouse(req.Request.Context(), warehouse)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to save warehouse")
+		return
+	}
+
+	if alreadyExists {
+		log.Infof("Warehouse %s already in store", warehouse.ID)
+		writeProblem(req, resp, http.StatusConflict, domain.NewError(domain.ErrAlreadyExists, "", "warehouse already exists"))
+		return
+	}
+
+	log.Infof("Warehouse %s saved in store", warehouse.ID)
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+warehouse.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, warehouse, restful.MIME_JSON)
+}
+
+func getWarehouse(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.PathParameter("id")
+	warehouse, exists, err := storage.GetWarehouse(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get warehouse")
+		return
+	}
+
+	if !exists {
+		log.Infof("Warehouse %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("warehouse not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(warehouse)
+}
+
+func listWarehouses(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	warehouses, err := storage.ListWarehouses(req.Request.Context())
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to list warehouses")
+		return
+	}
+
+	_ = resp.WriteAsJson(warehouses)
+}
+
+// warehouseStock answers with the stock of every product held in the warehouse named in the path
+func warehouseStock(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.PathParameter("id")
+	levels, exists, err := storage.WarehouseStock(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get warehouse stock")
+		return
+	}
+
+	if !exists {
+		log.Infof("Warehouse %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("warehouse not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(levels)
+}
+
+// stockLevels answers with the stock of a product in every warehouse
+func stockLevels(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Infof("No id provided in request")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	levels, exists, err := storage.StockLevels(req.Request.Context(), id)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to get stock levels")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	_ = resp.WriteAsJson(levels)
+}
+
+// transferStock moves stock of a product between warehouses and answers with both updated levels,
+// or 409 Conflict if the source has not enough available
+func transferStock(req *restful.Request, resp *restful.Response, storage domain.Storage) {
+	transfer := domain.StockTransfer{}
+	err := req.ReadEntity(&transfer)
+	if err != nil {
+		log.Errorf("Failed to read stock transfer, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := transfer.Validate(); err != nil {
+		log.Infof("Invalid stock transfer in request, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	levels, exists, err := storage.TransferStock(req.Request.Context(), transfer)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to transfer stock")
+		return
+	}
+
+	if !exists {
+		log.Infof("Product %s not in store", transfer.ID)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	log.Infof("Moved %d of product %s from warehouse %s to %s", transfer.Quantity, transfer.ID, transfer.From, transfer.To)
+	_ = resp.WriteAsJson(levels)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"exam-api/domain"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// statusOf maps an error returned by the storage to the status of the response.
+// The specific errors with a status of their own come first, the kinds of errors after them,
+// anything else is a failure of the service.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, domain.ErrUnknownWarehouse),
+		errors.Is(err, domain.ErrNoExchangeRate),
+		errors.Is(err, domain.ErrIdempotencyKeyReused),
+		errors.Is(err, domain.ErrInvalidPatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrAlreadyExists), errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// storageFailure logs err of a storage call and returns the status and the message to answer with.
+// Errors caused by the request are answered as they are, failures of the service only with message.
+func storageFailure(err error, message string) (int, string) {
+	status := statusOf(err)
+	if status >= http.StatusInternalServerError {
+		log.Errorf("Storage failed with err=%v, answering %d", err, status)
+		return status, message
+	}
+	log.Infof("Storage rejected the request with err=%v, answering %d", err, status)
+	return status, err.Error()
+}
+
+// writeStorageError answers a failed storage call with the status mapped from err
+func writeStorageError(req *restful.Request, resp *restful.Response, err error, message string) {
+	status, message := storageFailure(err, message)
+	if status >= http.StatusInternalServerError {
+		err = errors.New(message)
+	}
+	writeProblem(req, resp, status, err)
+}
+
+// failedItem is the result of a batch item whose storage call failed with err
+func failedItem(index int, id string, err error, message string) domain.BatchItemResult {
+	status, message := storageFailure(err, message)
+	return domain.BatchItemResult{Index: index, ID: id, Status: status, Error: message}
+}
+
+// writeProblem answers with an application/problem+json body describing err
+func writeProblem(req *restful.Request, resp *restful.Response, status int, err error) {
+	problem := domain.NewProblem(status, err, req.Request.URL.RequestURI())
+	_ = resp.WriteHeaderAndJson(status, problem, domain.ProblemContentType)
+}
+
+// writeAlreadyExists answers 409 with a problem naming the id the entity is already stored under
+func writeAlreadyExists(req *restful.Request, resp *restful.Response, id string, err error) {
+	problem := domain.NewProblem(http.StatusConflict, err, req.Request.URL.RequestURI())
+	problem.ID = id
+	_ = resp.WriteHeaderAndJson(http.StatusConflict, problem, domain.ProblemContentType)
+}
+
+// WriteServiceError answers the errors of the container itself, such as a path without a route, with a problem
+func WriteServiceError(serviceErr restful.ServiceError, req *restful.Request, resp *restful.Response) {
+	writeProblem(req, resp, serviceErr.Code, errors.New(serviceErr.Message))
+}
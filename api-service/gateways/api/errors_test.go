@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"exam-api/domain"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// The table is the same in both services but for the errors only one of them has,
+// so that a status changed in one copy of statusOf and not the other fails here.
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", domain.ErrNotFound, http.StatusNotFound},
+		{"already exists", domain.ErrAlreadyExists, http.StatusConflict},
+		{"conflict", domain.ErrConflict, http.StatusConflict},
+		{"validation", domain.ErrValidation, http.StatusBadRequest},
+		{"unavailable", domain.ErrUnavailable, http.StatusServiceUnavailable},
+
+		{"error of the not found kind", domain.NewError(domain.ErrNotFound, "", "no such reservation"), http.StatusNotFound},
+		{"error of the already exists kind", domain.NewError(domain.ErrAlreadyExists, "", "warehouse exists"), http.StatusConflict},
+		{"error of the conflict kind", domain.NewError(domain.ErrConflict, "", "busy"), http.StatusConflict},
+		{"error of the validation kind", domain.NewError(domain.ErrValidation, "", "bad name"), http.StatusBadRequest},
+		{"error of the unavailable kind", domain.NewError(domain.ErrUnavailable, "", "database is down"), http.StatusServiceUnavailable},
+		{"driver error wrapped as unavailable", domain.WrapError(domain.ErrUnavailable, errors.New("connection refused")), http.StatusServiceUnavailable},
+		{"driver error wrapped as conflict", domain.WrapError(domain.ErrConflict, errors.New("serialization failure")), http.StatusConflict},
+		{"kind wrapped with context", fmt.Errorf("update batch: %w", domain.ErrNotFound), http.StatusNotFound},
+
+		{"version mismatch", domain.ErrVersionMismatch, http.StatusPreconditionFailed},
+		{"version mismatch wrapped with context", fmt.Errorf("item 3: %w", domain.ErrVersionMismatch), http.StatusPreconditionFailed},
+		{"insufficient stock", domain.ErrInsufficientStock, http.StatusConflict},
+		{"unknown warehouse", domain.ErrUnknownWarehouse, http.StatusUnprocessableEntity},
+		{"unknown currency", domain.ErrUnknownCurrency, http.StatusBadRequest},
+		{"no exchange rate", domain.ErrNoExchangeRate, http.StatusUnprocessableEntity},
+		{"invalid sku", domain.ErrInvalidSKU, http.StatusBadRequest},
+		{"text too long", fmt.Errorf("name %w", domain.ErrTextTooLong), http.StatusBadRequest},
+		{"id taken", domain.ErrIDTaken, http.StatusConflict},
+		{"reservation closed", domain.ErrReservationClosed, http.StatusConflict},
+		{"price change closed", domain.ErrPriceChangeClosed, http.StatusConflict},
+		{"malformed cursor", domain.ErrMalformedCursor, http.StatusBadRequest},
+		{"cursor mismatch", domain.ErrCursorMismatch, http.StatusBadRequest},
+		{"idempotency key reused", domain.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+		{"idempotency key in progress", domain.ErrIdempotencyKeyInProgress, http.StatusConflict},
+
+		// only the api service applies JSON patches
+		{"invalid patch", fmt.Errorf("%w: unsupported op", domain.ErrInvalidPatch), http.StatusUnprocessableEntity},
+		{"patch test failed", domain.ErrPatchTestFailed, http.StatusConflict},
+
+		{"error outside the domain", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusOf(tt.err); got != tt.want {
+			t.Errorf("%s: statusOf(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int64
+		err    error
+	}{
+		{header: "", want: nil},
+		{header: "*", want: nil},
+		{header: `"3"`, want: []int64{3}},
+		{header: `"3", "5"`, want: []int64{3, 5}},
+		{header: `"3-EUR"`, want: []int64{3}},
+		{header: `W/"3", "5"`, want: []int64{5}},
+		{header: `W/"3"`, err: domain.ErrVersionMismatch},
+		{header: `"three"`, err: errInvalidIfMatch},
+	}
+	for _, tt := range tests {
+		got, err := parseIfMatch(tt.header)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseIfMatch(%q) error = %v, want %v", tt.header, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIfMatch(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestETagTellsConvertedRepresentationsApart(t *testing.T) {
+	stored, converted := etag(3, ""), etag(3, "EUR")
+	if stored == converted {
+		t.Fatalf("etag of the stored and converted product are both %s", stored)
+	}
+
+	versions, err := parseIfMatch(converted)
+	if err != nil || !reflect.DeepEqual(versions, []int64{3}) {
+		t.Errorf("parseIfMatch(%s) = %v, %v, want the version it was taken from", converted, versions, err)
+	}
+}
+
+func TestIfMatchVersionPicksTheCurrentVersionOutOfSeveral(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+	id, _, err := store.Save(ctx, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ifMatch := func(header string) *restful.Request {
+		r := httptest.NewRequest(http.MethodDelete, "/", nil)
+		r.Header.Set("If-Match", header)
+		return restful.NewRequest(r)
+	}
+
+	expected, err := ifMatchVersion(ctx, ifMatch(`"4", "1"`), store, id)
+	if err != nil || expected == nil || *expected != 1 {
+		t.Errorf("ifMatchVersion = %v, %v, want the current version 1", expected, err)
+	}
+	if _, err := ifMatchVersion(ctx, ifMatch(`"4", "5"`), store, id); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("ifMatchVersion error = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"exam-api/domain"
+	"exam-api/gateways/memory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// newIdempotentContainer serves handler behind the idempotency filter at POST /product
+func newIdempotentContainer(handler restful.RouteFunction) *restful.Container {
+	api := &API{keys: memory.NewIdempotencyKeys(), keyTTL: time.Minute}
+
+	ws := new(restful.WebService)
+	ws.Filter(api.idempotent)
+	ws.Route(ws.POST("/product").To(handler))
+
+	container := restful.NewContainer()
+	container.Add(ws)
+	return container
+}
+
+// serveKeyed sends a POST /product with the idempotency key and If-Match header,
+// answering 500 like the server would if the handler panics
+func serveKeyed(container *restful.Container, key, ifMatch string) (status int) {
+	r := httptest.NewRequest(http.MethodPost, "/product", nil)
+	r.Header.Set(domain.IdempotencyKeyHeader, key)
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+
+	defer func() {
+		if recover() != nil {
+			status = http.StatusInternalServerError
+		}
+	}()
+	container.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestIdempotentReleasesTheKeyOfAHandlerThatPanics(t *testing.T) {
+	calls := 0
+	container := newIdempotentContainer(func(req *restful.Request, resp *restful.Response) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		resp.WriteHeader(http.StatusCreated)
+	})
+
+	if status := serveKeyed(container, "retry", ""); status != http.StatusInternalServerError {
+		t.Fatalf("first request answered %d, want the panic", status)
+	}
+	if status := serveKeyed(container, "retry", ""); status != http.StatusCreated {
+		t.Errorf("retry answered %d, want %d as the key was released", status, http.StatusCreated)
+	}
+}
+
+func TestIdempotentTellsIfMatchApart(t *testing.T) {
+	container := newIdempotentContainer(func(req *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusNoContent)
+	})
+
+	if status := serveKeyed(container, "delete", `"3"`); status != http.StatusNoContent {
+		t.Fatalf("first request answered %d, want %d", status, http.StatusNoContent)
+	}
+	if status := serveKeyed(container, "delete", `"3"`); status != http.StatusNoContent {
+		t.Errorf("repeat answered %d, want the response replayed", status)
+	}
+	if status := serveKeyed(container, "delete", `"4"`); status != http.StatusUnprocessableEntity {
+		t.Errorf("request with another If-Match answered %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+}
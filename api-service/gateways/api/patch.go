@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"exam-api/domain"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
+// readProductDiff reads the body of a single PATCH request.
+// A plain JSON body is a ProductDiff whose diff member is a merge patch.
+// A merge patch or JSON Patch body is applied to the product named by ?id=,
+// a JSON Patch is resolved against its current state into a full patch that is
+// conditional on the version it was computed from.
+// The patch is checked with the rules a new product is checked with.
+// On failure it returns the status code the request should be answered with.
+func readProductDiff(req *restful.Request, storage domain.Storage) (domain.ProductDiff, int, error) {
+	diff, status, err := decodeProductDiff(req, storage)
+	if err != nil {
+		return diff, status, err
+	}
+	if err := diff.Diff.Validate(); err != nil {
+		return diff, http.StatusBadRequest, err
+	}
+	return diff, http.StatusOK, nil
+}
+
+// decodeProductDiff reads the body of a single PATCH request in whichever of the formats above it is in
+func decodeProductDiff(req *restful.Request, storage domain.Storage) (domain.ProductDiff, int, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.HeaderParameter(restful.HEADER_ContentType))
+
+	switch mediaType {
+	case mimeMergePatch:
+		diff := domain.ProductDiff{ID: req.QueryParameter("id")}
+		if diff.ID == "" {
+			return diff, http.StatusBadRequest, fmt.Errorf("id must be provided")
+		}
+		if err := json.NewDecoder(req.Request.Body).Decode(&diff.Diff); err != nil {
+			return diff, http.StatusBadRequest, err
+		}
+		return diff, http.StatusOK, nil
+
+	case mimeJSONPatch:
+		diff := domain.ProductDiff{ID: req.QueryParameter("id")}
+		if diff.ID == "" {
+			return diff, http.StatusBadRequest, fmt.Errorf("id must be provided")
+		}
+		var ops []domain.PatchOperation
+		if err := json.NewDecoder(req.Request.Body).Decode(&ops); err != nil {
+			return diff, http.StatusBadRequest, err
+		}
+
+		current, exists, err := storage.Get(req.Request.Context(), diff.ID)
+		if err != nil {
+			status, message := storageFailure(err, "failed to get product from store")
+			return diff, status, errors.New(message)
+		}
+		if !exists {
+			return diff, http.StatusNotFound, fmt.Errorf("product not found")
+		}
+
+		patched, err := domain.ApplyJSONPatch(current, ops)
+		if err != nil {
+			return diff, statusOf(err), err
+		}
+
+		diff.Diff = domain.PatchFrom(patched)
+		diff.Version = &current.Version
+		return diff, http.StatusOK, nil
+
+	default:
+		diff := domain.ProductDiff{}
+		if err := req.ReadEntity(&diff); err != nil {
+			return diff, http.StatusBadRequest, err
+		}
+		return diff, http.StatusOK, nil
+	}
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"exam-api/domain"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retention is how long finished jobs are kept around for polling
+const retention = time.Hour
+
+// RunFunc processes the batch of the job with the given id in the background, calling report
+// once for every finished item. It must return early once ctx is cancelled.
+type RunFunc func(ctx context.Context, jobID string, report func(result domain.BatchItemResult))
+
+type entry struct {
+	job    domain.Job
+	cancel context.CancelFunc
+	// reported flags the items of the job whose outcome is recorded
+	reported []bool
+}
+
+// Manager keeps track of background batch jobs, regardless of the backend they run against
+type Manager struct {
+	jobs map[string]*entry
+	mu   sync.RWMutex
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		jobs: make(map[string]*entry),
+		mu:   sync.RWMutex{},
+	}
+}
+
+// Start registers a new job and runs it in the background.
+// The returned job is a snapshot taken before any item was processed.
+func (m *Manager) Start(backend string, operation domain.JobOperation, total int, run RunFunc) domain.Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: domain.Job{
+			ID:        newID(),
+			Backend:   backend,
+			Operation: operation,
+			Status:    domain.JobStatusRunning,
+			Total:     total,
+			Results:   make([]domain.BatchItemResult, 0, total),
+			CreatedAt: time.Now().UTC(),
+		},
+		cancel:   cancel,
+		reported: make([]bool, total),
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.jobs[e.job.ID] = e
+	snapshot := copyJob(e.job)
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		run(ctx, e.job.ID, func(result domain.BatchItemResult) {
+			m.report(e, result)
+		})
+		m.finish(e)
+	}()
+
+	return snapshot
+}
+
+// Get returns a snapshot of the job with the given id
+func (m *Manager) Get(id string) (domain.Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return domain.Job{}, false
+	}
+	return copyJob(e.job), true
+}
+
+// Cancel stops a running job. Items already processed are not rolled back,
+// and those that finish afterwards are not recorded.
+// The second return value is false if the job does not exist.
+func (m *Manager) Cancel(id string) (domain.Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return domain.Job{}, false
+	}
+
+	if e.job.Status == domain.JobStatusRunning {
+		e.cancel()
+		e.job.Status = domain.JobStatusCancelled
+		now := time.Now().UTC()
+		e.job.FinishedAt = &now
+	}
+	return copyJob(e.job), true
+}
+
+func (m *Manager) report(e *entry, result domain.BatchItemResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e.job.Status != domain.JobStatusRunning {
+		return
+	}
+	if result.Index >= 0 && result.Index < len(e.reported) {
+		if e.reported[result.Index] {
+			return
+		}
+		e.reported[result.Index] = true
+	}
+
+	e.record(result)
+}
+
+// record adds the outcome of an item to the job
+func (e *entry) record(result domain.BatchItemResult) {
+	e.job.Results = append(e.job.Results, result)
+	e.job.Processed++
+	if result.Succeeded() {
+		e.job.Succeeded++
+	} else {
+		e.job.Failed++
+	}
+}
+
+func (m *Manager) finish(e *entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// a cancelled job keeps its status and finish time
+	if e.job.Status != domain.JobStatusRunning {
+		return
+	}
+	// a completed job accounts for every item, those its run never reported failed
+	for i, reported := range e.reported {
+		if !reported {
+			e.record(domain.BatchItemResult{Index: i, Status: http.StatusInternalServerError, Error: "item not processed"})
+		}
+	}
+	e.job.Status = domain.JobStatusCompleted
+	now := time.Now().UTC()
+	e.job.FinishedAt = &now
+}
+
+// prune drops finished jobs older than the retention period.
+// The caller must hold the writer's lock.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-retention)
+	for id, e := range m.jobs {
+		if e.job.FinishedAt != nil && e.job.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func copyJob(job domain.Job) domain.Job {
+	results := make([]domain.BatchItemResult, len(job.Results))
+	copy(results, job.Results)
+	job.Results = results
+	return job
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read only fails if the OS entropy source is unavailable
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
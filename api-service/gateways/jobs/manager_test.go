@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"exam-api/domain"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitFor polls the job until done reports true for it
+func waitFor(t *testing.T, m *Manager, id string, done func(domain.Job) bool) domain.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if done(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not get there in time, got %+v", id, job)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerRecordsReportedItems(t *testing.T) {
+	m := NewManager()
+
+	var gotID string
+	job := m.Start("redis", domain.JobOperationDelete, 2, func(ctx context.Context, jobID string, report func(domain.BatchItemResult)) {
+		gotID = jobID
+		report(domain.BatchItemResult{Index: 0, ID: "laptop", Status: http.StatusOK})
+		report(domain.BatchItemResult{Index: 1, ID: "mouse", Status: http.StatusNotFound, Error: "product not found"})
+	})
+
+	job = waitFor(t, m, job.ID, func(job domain.Job) bool {
+		return job.Status == domain.JobStatusCompleted
+	})
+	if gotID != job.ID {
+		t.Errorf("run got job id %q, want %q", gotID, job.ID)
+	}
+	if job.Processed != 2 || job.Succeeded != 1 || job.Failed != 1 || len(job.Results) != 2 {
+		t.Errorf("job %+v, want 2 processed items, 1 succeeded and 1 failed", job)
+	}
+}
+
+func TestManagerIgnoresItemsReportedAfterCancel(t *testing.T) {
+	m := NewManager()
+
+	cancelled := make(chan struct{})
+	finished := make(chan struct{})
+	job := m.Start("redis", domain.JobOperationCreate, 2, func(ctx context.Context, _ string, report func(domain.BatchItemResult)) {
+		defer close(finished)
+		report(domain.BatchItemResult{Index: 0, Status: http.StatusCreated})
+
+		<-cancelled
+		if ctx.Err() == nil {
+			t.Error("run context not cancelled with the job")
+		}
+		// an item the store finished after the job was cancelled
+		report(domain.BatchItemResult{Index: 1, Status: http.StatusCreated})
+	})
+
+	waitFor(t, m, job.ID, func(job domain.Job) bool {
+		return job.Processed == 1
+	})
+	if _, ok := m.Cancel(job.ID); !ok {
+		t.Fatalf("Cancel did not find job %s", job.ID)
+	}
+	close(cancelled)
+	<-finished
+
+	job, _ = m.Get(job.ID)
+	if job.Status != domain.JobStatusCancelled {
+		t.Errorf("job status %q, want %q", job.Status, domain.JobStatusCancelled)
+	}
+	if job.Processed != 1 || len(job.Results) != 1 {
+		t.Errorf("job recorded %d items, want only the one finished before the cancel", job.Processed)
+	}
+}
+
+func TestManagerFailsItemsNeverReported(t *testing.T) {
+	m := NewManager()
+
+	job := m.Start("redis", domain.JobOperationDelete, 3, func(ctx context.Context, _ string, report func(domain.BatchItemResult)) {
+		report(domain.BatchItemResult{Index: 1, ID: "mouse", Status: http.StatusOK})
+		report(domain.BatchItemResult{Index: 1, ID: "mouse", Status: http.StatusOK})
+	})
+
+	job = waitFor(t, m, job.ID, func(job domain.Job) bool {
+		return job.Status == domain.JobStatusCompleted
+	})
+	if job.Processed != 3 || job.Succeeded != 1 || job.Failed != 2 {
+		t.Fatalf("job %+v, want every item processed, the unreported ones failed", job)
+	}
+	for _, result := range job.Results {
+		if result.Index != 1 && result.Status != http.StatusInternalServerError {
+			t.Errorf("unreported item %d has status %d, want %d", result.Index, result.Status, http.StatusInternalServerError)
+		}
+	}
+}
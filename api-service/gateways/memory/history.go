@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"time"
+)
+
+// history holds the revisions of every product in the order they were recorded,
+// including those of deleted products. It is guarded by the mutex of the store it belongs to.
+type history struct {
+	revisions map[string][]domain.ProductRevision
+	lastID    int64
+}
+
+func newHistory() *history {
+	return &history{revisions: make(map[string][]domain.ProductRevision)}
+}
+
+// record appends the state of the product after event to its history
+func (h *history) record(id string, event domain.HistoryEvent, product domain.Product) {
+	h.lastID++
+	h.revisions[id] = append(h.revisions[id], domain.ProductRevision{
+		ID:         h.lastID,
+		ProductID:  id,
+		Event:      event,
+		Product:    product,
+		RecordedAt: time.Now(),
+	})
+}
+
+func (s *Store) History(ctx context.Context, productID string, limit int) ([]domain.ProductRevision, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions, ok := s.history.revisions[s.resolve(productID)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	result := make([]domain.ProductRevision, 0)
+	for i := len(revisions) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, revisions[i])
+	}
+	return result, true, nil
+}
+
+func (s *Store) GetAsOf(ctx context.Context, id string, at time.Time) (domain.Product, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	product, ok := domain.ProductAsOf(s.history.revisions[s.resolve(id)], at)
+	return product, ok, nil
+}
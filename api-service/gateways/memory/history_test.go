@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"testing"
+	"time"
+)
+
+func TestGetAsOfFollowsTheHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	id := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 1000, Stock: 5})
+	if _, err := s.Update(ctx, id, domain.ProductDiff{Diff: domain.ProductPatch{Price: domain.Value(1200)}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := s.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Restore(ctx, id); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if _, err := s.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	revisions, found, err := s.History(ctx, id, 10)
+	if err != nil || !found {
+		t.Fatalf("History = %v, %v", found, err)
+	}
+	events := []domain.HistoryEvent{domain.HistoryDeleted, domain.HistoryRestored, domain.HistoryDeleted, domain.HistoryUpdated, domain.HistoryCreated}
+	if len(revisions) != len(events) {
+		t.Fatalf("History = %+v, want %d revisions", revisions, len(events))
+	}
+	for i, event := range events {
+		if revisions[i].Event != event {
+			t.Errorf("revision %d is %s, want %s", i, revisions[i].Event, event)
+		}
+	}
+	created, updated, deleted, restored, deletedAgain := revisions[4], revisions[3], revisions[2], revisions[1], revisions[0]
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantFound bool
+		wantPrice int
+	}{
+		{name: "before creation", at: created.RecordedAt.Add(-time.Nanosecond)},
+		{name: "at creation", at: created.RecordedAt, wantFound: true, wantPrice: 1000},
+		{name: "after update", at: updated.RecordedAt, wantFound: true, wantPrice: 1200},
+		{name: "after delete", at: deleted.RecordedAt},
+		{name: "after restore", at: restored.RecordedAt, wantFound: true, wantPrice: 1200},
+		{name: "after the last delete", at: deletedAgain.RecordedAt.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, found, err := s.GetAsOf(ctx, id, tt.at)
+			if err != nil {
+				t.Fatalf("GetAsOf: %v", err)
+			}
+			if found != tt.wantFound || product.Price != tt.wantPrice {
+				t.Errorf("GetAsOf = %+v, %v, want price %d, %v", product, found, tt.wantPrice, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestGetAsOfOfAnUnknownProductFindsNothing(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, found, err := s.GetAsOf(context.Background(), "unknown", time.Now()); found || err != nil {
+		t.Errorf("GetAsOf = %v, %v, want nothing found", found, err)
+	}
+	if _, found, err := s.History(context.Background(), "unknown", 10); found || err != nil {
+		t.Errorf("History = %v, %v, want nothing found", found, err)
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"exam-api/domain"
+	"sync"
+	"time"
+)
+
+var _ domain.IdempotencyStore = (*IdempotencyKeys)(nil)
+
+// IdempotencyKeys keeps the responses to writes carrying an Idempotency-Key in memory,
+// whichever backend the writes went to
+type IdempotencyKeys struct {
+	keys map[string]idempotencyKey
+	mu   sync.Mutex
+}
+
+// idempotencyKey is a claimed key, its response is nil until the request is answered
+type idempotencyKey struct {
+	fingerprint string
+	response    *domain.IdempotentResponse
+	expiresAt   time.Time
+}
+
+func NewIdempotencyKeys() *IdempotencyKeys {
+	return &IdempotencyKeys{
+		keys: make(map[string]idempotencyKey),
+	}
+}
+
+func (k *IdempotencyKeys) Begin(key, fingerprint string, ttl time.Duration) (*domain.IdempotentResponse, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	claimed, ok := k.keys[key]
+	if !ok || !now.Before(claimed.expiresAt) {
+		k.keys[key] = idempotencyKey{fingerprint: fingerprint, expiresAt: now.Add(ttl)}
+		return nil, nil
+	}
+
+	switch {
+	case claimed.fingerprint != fingerprint:
+		return nil, domain.ErrIdempotencyKeyReused
+	case claimed.response == nil:
+		return nil, domain.ErrIdempotencyKeyInProgress
+	}
+	response := *claimed.response
+	return &response, nil
+}
+
+func (k *IdempotencyKeys) Complete(key string, response domain.IdempotentResponse) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if claimed, ok := k.keys[key]; ok {
+		claimed.response = &response
+		k.keys[key] = claimed
+	}
+	return nil
+}
+
+func (k *IdempotencyKeys) Release(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if claimed, ok := k.keys[key]; ok && claimed.response == nil {
+		delete(k.keys, key)
+	}
+	return nil
+}
+
+func (k *IdempotencyKeys) ExpireIdempotencyKeys(now time.Time) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	expired := 0
+	for key, claimed := range k.keys {
+		if !now.Before(claimed.expiresAt) {
+			delete(k.keys, key)
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"time"
+)
+
+// ledger holds the stock movements of every product in the order they happened.
+// It is guarded by the mutex of the store it belongs to.
+type ledger struct {
+	movements map[string][]domain.StockMovement
+	lastID    int64
+}
+
+func newLedger() *ledger {
+	return &ledger{movements: make(map[string][]domain.StockMovement)}
+}
+
+// record appends a movement to the ledger of the product, a zero delta leaves no trace
+func (l *ledger) record(productID, warehouse string, delta int, reason domain.MovementReason, actor, reference string) {
+	if delta == 0 {
+		return
+	}
+
+	l.lastID++
+	l.movements[productID] = append(l.movements[productID], domain.StockMovement{
+		ID:        l.lastID,
+		ProductID: productID,
+		Warehouse: warehouse,
+		Delta:     delta,
+		Reason:    reason,
+		Actor:     actor,
+		Reference: reference,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *Store) StockMovements(ctx context.Context, productID string, reason domain.MovementReason, limit int) ([]domain.StockMovement, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	productID = s.resolve(productID)
+	if _, ok := s.products[productID]; !ok {
+		return nil, false, nil
+	}
+
+	movements := s.ledger.movements[productID]
+	result := make([]domain.StockMovement, 0)
+	for i := len(movements) - 1; i >= 0 && len(result) < limit; i-- {
+		if reason == "" || movements[i].Reason == reason {
+			result = append(result, movements[i])
+		}
+	}
+	return result, true, nil
+}
+
+func (s *Store) ReconcileStock(ctx context.Context, productID string) (domain.StockReconciliation, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	productID = s.resolve(productID)
+	product, ok := s.products[productID]
+	if !ok {
+		return domain.StockReconciliation{}, false, nil
+	}
+
+	ledgerStock := 0
+	for _, movement := range s.ledger.movements[productID] {
+		ledgerStock += movement.Delta
+	}
+	return domain.NewStockReconciliation(productID, product.Stock, ledgerStock), true, nil
+}
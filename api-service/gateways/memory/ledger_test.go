@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"testing"
+)
+
+func TestEveryStockChangeWritesOneMovement(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	if _, err := s.CreateWarehouse(ctx, domain.Warehouse{ID: "north", Name: "North"}); err != nil {
+		t.Fatalf("CreateWarehouse: %v", err)
+	}
+	var id string
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   []domain.StockMovement
+	}{
+		{
+			name: "save",
+			change: func() (err error) {
+				id, _, err = s.Save(ctx, domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 10})
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "main", Delta: 10, Reason: domain.MovementInitial}},
+		},
+		{
+			name: "update of the stock",
+			change: func() error {
+				_, err := s.Update(ctx, id, domain.ProductDiff{Diff: domain.ProductPatch{Stock: domain.Value(12)}})
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "main", Delta: 2, Reason: domain.MovementAdjustment}},
+		},
+		{
+			name: "update leaving the stock alone",
+			change: func() error {
+				_, err := s.Update(ctx, id, domain.ProductDiff{Diff: domain.ProductPatch{Price: domain.Value(999)}})
+				return err
+			},
+		},
+		{
+			name: "adjustment with a reason",
+			change: func() error {
+				_, _, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -3, Reason: domain.MovementSale, Actor: "till-1", Reference: "order-7"})
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "main", Delta: -3, Reason: domain.MovementSale, Actor: "till-1", Reference: "order-7"}},
+		},
+		{
+			name: "adjustment without a reason",
+			change: func() error {
+				_, _, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: 1})
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "main", Delta: 1, Reason: domain.MovementAdjustment}},
+		},
+		{
+			name: "restock of another warehouse",
+			change: func() error {
+				_, _, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: 4, Warehouse: "north", Reason: domain.MovementRestock})
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "north", Delta: 4, Reason: domain.MovementRestock}},
+		},
+		{
+			name: "released reservation",
+			change: func() error {
+				reservation, _, err := s.Reserve(ctx, domain.ReservationRequest{ProductID: id, Quantity: 2, TTLSeconds: 60})
+				if err != nil {
+					return err
+				}
+				_, _, err = s.ReleaseReservation(ctx, reservation.ID)
+				return err
+			},
+		},
+		{
+			name: "confirmed reservation",
+			change: func() error {
+				reservation, _, err := s.Reserve(ctx, domain.ReservationRequest{ProductID: id, Quantity: 2, TTLSeconds: 60})
+				if err != nil {
+					return err
+				}
+				_, _, err = s.ConfirmReservation(ctx, reservation.ID)
+				return err
+			},
+			want: []domain.StockMovement{{Warehouse: "main", Delta: -2, Reason: domain.MovementSale}},
+		},
+		{
+			name: "transfer",
+			change: func() error {
+				_, _, err := s.TransferStock(ctx, domain.StockTransfer{ID: id, From: "main", To: "north", Quantity: 3, Reference: "truck-2"})
+				return err
+			},
+			want: []domain.StockMovement{
+				{Warehouse: "main", Delta: -3, Reason: domain.MovementTransfer, Reference: "truck-2"},
+				{Warehouse: "north", Delta: 3, Reason: domain.MovementTransfer, Reference: "truck-2"},
+			},
+		},
+	}
+
+	seen := 0
+	for _, tt := range tests {
+		if err := tt.change(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		movements, _, err := s.StockMovements(ctx, id, "", 100)
+		if err != nil {
+			t.Fatalf("%s: StockMovements: %v", tt.name, err)
+		}
+		if got := len(movements) - seen; got != len(tt.want) {
+			t.Errorf("%s wrote %d movements, want %d", tt.name, got, len(tt.want))
+			seen = len(movements)
+			continue
+		}
+		// movements come newest first, so the ones this change wrote lead in reverse order
+		for i, want := range tt.want {
+			got := movements[len(tt.want)-1-i]
+			if got.Warehouse != want.Warehouse || got.Delta != want.Delta || got.Reason != want.Reason ||
+				got.Actor != want.Actor || (want.Reference != "" && got.Reference != want.Reference) {
+				t.Errorf("%s wrote %+v, want %+v", tt.name, got, want)
+			}
+		}
+		seen = len(movements)
+	}
+
+	reconciliation, _, err := s.ReconcileStock(ctx, id)
+	if err != nil {
+		t.Fatalf("ReconcileStock: %v", err)
+	}
+	if !reconciliation.Consistent {
+		t.Errorf("ReconcileStock = %+v, want the ledger to add up to the stock", reconciliation)
+	}
+}
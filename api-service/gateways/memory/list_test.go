@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"reflect"
+	"testing"
+)
+
+func TestListPagesThroughNamesByteByByte(t *testing.T) {
+	s := newTestStore(t)
+	names := []string{"apple", "Zebra", "éclair", "banana", "Banana"}
+	for _, name := range names {
+		mustSave(t, s, domain.Product{Name: name, Manufacturer: "Acme"})
+	}
+
+	for _, descending := range []bool{false, true} {
+		want := []string{"Banana", "Zebra", "apple", "banana", "éclair"}
+		if descending {
+			want = []string{"éclair", "banana", "apple", "Zebra", "Banana"}
+		}
+
+		query := domain.ProductQuery{SortBy: domain.SortByName, Descending: descending, Limit: 2}
+		var got []string
+		for pages := 0; pages < len(names); pages++ {
+			page, err := s.List(context.Background(), query)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for _, item := range page.Items {
+				got = append(got, item.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("descending %v: listed %v, want %v", descending, got, want)
+		}
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	s := newTestStore(t)
+	mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 3, Tags: []string{"computers"}})
+	mustSave(t, s, domain.Product{Name: "Lamp", Manufacturer: "Globex", Price: 9900, Stock: 0, Tags: []string{"home"}})
+	mustSave(t, s, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Stock: 40, Tags: []string{"computers"}})
+	trashed := mustSave(t, s, domain.Product{Name: "Lathe", Manufacturer: "Acme", Price: 100})
+	if _, err := s.Delete(context.Background(), trashed); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	one, low, high := 1, 5000, 10000
+	tests := []struct {
+		name  string
+		query domain.ProductQuery
+		want  []string
+	}{
+		{"everything", domain.ProductQuery{}, []string{"Lamp", "Laptop", "Mouse"}},
+		{"manufacturer", domain.ProductQuery{Manufacturer: "Acme"}, []string{"Laptop", "Mouse"}},
+		{"tag", domain.ProductQuery{Tag: "home"}, []string{"Lamp"}},
+		{"name prefix", domain.ProductQuery{NamePrefix: "La"}, []string{"Lamp", "Laptop"}},
+		{"price range", domain.ProductQuery{MinPrice: &low, MaxPrice: &high}, []string{"Lamp", "Mouse"}},
+		{"stock range", domain.ProductQuery{MinStock: &low}, nil},
+		{"in stock", domain.ProductQuery{MinStock: &one, Tag: "computers"}, []string{"Laptop", "Mouse"}},
+	}
+	for _, tt := range tests {
+		tt.query.SortBy = domain.SortByName
+		page, err := s.List(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: List: %v", tt.name, err)
+		}
+		var got []string
+		for _, item := range page.Items {
+			got = append(got, item.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: listed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,33 @@
 package memory
 
 import (
+	"context"
 	"exam-api/domain"
+	"sort"
 	"sync"
 )
 
-// This lines checks if Store implements domain.Storage
+// This lines checks if Store implements domain.Storage and domain.Transactional
 // It will fail at build time if not
 var _ domain.Storage = (*Store)(nil)
+var _ domain.Transactional = (*Store)(nil)
 
 type Store struct {
-	products map[string]domain.Product
+	products     map[string]domain.Product
+	index        *SearchIndex
+	reservations map[string]domain.Reservation
+	prices       map[string]domain.PriceChange
+	ledger       *ledger
+	history      *history
+	trashed      map[string]trashed
+	warehouses   map[string]domain.Warehouse
+	levels       map[string]map[string]level
+	// aliases maps the ids renamed products had before to the ids they have now
+	aliases map[string]string
+	// ids assigns the id of a new product
+	ids domain.IDGenerator
+	// undo is set on the view of a transaction and records what it writes so that it can be rolled back
+	undo *undoLog
 	// We are using a Read-Write Mutex here
 	// This guarantees us when we lock and unlock it that either
 	// At most one goroutine is writing in the map and none are reading or;
@@ -18,71 +35,251 @@ type Store struct {
 	mu sync.RWMutex
 }
 
-func NewStore() *Store {
+func NewStore(ids domain.IDGenerator) *Store {
 	return &Store{
-		products: make(map[string]domain.Product),
-		mu:       sync.RWMutex{},
+		products:     make(map[string]domain.Product),
+		index:        NewSearchIndex(),
+		reservations: make(map[string]domain.Reservation),
+		prices:       make(map[string]domain.PriceChange),
+		ledger:       newLedger(),
+		history:      newHistory(),
+		trashed:      make(map[string]trashed),
+		warehouses:   newWarehouses(),
+		levels:       make(map[string]map[string]level),
+		aliases:      make(map[string]string),
+		ids:          ids,
+		mu:           sync.RWMutex{},
 	}
 }
 
-func (s *Store) Save(product domain.Product) (string, bool, error) {
+func (s *Store) Save(ctx context.Context, product domain.Product) (string, bool, error) {
 	// Lock - writer's lock
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.products[product.GetHash()]
-	if ok {
-		return product.GetHash(), true, nil
+	id, err := s.ids.NewID(product)
+	if err != nil {
+		return "", false, err
+	}
+
+	// a trashed product still holds its id until it is purged
+	for _, known := range append([]string{id}, s.ids.PreviousIDs(product)...) {
+		if s.holds(known) {
+			return known, true, nil
+		}
 	}
-	s.products[product.GetHash()] = product
-	return product.GetHash(), false, nil
+	s.touch(id)
+	product.Currency = product.CurrencyCode()
+	product.Version = 1
+	product.Reserved = 0
+	product.Available = product.Stock
+	product.SKU = ""
+	s.products[id] = product
+	s.index.Add(id, product)
+	s.levels[id] = map[string]level{domain.DefaultWarehouse: {quantity: product.Stock}}
+	s.ledger.record(id, domain.DefaultWarehouse, product.Stock, domain.MovementInitial, "", "")
+	s.history.record(id, domain.HistoryCreated, product)
+	return id, false, nil
 }
 
-func (s *Store) Get(id string) (domain.Product, bool, error) {
+func (s *Store) Get(ctx context.Context, id string) (domain.Product, bool, error) {
 	// RLock - reader's lock
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	p, ok := s.products[id]
+	p, ok := s.products[s.resolve(id)]
 	if !ok {
 		return domain.Product{}, false, nil
 	}
 	return p, true, nil
 }
 
-func (s *Store) Update(id string, diff domain.ProductDiff) (bool, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *Store) Update(ctx context.Context, id string, diff domain.ProductDiff) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// check if id exists in map
-	_, ok := s.products[id]
+	id = s.resolve(id)
+	current, ok := s.products[id]
+	if !ok {
+		return false, nil
+	}
+
+	if diff.Version != nil && *diff.Version != current.Version {
+		return false, domain.ErrVersionMismatch
+	}
 
-	// initialize new product
-	newProduct := domain.Product{
-		Name:         s.products[id].Name,
-		Manufacturer: s.products[id].Manufacturer,
-		Price:        diff.Diff.Price,
-		Stock:        diff.Diff.Stock,
-		Tags:         diff.Diff.Tags,
+	// merge the patch into the current product
+	newProduct := diff.Diff.Apply(current)
+	newProduct.Version = current.Version + 1
+	newProduct.Available = newProduct.Stock - newProduct.Reserved
+
+	// a new stock is applied to the default warehouse, which must keep what it has reserved
+	delta := newProduct.Stock - current.Stock
+	main := s.levels[id][domain.DefaultWarehouse]
+	if main.quantity+delta < main.reserved {
+		return true, domain.ErrInsufficientStock
 	}
+	main.quantity += delta
 
 	// update product
+	s.touch(id)
 	s.products[id] = newProduct
+	s.index.Add(id, newProduct)
+	s.setLevel(id, domain.DefaultWarehouse, main)
+	s.ledger.record(id, domain.DefaultWarehouse, delta, domain.MovementAdjustment, "", "")
+	s.history.record(id, domain.HistoryUpdated, newProduct)
 
 	// return updated product
 	return ok, nil
 }
 
-func (s *Store) Delete(id string) (bool, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *Store) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// check if id exists in map
+	id = s.resolve(id)
 	_, ok := s.products[id]
-
-	// delete id from products
-	delete(s.products, id)
+	if ok {
+		s.trash(id)
+	}
 
 	// return deleted product
 	return ok, nil
 }
+
+func (s *Store) DeleteIfVersion(ctx context.Context, id string, version int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id = s.resolve(id)
+	current, ok := s.products[id]
+	if !ok {
+		return false, nil
+	}
+
+	if current.Version != version {
+		return false, domain.ErrVersionMismatch
+	}
+
+	s.trash(id)
+	return true, nil
+}
+
+func (s *Store) AdjustStock(ctx context.Context, adjustment domain.StockAdjustment) (domain.Product, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, delta, warehouse := s.resolve(adjustment.ID), adjustment.Delta, adjustment.WarehouseID()
+	current, ok := s.products[id]
+	if !ok {
+		return domain.Product{}, false, nil
+	}
+	if _, ok := s.warehouses[warehouse]; !ok {
+		return current, true, domain.ErrUnknownWarehouse
+	}
+
+	// the check and the write happen under the same lock so concurrent adjustments cannot oversell,
+	// stock held by reservations cannot be taken away either
+	l := s.levels[id][warehouse]
+	if l.available()+delta < 0 {
+		return current, true, domain.ErrInsufficientStock
+	}
+	l.quantity += delta
+
+	s.touch(id)
+	current.Stock += delta
+	current.Available += delta
+	current.Version++
+	s.products[id] = current
+	s.setLevel(id, warehouse, l)
+	s.ledger.record(id, warehouse, delta, adjustment.MovementReason(), adjustment.Actor, adjustment.Reference)
+	s.history.record(id, domain.HistoryUpdated, current)
+	return current, true, nil
+}
+
+func (s *Store) List(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	var cursor *domain.Cursor
+	if query.Cursor != "" {
+		c, err := query.DecodeCursor()
+		if err != nil {
+			return domain.ProductPage{}, err
+		}
+		cursor = &c
+	}
+
+	s.mu.RLock()
+	matches := make([]domain.ListedProduct, 0)
+	for id, product := range s.products {
+		if !query.Matches(product) {
+			continue
+		}
+		listed := domain.ListedProduct{ID: id, Product: product}
+		if cursor != nil && !query.After(listed, *cursor) {
+			continue
+		}
+		matches = append(matches, listed)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return query.Less(matches[i], matches[j])
+	})
+	return query.Page(matches), nil
+}
+
+func (s *Store) Search(ctx context.Context, text string, limit int) ([]domain.SearchResult, error) {
+	s.mu.RLock()
+	scores := s.index.Search(text)
+	results := make([]domain.SearchResult, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, domain.SearchResult{
+			ListedProduct: domain.ListedProduct{ID: id, Product: s.products[id]},
+			Score:         score,
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Atomically runs fn against a view of the store while holding the writer's lock.
+// The view records what it overwrites in an undo log, which is played back if fn returns an error,
+// so either every change made by fn is kept or none is, at a cost that grows with what fn touches rather than with the store.
+func (s *Store) Atomically(fn func(tx domain.Storage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the view shares the maps and index but has its own mutex, since we already hold ours
+	tx := &Store{
+		products:     s.products,
+		index:        s.index,
+		reservations: s.reservations,
+		prices:       s.prices,
+		ledger:       s.ledger,
+		history:      s.history,
+		trashed:      s.trashed,
+		warehouses:   s.warehouses,
+		levels:       s.levels,
+		aliases:      s.aliases,
+		ids:          s.ids,
+		undo:         newUndoLog(s.undo),
+	}
+	if err := fn(tx); err != nil {
+		s.rollBack(tx.undo)
+		return err
+	}
+
+	return nil
+}
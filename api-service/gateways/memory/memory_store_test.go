@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"reflect"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	ids, err := domain.NewIDGenerator(domain.DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	return NewStore(ids)
+}
+
+func mustSave(t *testing.T, s *Store, product domain.Product) string {
+	t.Helper()
+
+	id, exists, err := s.Save(context.Background(), product)
+	if err != nil || exists {
+		t.Fatalf("Save(%s) = %v, %v", product.Name, exists, err)
+	}
+	return id
+}
+
+func TestAtomicallyRollsBackEverythingTheBatchTouched(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	laptop := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+	mouse := mustSave(t, s, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Stock: 2})
+	if _, _, err := s.Reserve(ctx, domain.ReservationRequest{ProductID: mouse, Quantity: 1, TTLSeconds: 60}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	wantLaptop, _, _ := s.Get(ctx, laptop)
+	wantMouse, _, _ := s.Get(ctx, mouse)
+	wantLevels, _, _ := s.StockLevels(ctx, laptop)
+	wantMovements, _, _ := s.StockMovements(ctx, laptop, "", 10)
+
+	rollback := errors.New("roll back")
+	var keyboard string
+	err := s.Atomically(func(tx domain.Storage) error {
+		var err error
+		if keyboard, _, err = tx.Save(ctx, domain.Product{Name: "Keyboard", Manufacturer: "Acme", Price: 2999}); err != nil {
+			return err
+		}
+		if _, _, err = tx.AdjustStock(ctx, domain.StockAdjustment{ID: laptop, Delta: -3}); err != nil {
+			return err
+		}
+		if _, err = tx.Delete(ctx, mouse); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Atomically = %v, want %v", err, rollback)
+	}
+
+	if _, found, _ := s.Get(ctx, keyboard); found {
+		t.Error("product saved by a rolled back batch is still stored")
+	}
+	if results, _ := s.Search(ctx, "keyboard", 10); len(results) != 0 {
+		t.Errorf("search finds %+v, want the rolled back product unindexed", results)
+	}
+	if got, _, _ := s.Get(ctx, laptop); !reflect.DeepEqual(got, wantLaptop) {
+		t.Errorf("laptop %+v, want %+v", got, wantLaptop)
+	}
+	if got, _, _ := s.StockLevels(ctx, laptop); !reflect.DeepEqual(got, wantLevels) {
+		t.Errorf("laptop levels %+v, want %+v", got, wantLevels)
+	}
+	if got, _, _ := s.StockMovements(ctx, laptop, "", 10); !reflect.DeepEqual(got, wantMovements) {
+		t.Errorf("laptop movements %+v, want %+v", got, wantMovements)
+	}
+	if got, _, _ := s.Get(ctx, mouse); !reflect.DeepEqual(got, wantMouse) {
+		t.Errorf("mouse %+v, want %+v", got, wantMouse)
+	}
+	if trash, _ := s.Trash(ctx); len(trash) != 0 {
+		t.Errorf("trash holds %+v, want it empty", trash)
+	}
+}
+
+func TestAtomicallyKeepsChangesOfBatchesThatSucceed(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	laptop := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+
+	err := s.Atomically(func(tx domain.Storage) error {
+		_, _, err := tx.AdjustStock(ctx, domain.StockAdjustment{ID: laptop, Delta: -3})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Atomically: %v", err)
+	}
+
+	if got, _, _ := s.Get(ctx, laptop); got.Stock != 2 || got.Version != 2 {
+		t.Errorf("laptop %+v, want the adjustment kept", got)
+	}
+}
+
+func TestAtomicallyRollsBackNestedBatchesWithTheOuterOne(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	rollback := errors.New("roll back")
+	var laptop string
+	err := s.Atomically(func(tx domain.Storage) error {
+		err := tx.(domain.Transactional).Atomically(func(inner domain.Storage) error {
+			var err error
+			laptop, _, err = inner.Save(ctx, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Atomically = %v, want %v", err, rollback)
+	}
+
+	if _, found, _ := s.Get(ctx, laptop); found {
+		t.Error("product saved by a nested batch survived the rollback of the outer one")
+	}
+}
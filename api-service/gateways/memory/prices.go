@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"sort"
+	"time"
+)
+
+func (s *Store) SchedulePrice(ctx context.Context, request domain.PriceChangeRequest) (domain.PriceChange, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productID := s.resolve(request.ProductID)
+	product, ok := s.products[productID]
+	if !ok {
+		return domain.PriceChange{}, false, nil
+	}
+
+	// a change without a currency keeps the product in the one it is priced in now
+	currency := request.Currency
+	if currency == "" {
+		currency = product.CurrencyCode()
+	}
+
+	change := domain.PriceChange{
+		ID:            domain.NewPriceChangeID(),
+		ProductID:     productID,
+		Price:         request.Price,
+		Currency:      currency,
+		EffectiveFrom: request.EffectiveFrom,
+		Status:        domain.PriceChangePending,
+		CreatedAt:     time.Now(),
+	}
+	s.touchPrice(change.ID)
+	s.prices[change.ID] = change
+	return change, true, nil
+}
+
+func (s *Store) GetPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	change, ok := s.prices[id]
+	return change, ok, nil
+}
+
+func (s *Store) PriceSchedule(ctx context.Context, productID string) ([]domain.PriceChange, bool, error) {
+	s.mu.RLock()
+	productID = s.resolve(productID)
+	_, ok := s.products[productID]
+	changes := make([]domain.PriceChange, 0)
+	for _, change := range s.prices {
+		if change.ProductID == productID {
+			changes = append(changes, change)
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return scheduledBefore(changes[i], changes[j])
+	})
+	return changes, true, nil
+}
+
+func (s *Store) CancelPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.prices[id]
+	if !ok {
+		return domain.PriceChange{}, false, nil
+	}
+	if change.Status != domain.PriceChangePending {
+		return change, true, domain.ErrPriceChangeClosed
+	}
+
+	change.Status = domain.PriceChangeCancelled
+	s.touchPrice(id)
+	s.prices[id] = change
+	return change, true, nil
+}
+
+func (s *Store) PriceHistory(ctx context.Context, productID string, limit int) ([]domain.PricePoint, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions, ok := s.history.revisions[s.resolve(productID)]
+	if !ok {
+		return nil, false, nil
+	}
+	return domain.PriceHistory(revisions, limit), true, nil
+}
+
+// ApplyPriceChanges sets the price and currency of every product with a pending change due by now.
+// When several changes of a product are due only the latest is applied, the others are superseded.
+// Products in the trash keep their changes pending until they are restored.
+// It returns the number of changes applied.
+func (s *Store) ApplyPriceChanges(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make(map[string]domain.PriceChange)
+	for id, change := range s.prices {
+		if change.Status != domain.PriceChangePending || change.EffectiveFrom.After(now) {
+			continue
+		}
+		if _, ok := s.products[change.ProductID]; !ok {
+			continue
+		}
+
+		latest, ok := due[change.ProductID]
+		if !ok || scheduledBefore(latest, change) {
+			due[change.ProductID] = change
+			if ok {
+				latest.Status = domain.PriceChangeSuperseded
+				s.touchPrice(latest.ID)
+				s.prices[latest.ID] = latest
+			}
+			continue
+		}
+		change.Status = domain.PriceChangeSuperseded
+		s.touchPrice(id)
+		s.prices[id] = change
+	}
+
+	for productID, change := range due {
+		s.touch(productID)
+		s.touchPrice(change.ID)
+		product := s.products[productID]
+		product.Price, product.Currency = change.Price, change.Currency
+		product.Version++
+		s.products[productID] = product
+		s.index.Add(productID, product)
+		s.history.record(productID, domain.HistoryUpdated, product)
+
+		appliedAt := now
+		change.Status = domain.PriceChangeApplied
+		change.AppliedAt = &appliedAt
+		s.prices[change.ID] = change
+	}
+	return len(due)
+}
+
+// scheduledBefore orders price changes by effective time, then by the time they were scheduled
+func scheduledBefore(a, b domain.PriceChange) bool {
+	if !a.EffectiveFrom.Equal(b.EffectiveFrom) {
+		return a.EffectiveFrom.Before(b.EffectiveFrom)
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
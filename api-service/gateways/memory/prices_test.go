@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyPriceChangesSetsTheCurrencyOfTheChange(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	laptop := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Currency: "USD"})
+	now := time.Now()
+
+	kept, _, err := s.SchedulePrice(ctx, domain.PriceChangeRequest{ProductID: laptop, Price: 450000, EffectiveFrom: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("SchedulePrice: %v", err)
+	}
+	if kept.Currency != "USD" {
+		t.Errorf("change without a currency is in %q, want the product's USD", kept.Currency)
+	}
+	if applied := s.ApplyPriceChanges(now); applied != 1 {
+		t.Fatalf("ApplyPriceChanges applied %d changes, want 1", applied)
+	}
+
+	if _, _, err := s.SchedulePrice(ctx, domain.PriceChangeRequest{ProductID: laptop, Price: 420000, Currency: "EUR", EffectiveFrom: now}); err != nil {
+		t.Fatalf("SchedulePrice: %v", err)
+	}
+	s.ApplyPriceChanges(now)
+
+	if got, _, _ := s.Get(ctx, laptop); got.Price != 420000 || got.Currency != "EUR" {
+		t.Errorf("laptop %+v, want it priced 420000 EUR", got)
+	}
+
+	points, _, err := s.PriceHistory(ctx, laptop, 10)
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	got := make([]domain.Money, len(points))
+	for i, point := range points {
+		got[i] = point.PriceMoney()
+	}
+	want := []domain.Money{{Amount: 420000, Currency: "EUR"}, {Amount: 450000, Currency: "USD"}, {Amount: 499999, Currency: "USD"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("price history %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+)
+
+func (s *Store) Rename(ctx context.Context, rename domain.ProductRename) (domain.RenameResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.resolve(rename.ID)
+	current, ok := s.products[id]
+	if !ok {
+		return domain.RenameResult{}, false, nil
+	}
+
+	if rename.Version != nil && *rename.Version != current.Version {
+		return domain.RenameResult{}, true, domain.ErrVersionMismatch
+	}
+
+	renamed := rename.Apply(current)
+	newID, err := s.ids.RenamedID(id, renamed)
+	if err != nil {
+		return domain.RenameResult{}, true, err
+	}
+	for _, candidate := range append([]string{newID}, s.ids.PreviousIDs(renamed)...) {
+		if candidate != id && s.resolve(candidate) != id && s.taken(candidate) {
+			return domain.RenameResult{}, true, domain.ErrIDTaken
+		}
+	}
+
+	renamed.Version = current.Version + 1
+	s.touch(id)
+	s.touch(newID)
+	if newID != id {
+		s.rekey(id, newID)
+		s.touchAlias(id)
+		s.aliases[id] = newID
+	}
+	s.products[newID] = renamed
+	s.index.Add(newID, renamed)
+	s.history.record(newID, domain.HistoryRenamed, renamed)
+
+	result := domain.RenameResult{ID: newID, Product: renamed}
+	if newID != id {
+		result.PreviousID = id
+	}
+	return result, true, nil
+}
+
+// resolve returns the id the product known by id is stored under, following the alias left
+// by a rename unless id belongs to a product of its own. The caller must hold a lock.
+func (s *Store) resolve(id string) string {
+	if s.holds(id) {
+		return id
+	}
+	if target, ok := s.aliases[id]; ok {
+		return target
+	}
+	return id
+}
+
+// holds reports whether a product, trashed or not, is stored under id
+func (s *Store) holds(id string) bool {
+	_, ok := s.products[id]
+	_, inTrash := s.trashed[id]
+	return ok || inTrash
+}
+
+// taken reports whether id is used by a product or as an alias
+func (s *Store) taken(id string) bool {
+	_, aliased := s.aliases[id]
+	return s.holds(id) || aliased
+}
+
+// rekey moves everything kept about a product from one id to another, aliases of the product follow it.
+// The caller must hold the writer's lock.
+func (s *Store) rekey(from, to string) {
+	s.touch(from)
+	s.touch(to)
+	delete(s.products, from)
+	s.index.Remove(from)
+	s.touchAlias(to)
+	delete(s.aliases, to)
+	for alias, target := range s.aliases {
+		if target == from {
+			s.touchAlias(alias)
+			s.aliases[alias] = to
+		}
+	}
+
+	if levels, ok := s.levels[from]; ok {
+		delete(s.levels, from)
+		s.levels[to] = levels
+	}
+
+	// the slices are shared with snapshots, so the moved entries are copies
+	if movements, ok := s.ledger.movements[from]; ok {
+		moved := make([]domain.StockMovement, len(movements))
+		for i, movement := range movements {
+			movement.ProductID = to
+			moved[i] = movement
+		}
+		delete(s.ledger.movements, from)
+		s.ledger.movements[to] = moved
+	}
+	if revisions, ok := s.history.revisions[from]; ok {
+		moved := make([]domain.ProductRevision, len(revisions))
+		for i, revision := range revisions {
+			revision.ProductID = to
+			moved[i] = revision
+		}
+		delete(s.history.revisions, from)
+		s.history.revisions[to] = moved
+	}
+
+	for reservationID, reservation := range s.reservations {
+		if reservation.ProductID == from {
+			s.touchReservation(reservationID)
+			reservation.ProductID = to
+			s.reservations[reservationID] = reservation
+		}
+	}
+	for changeID, change := range s.prices {
+		if change.ProductID == from {
+			s.touchPrice(changeID)
+			change.ProductID = to
+			s.prices[changeID] = change
+		}
+	}
+}
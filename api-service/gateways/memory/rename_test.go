@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"testing"
+	"time"
+)
+
+func TestRenamedProductIsFoundUnderItsPreviousID(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	previous := mustSave(t, s, domain.Product{Name: "Lapte", Manufacturer: "Napolac", Price: 899, Stock: 10})
+
+	name := "Napolact"
+	renamed, _, err := s.Rename(ctx, domain.ProductRename{ID: previous, Manufacturer: &name})
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	id := renamed.ID
+	if id == previous {
+		t.Fatalf("rename kept id %s, want the product moved", id)
+	}
+
+	product, found, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: previous, Delta: -2})
+	if err != nil || !found || product.Stock != 8 {
+		t.Fatalf("AdjustStock = %+v, %v, %v, want the stock of the renamed product taken", product, found, err)
+	}
+	reservation, found, err := s.Reserve(ctx, domain.ReservationRequest{ProductID: previous, Quantity: 1, TTLSeconds: 60})
+	if err != nil || !found || reservation.ProductID != id {
+		t.Errorf("Reserve = %+v, %v, %v, want a reservation of %s", reservation, found, err, id)
+	}
+	change, found, err := s.SchedulePrice(ctx, domain.PriceChangeRequest{ProductID: previous, Price: 999, EffectiveFrom: time.Now().Add(time.Hour)})
+	if err != nil || !found || change.ProductID != id {
+		t.Errorf("SchedulePrice = %+v, %v, %v, want a change of %s", change, found, err, id)
+	}
+	if changes, found, _ := s.PriceSchedule(ctx, previous); !found || len(changes) != 1 {
+		t.Errorf("PriceSchedule = %+v, %v, want the scheduled change", changes, found)
+	}
+	if movements, found, _ := s.StockMovements(ctx, previous, "", 10); !found || len(movements) != 2 {
+		t.Errorf("StockMovements = %+v, %v, want the initial stock and the adjustment", movements, found)
+	}
+	if levels, found, _ := s.StockLevels(ctx, previous); !found || len(levels) != 1 || levels[0].ProductID != id {
+		t.Errorf("StockLevels = %+v, %v, want the levels of %s", levels, found, id)
+	}
+
+	if _, err := s.CreateWarehouse(ctx, domain.Warehouse{ID: "north", Name: "North"}); err != nil {
+		t.Fatalf("CreateWarehouse: %v", err)
+	}
+	if levels, found, err := s.TransferStock(ctx, domain.StockTransfer{ID: previous, From: domain.DefaultWarehouse, To: "north", Quantity: 3}); err != nil || !found || len(levels) != 2 {
+		t.Errorf("TransferStock = %+v, %v, %v, want stock moved", levels, found, err)
+	}
+
+	if _, err := s.Delete(ctx, previous); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if restored, err := s.Restore(ctx, previous); err != nil || !restored {
+		t.Errorf("Restore = %v, %v, want the renamed product restored", restored, err)
+	}
+}
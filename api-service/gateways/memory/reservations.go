@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"time"
+)
+
+// reservationRetention is how long closed reservations are kept around after they expire
+const reservationRetention = time.Hour
+
+func (s *Store) Reserve(ctx context.Context, request domain.ReservationRequest) (domain.Reservation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productID, quantity, warehouse := s.resolve(request.ProductID), request.Quantity, request.WarehouseID()
+	product, ok := s.products[productID]
+	if !ok {
+		return domain.Reservation{}, false, nil
+	}
+	if _, ok := s.warehouses[warehouse]; !ok {
+		return domain.Reservation{}, true, domain.ErrUnknownWarehouse
+	}
+
+	l := s.levels[productID][warehouse]
+	if l.available() < quantity {
+		return domain.Reservation{}, true, domain.ErrInsufficientStock
+	}
+	l.reserved += quantity
+
+	s.touch(productID)
+	product.Reserved += quantity
+	product.Available -= quantity
+	s.products[productID] = product
+	s.setLevel(productID, warehouse, l)
+
+	now := time.Now()
+	reservation := domain.Reservation{
+		ID:        domain.NewReservationID(),
+		ProductID: productID,
+		Warehouse: warehouse,
+		Quantity:  quantity,
+		Status:    domain.ReservationPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(request.TTL()),
+	}
+	s.touchReservation(reservation.ID)
+	s.reservations[reservation.ID] = reservation
+	return reservation, true, nil
+}
+
+func (s *Store) GetReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reservation, ok := s.reservations[id]
+	return reservation, ok, nil
+}
+
+func (s *Store) ConfirmReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[id]
+	if !ok {
+		return domain.Reservation{}, false, nil
+	}
+
+	// a reservation past its expiry cannot be confirmed even if the sweeper has not caught it yet
+	if reservation.Status == domain.ReservationPending && !time.Now().Before(reservation.ExpiresAt) {
+		reservation = s.close(reservation, domain.ReservationExpired)
+	}
+	if reservation.Status != domain.ReservationPending {
+		return reservation, true, domain.ErrReservationClosed
+	}
+
+	product, ok := s.products[reservation.ProductID]
+	if !ok {
+		// the product was deleted while the reservation was pending
+		return s.close(reservation, domain.ReservationReleased), true, domain.ErrReservationClosed
+	}
+
+	l := s.levels[reservation.ProductID][reservation.Warehouse]
+	l.quantity -= reservation.Quantity
+	l.reserved -= reservation.Quantity
+
+	s.touch(reservation.ProductID)
+	product.Stock -= reservation.Quantity
+	product.Reserved -= reservation.Quantity
+	product.Version++
+	s.products[reservation.ProductID] = product
+	s.setLevel(reservation.ProductID, reservation.Warehouse, l)
+	s.ledger.record(reservation.ProductID, reservation.Warehouse, -reservation.Quantity, domain.MovementSale, "", reservation.ID)
+	s.history.record(reservation.ProductID, domain.HistoryUpdated, product)
+
+	reservation.Status = domain.ReservationConfirmed
+	s.touchReservation(id)
+	s.reservations[id] = reservation
+	return reservation, true, nil
+}
+
+func (s *Store) ReleaseReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[id]
+	if !ok {
+		return domain.Reservation{}, false, nil
+	}
+
+	if reservation.Status != domain.ReservationPending {
+		return reservation, true, domain.ErrReservationClosed
+	}
+
+	return s.close(reservation, domain.ReservationReleased), true, nil
+}
+
+// ExpireReservations closes every pending reservation that expired by now, handing its stock back,
+// and forgets closed reservations that expired more than reservationRetention ago.
+// It returns the number of reservations that expired.
+func (s *Store) ExpireReservations(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for id, reservation := range s.reservations {
+		if now.Before(reservation.ExpiresAt) {
+			continue
+		}
+		if reservation.Status == domain.ReservationPending {
+			s.close(reservation, domain.ReservationExpired)
+			expired++
+			continue
+		}
+		if now.Sub(reservation.ExpiresAt) > reservationRetention {
+			s.touchReservation(id)
+			delete(s.reservations, id)
+		}
+	}
+	return expired
+}
+
+// close moves a pending reservation to status and hands its stock back to the product and warehouse.
+// The caller must hold the writer's lock.
+func (s *Store) close(reservation domain.Reservation, status domain.ReservationStatus) domain.Reservation {
+	if product, ok := s.products[reservation.ProductID]; ok {
+		s.touch(reservation.ProductID)
+		product.Reserved -= reservation.Quantity
+		product.Available += reservation.Quantity
+		s.products[reservation.ProductID] = product
+
+		l := s.levels[reservation.ProductID][reservation.Warehouse]
+		l.reserved -= reservation.Quantity
+		s.setLevel(reservation.ProductID, reservation.Warehouse, l)
+	}
+
+	reservation.Status = status
+	s.touchReservation(reservation.ID)
+	s.reservations[reservation.ID] = reservation
+	return reservation
+}
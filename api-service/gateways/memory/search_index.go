@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"exam-api/domain"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Field weights, a match in the name counts more than one in the tags
+const (
+	nameWeight         = 1.0
+	manufacturerWeight = 0.6
+	tagWeight          = 0.4
+)
+
+// Match qualities of a query token against an indexed token
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.75
+	fuzzyMatch  = 0.5
+)
+
+// SearchIndex is an inverted index over the name, manufacturer and tags of products.
+// It is not safe for concurrent use, the Store guards it with its own lock.
+type SearchIndex struct {
+	// postings maps a normalised token to the ids of the products containing it,
+	// with the weight of the heaviest field it appears in
+	postings map[string]map[string]float64
+	// tokens remembers what every product was indexed under so it can be removed
+	tokens map[string][]string
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings: make(map[string]map[string]float64),
+		tokens:   make(map[string][]string),
+	}
+}
+
+// Add indexes product under id, replacing whatever was indexed under it before
+func (ix *SearchIndex) Add(id string, product domain.Product) {
+	ix.Remove(id)
+
+	weights := make(map[string]float64)
+	index := func(text string, weight float64) {
+		for _, token := range tokenize(text) {
+			if weights[token] < weight {
+				weights[token] = weight
+			}
+		}
+	}
+	index(product.Name, nameWeight)
+	index(product.Manufacturer, manufacturerWeight)
+	for _, tag := range product.Tags {
+		index(tag, tagWeight)
+	}
+
+	tokens := make([]string, 0, len(weights))
+	for token, weight := range weights {
+		if ix.postings[token] == nil {
+			ix.postings[token] = make(map[string]float64)
+		}
+		ix.postings[token][id] = weight
+		tokens = append(tokens, token)
+	}
+	ix.tokens[id] = tokens
+}
+
+// Remove drops everything indexed under id
+func (ix *SearchIndex) Remove(id string) {
+	for _, token := range ix.tokens[id] {
+		delete(ix.postings[token], id)
+		if len(ix.postings[token]) == 0 {
+			delete(ix.postings, token)
+		}
+	}
+	delete(ix.tokens, id)
+}
+
+// Search scores every product matching at least one token of text.
+// Query tokens match indexed tokens exactly, as a prefix or within a small edit distance.
+func (ix *SearchIndex) Search(text string) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, queryToken := range tokenize(text) {
+		for token, ids := range ix.postings {
+			quality := matchQuality(queryToken, token)
+			if quality == 0 {
+				continue
+			}
+			for id, weight := range ids {
+				scores[id] += quality * weight
+			}
+		}
+	}
+	return scores
+}
+
+func matchQuality(queryToken, token string) float64 {
+	switch {
+	case token == queryToken:
+		return exactMatch
+	case strings.HasPrefix(token, queryToken):
+		return prefixMatch
+	}
+
+	// short tokens are too easy to confuse with one another
+	maxEdits := 0
+	switch n := len([]rune(queryToken)); {
+	case n >= 8:
+		maxEdits = 2
+	case n >= 4:
+		maxEdits = 1
+	}
+	if maxEdits > 0 && levenshtein(queryToken, token, maxEdits) <= maxEdits {
+		return fuzzyMatch
+	}
+	return 0
+}
+
+// levenshtein returns the edit distance between a and b,
+// or any value above max as soon as it is known to exceed it
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// tokenize splits text into lower case words stripped of diacritics,
+// so that "Lăptărie Şoim" yields "laptarie" and "soim"
+func tokenize(text string) []string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, text)
+	if err != nil {
+		folded = text
+	}
+
+	return strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
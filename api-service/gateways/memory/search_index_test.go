@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Lapte UHT", []string{"lapte", "uht"}},
+		{"Lăptărie Şoim", []string{"laptarie", "soim"}},
+		{"lapte, uht; (1.5%)", []string{"lapte", "uht", "1", "5"}},
+		{"O'Reilly", []string{"o", "reilly"}},
+		{"!!! -- ?", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchIndexRanksMatches(t *testing.T) {
+	ix := NewSearchIndex()
+	ix.Add("name", domain.Product{Name: "Lapte", Manufacturer: "Napolact"})
+	ix.Add("prefix", domain.Product{Name: "Laptop", Manufacturer: "Acme"})
+	ix.Add("manufacturer", domain.Product{Name: "Branza", Manufacturer: "Lapte SRL"})
+	ix.Add("tag", domain.Product{Name: "Iaurt", Manufacturer: "Danone", Tags: []string{"lapte"}})
+	ix.Add("typo", domain.Product{Name: "Lapto", Manufacturer: "Acme"})
+	ix.Add("unrelated", domain.Product{Name: "Paine", Manufacturer: "Vel Pitar"})
+
+	scores := ix.Search("lapte")
+	ranked := []string{"name", "manufacturer", "tag"}
+	for i := 1; i < len(ranked); i++ {
+		if scores[ranked[i-1]] <= scores[ranked[i]] {
+			t.Errorf("%s scored %v, want above %s at %v", ranked[i-1], scores[ranked[i-1]], ranked[i], scores[ranked[i]])
+		}
+	}
+	if scores["typo"] == 0 || scores["typo"] >= scores["name"] {
+		t.Errorf("typo scored %v, want a fuzzy match below the exact one", scores["typo"])
+	}
+	if _, found := scores["unrelated"]; found {
+		t.Error("unrelated product matched")
+	}
+
+	if scores := ix.Search("lap"); scores["prefix"] != prefixMatch*nameWeight {
+		t.Errorf("prefix scored %v, want %v", scores["prefix"], prefixMatch*nameWeight)
+	}
+	// a token this short is never matched fuzzily
+	if scores := ix.Search("lpt"); len(scores) != 0 {
+		t.Errorf("Search(lpt) = %v, want no matches", scores)
+	}
+}
+
+func TestSearchIndexForgetsRemovedProducts(t *testing.T) {
+	ix := NewSearchIndex()
+	ix.Add("laptop", domain.Product{Name: "Laptop", Manufacturer: "Acme"})
+	ix.Add("laptop", domain.Product{Name: "Notebook", Manufacturer: "Acme"})
+
+	if scores := ix.Search("laptop"); len(scores) != 0 {
+		t.Errorf("Search of the previous name = %v, want it forgotten", scores)
+	}
+	ix.Remove("laptop")
+	if scores := ix.Search("notebook acme"); len(scores) != 0 || len(ix.postings) != 0 {
+		t.Errorf("Search after Remove = %v with postings %v, want nothing left", scores, ix.postings)
+	}
+}
+
+func TestSearchOfPunctuationFindsNothing(t *testing.T) {
+	s := newTestStore(t)
+	mustSave(t, s, domain.Product{Name: "Lapte", Manufacturer: "Napolact"})
+
+	results, err := s.Search(context.Background(), "?! -", 10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search = %+v, %v, want no results", results, err)
+	}
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentDecrementsNeverTakeStockBelowZero(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	id := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 50})
+
+	var mu sync.Mutex
+	taken, refused := 0, 0
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 80; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product, _, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -1})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && product.Stock >= 0:
+				taken++
+			case errors.Is(err, domain.ErrInsufficientStock):
+				refused++
+			default:
+				t.Errorf("AdjustStock = %+v, %v", product, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken != 50 || refused != 30 {
+		t.Errorf("%d decrements taken and %d refused, want 50 and 30", taken, refused)
+	}
+	if product, _, _ := s.Get(ctx, id); product.Stock != 0 {
+		t.Errorf("stock %d, want 0", product.Stock)
+	}
+	if _, _, err := s.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -1}); !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Errorf("AdjustStock at zero = %v, want %v", err, domain.ErrInsufficientStock)
+	}
+}
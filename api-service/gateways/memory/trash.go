@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"sort"
+	"time"
+)
+
+// trashed is a deleted product, its stock levels and ledger stay in place so a restore loses nothing
+type trashed struct {
+	product   domain.Product
+	deletedAt time.Time
+}
+
+// trash moves a product to the trash, releasing its pending reservations.
+// The caller must hold the writer's lock and have checked the product exists.
+func (s *Store) trash(id string) {
+	s.touch(id)
+	product := s.products[id]
+	for _, reservation := range s.reservations {
+		if reservation.ProductID == id && reservation.Status == domain.ReservationPending {
+			s.touchReservation(reservation.ID)
+			reservation.Status = domain.ReservationReleased
+			s.reservations[reservation.ID] = reservation
+		}
+	}
+	for warehouse, l := range s.levels[id] {
+		l.reserved = 0
+		s.setLevel(id, warehouse, l)
+	}
+	product.Reserved = 0
+	product.Available = product.Stock
+
+	s.history.record(id, domain.HistoryDeleted, product)
+	s.trashed[id] = trashed{product: product, deletedAt: time.Now()}
+	delete(s.products, id)
+	s.index.Remove(id)
+}
+
+func (s *Store) Trash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	s.mu.RLock()
+	products := make([]domain.TrashedProduct, 0, len(s.trashed))
+	for id, t := range s.trashed {
+		products = append(products, domain.TrashedProduct{
+			ListedProduct: domain.ListedProduct{ID: id, Product: t.product},
+			DeletedAt:     t.deletedAt,
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(products, func(i, j int) bool {
+		if !products[i].DeletedAt.Equal(products[j].DeletedAt) {
+			return products[i].DeletedAt.After(products[j].DeletedAt)
+		}
+		return products[i].ID < products[j].ID
+	})
+	return products, nil
+}
+
+func (s *Store) Restore(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id = s.resolve(id)
+	t, ok := s.trashed[id]
+	if !ok {
+		return false, nil
+	}
+
+	s.touch(id)
+	delete(s.trashed, id)
+	s.products[id] = t.product
+	s.index.Add(id, t.product)
+	s.history.record(id, domain.HistoryRestored, t.product)
+	return true, nil
+}
+
+func (s *Store) Purge(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id = s.resolve(id)
+	if _, ok := s.trashed[id]; !ok {
+		return false, nil
+	}
+
+	s.purge(id)
+	return true, nil
+}
+
+func (s *Store) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, t := range s.trashed {
+		if t.deletedAt.Before(before) {
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purge forgets everything about a trashed product but its history.
+// The caller must hold the writer's lock.
+func (s *Store) purge(id string) {
+	s.touch(id)
+	delete(s.trashed, id)
+	delete(s.ledger.movements, id)
+	delete(s.levels, id)
+	for reservationID, reservation := range s.reservations {
+		if reservation.ProductID == id {
+			s.touchReservation(reservationID)
+			delete(s.reservations, reservationID)
+		}
+	}
+	for changeID, change := range s.prices {
+		if change.ProductID == id {
+			s.touchPrice(changeID)
+			delete(s.prices, changeID)
+		}
+	}
+	for alias, target := range s.aliases {
+		if target == id {
+			s.touchAlias(alias)
+			delete(s.aliases, alias)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"testing"
+	"time"
+)
+
+// listedIDs returns the ids of every product the listing shows
+func listedIDs(t *testing.T, s *Store) []string {
+	t.Helper()
+
+	page, err := s.List(context.Background(), domain.ProductQuery{SortBy: domain.SortByID, Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	ids := make([]string, 0, len(page.Items))
+	for _, item := range page.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestTrashedProductsAreHiddenUntilRestored(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	laptop := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 1000, Stock: 5})
+	mouse := mustSave(t, s, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 50, Stock: 2})
+	reservation, _, err := s.Reserve(ctx, domain.ReservationRequest{ProductID: laptop, Quantity: 2, TTLSeconds: 60})
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	if found, err := s.Delete(ctx, laptop); err != nil || !found {
+		t.Fatalf("Delete = %v, %v", found, err)
+	}
+
+	if _, found, _ := s.Get(ctx, laptop); found {
+		t.Error("Get finds the trashed product")
+	}
+	if ids := listedIDs(t, s); len(ids) != 1 || ids[0] != mouse {
+		t.Errorf("List shows %v, want only %s", ids, mouse)
+	}
+	if results, _ := s.Search(ctx, "laptop", 10); len(results) != 0 {
+		t.Errorf("Search finds %+v, want nothing", results)
+	}
+	if found, _ := s.Update(ctx, laptop, domain.ProductDiff{Diff: domain.ProductPatch{Price: domain.Value(900)}}); found {
+		t.Error("Update changed the trashed product")
+	}
+	if got, _, _ := s.GetReservation(ctx, reservation.ID); got.Status != domain.ReservationReleased {
+		t.Errorf("reservation %s, want it released with the delete", got.Status)
+	}
+	trash, _ := s.Trash(ctx)
+	if len(trash) != 1 || trash[0].ID != laptop || trash[0].Reserved != 0 || trash[0].Stock != 5 {
+		t.Fatalf("Trash = %+v, want the laptop with its stock and nothing reserved", trash)
+	}
+
+	if found, err := s.Restore(ctx, laptop); err != nil || !found {
+		t.Fatalf("Restore = %v, %v", found, err)
+	}
+	if found, _ := s.Restore(ctx, laptop); found {
+		t.Error("Restore of a product no longer in the trash succeeded")
+	}
+
+	if product, found, _ := s.Get(ctx, laptop); !found || product.Price != 1000 || product.Stock != 5 || product.Available != 5 {
+		t.Errorf("Get = %+v, %v, want the product as it was deleted", product, found)
+	}
+	if ids := listedIDs(t, s); len(ids) != 2 {
+		t.Errorf("List shows %v, want both products", ids)
+	}
+	if results, _ := s.Search(ctx, "laptop", 10); len(results) != 1 || results[0].ID != laptop {
+		t.Errorf("Search finds %+v, want the restored product", results)
+	}
+	if trash, _ := s.Trash(ctx); len(trash) != 0 {
+		t.Errorf("Trash = %+v, want it empty", trash)
+	}
+}
+
+func TestPurgeTrashKeepsProductsDeletedWithinRetention(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	old := mustSave(t, s, domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 5})
+	recent := mustSave(t, s, domain.Product{Name: "Mouse", Manufacturer: "Acme", Stock: 2})
+	kept := mustSave(t, s, domain.Product{Name: "Keyboard", Manufacturer: "Acme", Stock: 1})
+
+	if _, err := s.Delete(ctx, old); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	if _, err := s.Delete(ctx, recent); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	purged, err := s.PurgeTrash(ctx, cutoff)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash = %d, %v, want 1 purged", purged, err)
+	}
+	if trash, _ := s.Trash(ctx); len(trash) != 1 || trash[0].ID != recent {
+		t.Errorf("Trash = %+v, want only the product deleted after the cutoff", trash)
+	}
+	if found, _ := s.Restore(ctx, old); found {
+		t.Error("Restore brought back a purged product")
+	}
+	if _, found, _ := s.StockMovements(ctx, old, "", 10); found {
+		t.Error("the ledger of a purged product is kept")
+	}
+	if _, found, _ := s.History(ctx, old, 10); !found {
+		t.Error("the history of a purged product is dropped")
+	}
+	if _, found, _ := s.Get(ctx, kept); !found {
+		t.Error("PurgeTrash removed a product that was never deleted")
+	}
+
+	if found, _ := s.Purge(ctx, kept); found {
+		t.Error("Purge removed a product that is not in the trash")
+	}
+	if found, err := s.Purge(ctx, recent); err != nil || !found {
+		t.Errorf("Purge = %v, %v", found, err)
+	}
+	if trash, _ := s.Trash(ctx); len(trash) != 0 {
+		t.Errorf("Trash = %+v, want it empty", trash)
+	}
+}
@@ -0,0 +1,126 @@
+package memory
+
+import "exam-api/domain"
+
+// undoLog holds what a transaction view overwrote, as it was before the first write,
+// so a rollback only puts back the entries the transaction touched.
+// The log of a nested transaction points to the log of the one it runs in, which must be able to roll back the same writes.
+type undoLog struct {
+	outer        *undoLog
+	products     map[string]before[domain.Product]
+	trashed      map[string]before[trashed]
+	levels       map[string]before[map[string]level]
+	movements    map[string]before[[]domain.StockMovement]
+	revisions    map[string]before[[]domain.ProductRevision]
+	reservations map[string]before[domain.Reservation]
+	prices       map[string]before[domain.PriceChange]
+	aliases      map[string]before[string]
+}
+
+// before is an entry of a map as it was before a transaction wrote it, ok is false when the key was missing
+type before[V any] struct {
+	value V
+	ok    bool
+}
+
+func newUndoLog(outer *undoLog) *undoLog {
+	return &undoLog{
+		outer:        outer,
+		products:     make(map[string]before[domain.Product]),
+		trashed:      make(map[string]before[trashed]),
+		levels:       make(map[string]before[map[string]level]),
+		movements:    make(map[string]before[[]domain.StockMovement]),
+		revisions:    make(map[string]before[[]domain.ProductRevision]),
+		reservations: make(map[string]before[domain.Reservation]),
+		prices:       make(map[string]before[domain.PriceChange]),
+		aliases:      make(map[string]before[string]),
+	}
+}
+
+// remember keeps the entry of m under key unless the log already holds an older one
+func remember[V any](log map[string]before[V], m map[string]V, key string) {
+	if _, ok := log[key]; ok {
+		return
+	}
+	value, ok := m[key]
+	log[key] = before[V]{value: value, ok: ok}
+}
+
+// rollBack puts the entries kept in log back into m
+func rollBack[V any](m map[string]V, log map[string]before[V]) {
+	for key, entry := range log {
+		if entry.ok {
+			m[key] = entry.value
+		} else {
+			delete(m, key)
+		}
+	}
+}
+
+// touch records everything kept under the product id before a transaction view changes it.
+// The ledger and history only ever append, so their slices are kept as they are,
+// the levels of a warehouse are written in place and are copied.
+// Outside of a transaction it does nothing. The caller must hold the writer's lock.
+func (s *Store) touch(id string) {
+	for log := s.undo; log != nil; log = log.outer {
+		remember(log.products, s.products, id)
+		remember(log.trashed, s.trashed, id)
+		remember(log.movements, s.ledger.movements, id)
+		remember(log.revisions, s.history.revisions, id)
+		if _, ok := log.levels[id]; !ok {
+			levels, ok := s.levels[id]
+			log.levels[id] = before[map[string]level]{value: copyMap(levels), ok: ok}
+		}
+	}
+}
+
+// touchReservation records the reservation id before a transaction view changes it
+func (s *Store) touchReservation(id string) {
+	for log := s.undo; log != nil; log = log.outer {
+		remember(log.reservations, s.reservations, id)
+	}
+}
+
+// touchPrice records the price change id before a transaction view changes it
+func (s *Store) touchPrice(id string) {
+	for log := s.undo; log != nil; log = log.outer {
+		remember(log.prices, s.prices, id)
+	}
+}
+
+// touchAlias records the alias before a transaction view changes it
+func (s *Store) touchAlias(alias string) {
+	for log := s.undo; log != nil; log = log.outer {
+		remember(log.aliases, s.aliases, alias)
+	}
+}
+
+// rollBack undoes every write recorded in log, in place so that views of an outer transaction see it too.
+// The caller must hold the writer's lock.
+func (s *Store) rollBack(log *undoLog) {
+	rollBack(s.products, log.products)
+	rollBack(s.trashed, log.trashed)
+	rollBack(s.levels, log.levels)
+	rollBack(s.ledger.movements, log.movements)
+	rollBack(s.history.revisions, log.revisions)
+	rollBack(s.reservations, log.reservations)
+	rollBack(s.prices, log.prices)
+	rollBack(s.aliases, log.aliases)
+
+	for id := range log.products {
+		if product, ok := s.products[id]; ok {
+			s.index.Add(id, product)
+		} else {
+			s.index.Remove(id)
+		}
+	}
+}
+
+// copyMap returns a shallow copy of m
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"exam-api/domain"
+	"sort"
+)
+
+// level is the stock of a product in a single warehouse.
+// The levels of a product are kept in step with its Stock and Reserved totals.
+type level struct {
+	quantity int
+	reserved int
+}
+
+func (l level) available() int {
+	return l.quantity - l.reserved
+}
+
+func (l level) stockLevel(productID, warehouse string) domain.StockLevel {
+	return domain.StockLevel{
+		ProductID: productID,
+		Warehouse: warehouse,
+		Quantity:  l.quantity,
+		Reserved:  l.reserved,
+		Available: l.available(),
+	}
+}
+
+// newWarehouses returns the warehouses every store starts with
+func newWarehouses() map[string]domain.Warehouse {
+	return map[string]domain.Warehouse{
+		domain.DefaultWarehouse: {ID: domain.DefaultWarehouse, Name: domain.DefaultWarehouse},
+	}
+}
+
+// setLevel stores the level of the product in the warehouse.
+// The caller must hold the writer's lock.
+func (s *Store) setLevel(productID, warehouse string, l level) {
+	levels, ok := s.levels[productID]
+	if !ok {
+		levels = make(map[string]level)
+		s.levels[productID] = levels
+	}
+	levels[warehouse] = l
+}
+
+func (s *Store) CreateWarehouse(ctx context.Context, warehouse domain.Warehouse) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.warehouses[warehouse.ID]; ok {
+		return true, nil
+	}
+	s.warehouses[warehouse.ID] = warehouse
+	return false, nil
+}
+
+func (s *Store) GetWarehouse(ctx context.Context, id string) (domain.Warehouse, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	warehouse, ok := s.warehouses[id]
+	return warehouse, ok, nil
+}
+
+func (s *Store) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	s.mu.RLock()
+	warehouses := make([]domain.Warehouse, 0, len(s.warehouses))
+	for _, warehouse := range s.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(warehouses, func(i, j int) bool {
+		return warehouses[i].ID < warehouses[j].ID
+	})
+	return warehouses, nil
+}
+
+func (s *Store) StockLevels(ctx context.Context, productID string) ([]domain.StockLevel, bool, error) {
+	s.mu.RLock()
+	productID = s.resolve(productID)
+	if _, ok := s.products[productID]; !ok {
+		s.mu.RUnlock()
+		return nil, false, nil
+	}
+	levels := make([]domain.StockLevel, 0, len(s.levels[productID]))
+	for warehouse, l := range s.levels[productID] {
+		levels = append(levels, l.stockLevel(productID, warehouse))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Warehouse < levels[j].Warehouse
+	})
+	return levels, true, nil
+}
+
+func (s *Store) WarehouseStock(ctx context.Context, warehouseID string) ([]domain.StockLevel, bool, error) {
+	s.mu.RLock()
+	if _, ok := s.warehouses[warehouseID]; !ok {
+		s.mu.RUnlock()
+		return nil, false, nil
+	}
+	levels := make([]domain.StockLevel, 0)
+	for id, productLevels := range s.levels {
+		if l, ok := productLevels[warehouseID]; ok {
+			levels = append(levels, l.stockLevel(id, warehouseID))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].ProductID < levels[j].ProductID
+	})
+	return levels, true, nil
+}
+
+func (s *Store) TransferStock(ctx context.Context, transfer domain.StockTransfer) ([]domain.StockLevel, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.resolve(transfer.ID)
+	if _, ok := s.products[id]; !ok {
+		return nil, false, nil
+	}
+	if _, ok := s.warehouses[transfer.From]; !ok {
+		return nil, true, domain.ErrUnknownWarehouse
+	}
+	if _, ok := s.warehouses[transfer.To]; !ok {
+		return nil, true, domain.ErrUnknownWarehouse
+	}
+
+	// only available stock moves, what is reserved stays where the reservation was made
+	from, to := s.levels[id][transfer.From], s.levels[id][transfer.To]
+	if from.available() < transfer.Quantity {
+		return nil, true, domain.ErrInsufficientStock
+	}
+	from.quantity -= transfer.Quantity
+	to.quantity += transfer.Quantity
+
+	// the totals of the product do not change, so neither does its version
+	s.touch(id)
+	s.setLevel(id, transfer.From, from)
+	s.setLevel(id, transfer.To, to)
+	s.ledger.record(id, transfer.From, -transfer.Quantity, domain.MovementTransfer, transfer.Actor, transfer.Reference)
+	s.ledger.record(id, transfer.To, transfer.Quantity, domain.MovementTransfer, transfer.Actor, transfer.Reference)
+
+	return []domain.StockLevel{from.stockLevel(id, transfer.From), to.stockLevel(id, transfer.To)}, true, nil
+}
@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when submitting to a pool that has been closed
+var ErrClosed = errors.New("pool closed")
+
+// Stats is a point in time view of the pool
+type Stats struct {
+	Workers       int    `json:"workers"`
+	QueueCapacity int    `json:"queueCapacity"`
+	QueueDepth    int    `json:"queueDepth"`
+	InFlight      int64  `json:"inFlight"`
+	Completed     uint64 `json:"completed"`
+}
+
+// Pool runs tasks on a fixed number of workers.
+// Tasks wait in a bounded queue; once it is full Submit blocks,
+// which pushes back on whoever is producing work.
+type Pool struct {
+	tasks     chan func()
+	workers   int
+	inFlight  int64
+	completed uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewPool starts workers goroutines fed by a queue holding up to queueSize tasks
+func NewPool(workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Pool{
+		tasks:   make(chan func(), queueSize),
+		workers: workers,
+		done:    make(chan struct{}),
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Workers returns the global concurrency limit of the pool
+func (p *Pool) Workers() int {
+	return p.workers
+}
+
+// Submit queues task, blocking while the queue is full.
+// It gives up if ctx is cancelled or the pool is closed first.
+func (p *Pool) Submit(ctx context.Context, task func()) error {
+	select {
+	case <-p.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return ErrClosed
+	}
+}
+
+// Stats returns the current queue depth and in-flight work
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:       p.workers,
+		QueueCapacity: cap(p.tasks),
+		QueueDepth:    len(p.tasks),
+		InFlight:      atomic.LoadInt64(&p.inFlight),
+		Completed:     atomic.LoadUint64(&p.completed),
+	}
+}
+
+// Close stops the workers once the queued tasks have run
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case task := <-p.tasks:
+			p.run(task)
+		case <-p.done:
+			// drain whatever is left so no submitter waits forever on a result
+			for {
+				select {
+				case task := <-p.tasks:
+					p.run(task)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *Pool) run(task func()) {
+	atomic.AddInt64(&p.inFlight, 1)
+	defer func() {
+		atomic.AddInt64(&p.inFlight, -1)
+		atomic.AddUint64(&p.completed, 1)
+	}()
+	task()
+}
@@ -0,0 +1,100 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolRunsAtMostWorkersTasksAtOnce(t *testing.T) {
+	p := NewPool(2, 10)
+	defer p.Close()
+
+	var running, most int64
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		err := p.Submit(context.Background(), func() {
+			defer wg.Done()
+			n := atomic.AddInt64(&running, 1)
+			for {
+				m := atomic.LoadInt64(&most)
+				if n <= m || atomic.CompareAndSwapInt64(&most, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+		})
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if most > 2 {
+		t.Errorf("%d tasks ran at once, want at most 2", most)
+	}
+	if most < 1 {
+		t.Error("no task ran")
+	}
+}
+
+func TestPoolSubmitBlocksWhileTheQueueIsFull(t *testing.T) {
+	p := NewPool(1, 1)
+	defer p.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	if err := p.Submit(context.Background(), func() {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+	// the worker is busy, so this one fills the queue
+	if err := p.Submit(context.Background(), func() {}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if stats := p.Stats(); stats.InFlight != 1 || stats.QueueDepth != 1 {
+		t.Errorf("Stats = %+v, want 1 task in flight and 1 queued", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, func() {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit to a full queue = %v, want it to block until %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPoolCloseRunsQueuedTasksAndTurnsDownNewOnes(t *testing.T) {
+	p := NewPool(1, 5)
+
+	var ran int64
+	for i := 0; i < 5; i++ {
+		if err := p.Submit(context.Background(), func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&ran, 1)
+		}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	p.Close()
+
+	if ran != 5 {
+		t.Errorf("%d of the 5 queued tasks ran before Close returned, want all of them", ran)
+	}
+	if completed := p.Stats().Completed; completed != 5 {
+		t.Errorf("Stats.Completed = %d, want 5", completed)
+	}
+	if err := p.Submit(context.Background(), func() {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close = %v, want %v", err, ErrClosed)
+	}
+	// closing again is harmless
+	p.Close()
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestRepo(t *testing.T) (*RedisRepo, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisRepo(client), client
+}
+
+// pop takes the next message off the queue the way the store service consumes it
+func pop(t *testing.T, client *redis.Client) domain.QueueMessage {
+	t.Helper()
+
+	data, err := client.RPop(context.Background(), domain.ProductQueueKey).Bytes()
+	if err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+
+	var msg domain.QueueMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	return msg
+}
+
+func TestRedisRepoQueuesBatchesInOrder(t *testing.T) {
+	repo, client := newTestRepo(t)
+
+	products := []domain.Product{
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 3},
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Currency: "EUR"},
+	}
+	diffs := []domain.ProductDiff{{ID: "laptop", Diff: domain.ProductPatch{Price: domain.Value(450000)}}}
+	ids := []string{"laptop", "mouse"}
+
+	if err := repo.Add(products); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := repo.Update(diffs); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Delete(ids); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	want := []domain.QueueMessage{
+		{Operation: domain.QueueOperationCreate, Products: products},
+		{Operation: domain.QueueOperationUpdate, Diffs: diffs},
+		{Operation: domain.QueueOperationDelete, IDs: ids},
+	}
+	for i, expected := range want {
+		got := pop(t, client)
+		if got.Operation != expected.Operation {
+			t.Fatalf("message %d: operation %q, want %q", i, got.Operation, expected.Operation)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("message %d: got %+v, want %+v", i, got, expected)
+		}
+	}
+
+	if n := client.LLen(context.Background(), domain.ProductQueueKey).Val(); n != 0 {
+		t.Errorf("queue holds %d messages after draining, want 0", n)
+	}
+}
+
+func TestRedisRepoReportsRedisErrors(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	repo := NewRedisRepo(client)
+
+	server.Close()
+
+	if err := repo.Delete([]string{"laptop"}); err == nil {
+		t.Fatal("Delete succeeded with redis down, want an error")
+	}
+}
+
+func TestRedisRepoReadsJobResults(t *testing.T) {
+	repo, client := newTestRepo(t)
+	ctx := context.Background()
+
+	results, err := repo.JobResults(ctx, "job", time.Second)
+	if err != nil || results != nil {
+		t.Fatalf("JobResults with nothing reported = %v, %v, want no results", results, err)
+	}
+
+	want := []domain.BatchItemResult{
+		{Index: 100, ID: "laptop", Status: 201},
+		{Index: 101, ID: "mouse", Status: 409, Error: "already in database"},
+	}
+	data, _ := json.Marshal(want)
+	client.RPush(ctx, domain.JobResultsKey("job"), data)
+
+	results, err = repo.JobResults(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("JobResults: %v", err)
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("JobResults = %+v, want %+v", results, want)
+	}
+}
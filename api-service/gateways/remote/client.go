@@ -1,163 +1,272 @@
 package remote
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"exam-api/domain"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Implement the following client to connect to the remote storage server
 
+// storeError turns an error response of the store service into an error. The problem it answers with
+// is decoded back into the typed error it describes, any other body is reported as it is.
+func storeError(status int, body []byte) error {
+	var problem domain.Problem
+	if err := json.Unmarshal(body, &problem); err == nil && problem.Code != "" {
+		return problem.Err()
+	}
+	return fmt.Errorf("unexpected response from store, status=%d body=%s", status, strings.TrimSpace(string(body)))
+}
+
+// Client calls the store service at baseURL, e.g. http://localhost:8081.
+// It is safe for concurrent use, connections to the store are pooled by its http.Client.
+// Every call is cancelled with the ctx it is given, such as the context of the request it is made for.
 type Client struct {
-	client http.Client
+	client  *http.Client
+	baseURL string
 }
 
-func NewClient(client http.Client) *Client {
-	return &Client{client: client}
+func NewClient(client *http.Client, baseURL string) *Client {
+	return &Client{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
 }
 
-func (c *Client) Save(product domain.Product) (string, bool, error) {
-	url := "http://localhost:8081/store/product"
-	method := "POST"
+// NewHTTPClient returns an http.Client for the store service. Every call gives up after timeout,
+// and up to maxIdleConns keep-alive connections are kept open for idleConnTimeout between calls.
+func NewHTTPClient(timeout time.Duration, maxIdleConns int, idleConnTimeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	transport.IdleConnTimeout = idleConnTimeout
 
-	client := &http.Client{}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
 
-	marshalledProduct, err := json.Marshal(product)
+// newRequest prepares a request on the path of the store service, cancelled with ctx
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+}
 
+// send sends req to the store service. A store that can not be reached in time is unavailable.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	res, err := c.client.Do(req)
 	if err != nil {
-		return "", false, err
+		return nil, domain.WrapError(domain.ErrUnavailable, err)
 	}
-	req, err := http.NewRequest(method, url, strings.NewReader(string(marshalledProduct)))
+	return res, nil
+}
 
+// Save creates the product in the store service, which assigns its id and answers with it
+func (c *Client) Save(ctx context.Context, product domain.Product) (string, bool, error) {
+	status, body, err := c.postJSON(ctx, "/store/product", product)
 	if err != nil {
-		fmt.Println(err)
 		return "", false, err
 	}
+
+	switch {
+	case status == http.StatusOK:
+		var id string
+		if err := json.Unmarshal(body, &id); err != nil {
+			return "", false, err
+		}
+		return id, false, nil
+	}
+	err = storeError(status, body)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		// the store names the id it already holds the product under in the problem
+		var problem domain.Problem
+		_ = json.Unmarshal(body, &problem)
+		return problem.ID, true, nil
+	}
+	return "", false, err
+}
+
+// Get reads the product from the store service. A 404 is reported as not found.
+func (c *Client) Get(ctx context.Context, id string) (domain.Product, bool, error) {
+	getPath := "/store/product"
+
+	var product domain.Product
+	found, err := c.getJSON(ctx, getPath+"?"+url.Values{"id": {id}}.Encode(), &product)
+	if err != nil || !found {
+		return domain.Product{}, found, err
+	}
+	return product, true, nil
+}
+
+// Update patches the product in the store service. A 404 is reported as not found,
+// other errors are decoded from the problem the store answers with.
+func (c *Client) Update(ctx context.Context, id string, diff domain.ProductDiff) (bool, error) {
+	updatePath := "/store/product"
+	method := "PATCH"
+
+	// the store applies the patch itself, so absent members stay absent on the wire
+	diff.ID = id
+	marshalledDiff, err := json.Marshal(diff)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := c.newRequest(ctx, method, updatePath, strings.NewReader(string(marshalledDiff)))
+	if err != nil {
+		return false, err
+	}
 	req.Header.Add("Content-Type", "application/json")
 
-	res, err := client.Do(req)
+	return c.doWrite(req)
+}
+
+// Delete moves the product to the trash of the store service. A 404 is reported as not found.
+func (c *Client) Delete(ctx context.Context, id string) (bool, error) {
+	deletePath := "/store/product"
+	method := "DELETE"
+
+	req, err := c.newRequest(ctx, method, deletePath+"?"+url.Values{"id": {id}}.Encode(), nil)
 	if err != nil {
-		fmt.Println(err)
-		return "", false, err
+		return false, err
+	}
+
+	return c.doWrite(req)
+}
+
+// doWrite sends a write on a single product to the store service.
+// It returns false without an error if the store answers 404.
+func (c *Client) doWrite(req *http.Request) (bool, error) {
+	res, err := c.send(req)
+	if err != nil {
+		return false, err
 	}
 	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err)
-		return "", false, err
+		return false, err
 	}
-
-	fmt.Println(string(body))
-	return product.GetHash(), false, nil
+	return foundStatus(res.StatusCode, body)
 }
 
-func (c *Client) Get(id string) (domain.Product, bool, error) {
-	url := "http://localhost:8081/store/product/"
+func (c *Client) List(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	listPath := "/store/product"
 	method := "GET"
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url+"?id="+id, nil)
-
+	req, err := c.newRequest(ctx, method, listPath+"?"+query.Values().Encode(), nil)
 	if err != nil {
-		fmt.Println(err)
-		return domain.Product{}, false, err
+		return domain.ProductPage{}, err
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	res, _ := client.Do(req)
+	res, err := c.send(req)
 	if err != nil {
-		fmt.Println(err)
-		return domain.Product{}, false, err
+		return domain.ProductPage{}, err
 	}
 	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err)
-		return domain.Product{}, false, err
+		return domain.ProductPage{}, err
 	}
 
-	// unmarshal the body into a product
-	var product domain.Product
-	err = json.Unmarshal(body, &product)
-	if err != nil {
-		fmt.Println(err)
-		return domain.Product{}, false, err
+	if res.StatusCode != http.StatusOK {
+		return domain.ProductPage{}, storeError(res.StatusCode, body)
 	}
-	return product, true, nil
-}
-
-func (c *Client) Update(id string, diff domain.ProductDiff) (bool, error) {
-	url := "http://localhost:8081/store/product"
-	method := "PATCH"
 
-	client := &http.Client{}
-
-	// initialize new product
-	newProduct := domain.Product{
-		Name:         id,
-		Manufacturer: "",
-		Price:        diff.Diff.Price,
-		Stock:        diff.Diff.Stock,
-		Tags:         diff.Diff.Tags,
+	var page domain.ProductPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return domain.ProductPage{}, err
 	}
+	return page, nil
+}
 
-	marshalledProduct, err := json.Marshal(newProduct)
+func (c *Client) Search(ctx context.Context, text string, limit int) ([]domain.SearchResult, error) {
+	searchPath := "/store/product/search"
+	method := "GET"
 
-	req, err := http.NewRequest(method, url, strings.NewReader(string(marshalledProduct)))
+	query := url.Values{}
+	query.Set("q", text)
+	query.Set("limit", strconv.Itoa(limit))
 
+	req, err := c.newRequest(ctx, method, searchPath+"?"+query.Encode(), nil)
 	if err != nil {
-		fmt.Println(err)
-		return false, err
+		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
 
-	res, err := client.Do(req)
+	res, err := c.send(req)
 	if err != nil {
-		fmt.Println(err)
-		return false, err
+		return nil, err
 	}
 	defer res.Body.Close()
 
-	_, err = ioutil.ReadAll(res.Body)
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err)
-		return false, err
+		return nil, err
 	}
 
-	return true, nil
+	if res.StatusCode != http.StatusOK {
+		return nil, storeError(res.StatusCode, body)
+	}
+
+	var results []domain.SearchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-func (c *Client) Delete(id string) (bool, error) {
-	url := "http://localhost:8081/store/product/"
+func (c *Client) DeleteIfVersion(ctx context.Context, id string, version int64) (bool, error) {
+	deletePath := "/store/product"
 	method := "DELETE"
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url+"?id="+id, nil)
-
+	req, err := c.newRequest(ctx, method, deletePath+"?"+url.Values{"id": {id}}.Encode(), nil)
 	if err != nil {
-		fmt.Println(err)
 		return false, err
 	}
+	req.Header.Add("If-Match", strconv.Quote(strconv.FormatInt(version, 10)))
+
+	return c.doWrite(req)
+}
+
+func (c *Client) AdjustStock(ctx context.Context, adjustment domain.StockAdjustment) (domain.Product, bool, error) {
+	stockPath := "/store/product/stock"
+
+	marshalledAdjustment, err := json.Marshal(adjustment)
+	if err != nil {
+		return domain.Product{}, false, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, stockPath, strings.NewReader(string(marshalledAdjustment)))
+	if err != nil {
+		return domain.Product{}, false, err
+	}
 	req.Header.Add("Content-Type", "application/json")
 
-	res, err := client.Do(req)
+	res, err := c.send(req)
 	if err != nil {
-		fmt.Println(err)
-		return false, err
+		return domain.Product{}, false, err
 	}
 	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err)
-		return false, err
+		return domain.Product{}, false, err
 	}
-	fmt.Println(string(body))
 
-	return true, nil
+	switch res.StatusCode {
+	case http.StatusOK:
+		var product domain.Product
+		if err := json.Unmarshal(body, &product); err != nil {
+			return domain.Product{}, false, err
+		}
+		return product, true, nil
+	case http.StatusNotFound:
+		return domain.Product{}, false, nil
+	}
+	return domain.Product{}, false, storeError(res.StatusCode, body)
 }
@@ -0,0 +1,87 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// This lines checks if Client implements domain.BatchStorage
+// It will fail at build time if not
+var _ domain.BatchStorage = (*Client)(nil)
+
+const batchPath = "/store/product/batch"
+
+func (c *Client) SaveBatch(ctx context.Context, products []domain.Product, atomic bool) ([]domain.BatchItemResult, error) {
+	marshalledProducts, err := json.Marshal(products)
+	if err != nil {
+		return nil, err
+	}
+	return c.doBatch(ctx, http.MethodPost, batchPath+atomicQuery(atomic, "?"), bytes.NewReader(marshalledProducts))
+}
+
+func (c *Client) GetBatch(ctx context.Context, ids []string) ([]domain.BatchItemResult, error) {
+	return c.doBatch(ctx, http.MethodGet, batchPath+"?"+url.Values{"id": ids}.Encode(), nil)
+}
+
+func (c *Client) UpdateBatch(ctx context.Context, diffs []domain.ProductDiff, atomic bool) ([]domain.BatchItemResult, error) {
+	marshalledDiffs, err := json.Marshal(diffs)
+	if err != nil {
+		return nil, err
+	}
+	return c.doBatch(ctx, http.MethodPatch, batchPath+atomicQuery(atomic, "?"), bytes.NewReader(marshalledDiffs))
+}
+
+func (c *Client) DeleteBatch(ctx context.Context, ids []string, atomic bool) ([]domain.BatchItemResult, error) {
+	return c.doBatch(ctx, http.MethodDelete, batchPath+"?"+url.Values{"id": ids}.Encode()+atomicQuery(atomic, "&"), nil)
+}
+
+func (c *Client) AdjustStockBatch(ctx context.Context, adjustments []domain.StockAdjustment, atomic bool) ([]domain.BatchItemResult, error) {
+	marshalledAdjustments, err := json.Marshal(adjustments)
+	if err != nil {
+		return nil, err
+	}
+	return c.doBatch(ctx, http.MethodPost, batchPath+"/stock"+atomicQuery(atomic, "?"), bytes.NewReader(marshalledAdjustments))
+}
+
+// atomicQuery returns the query parameter asking the store for an all-or-nothing batch,
+// prefixed by sep, or nothing if atomic is false
+func atomicQuery(atomic bool, sep string) string {
+	if !atomic {
+		return ""
+	}
+	return sep + "atomic=true"
+}
+
+// doBatch calls a store service batch endpoint and decodes its per-item results.
+// The store answers with results for 200, 207 and uniform failures alike,
+// so any other body is treated as an error.
+func (c *Client) doBatch(ctx context.Context, method, path string, body io.Reader) ([]domain.BatchItemResult, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []domain.BatchItemResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, storeError(res.StatusCode, data)
+	}
+	return results, nil
+}
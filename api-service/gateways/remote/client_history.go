@@ -0,0 +1,25 @@
+package remote
+
+import (
+	"context"
+	"exam-api/domain"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (c *Client) History(ctx context.Context, productID string, limit int) ([]domain.ProductRevision, bool, error) {
+	query := url.Values{"id": {productID}, "limit": {strconv.Itoa(limit)}}
+
+	var revisions []domain.ProductRevision
+	found, err := c.getJSON(ctx, "/store/product/history?"+query.Encode(), &revisions)
+	return revisions, found, err
+}
+
+func (c *Client) GetAsOf(ctx context.Context, id string, at time.Time) (domain.Product, bool, error) {
+	query := url.Values{"id": {id}, "asOf": {at.Format(time.RFC3339Nano)}}
+
+	var product domain.Product
+	found, err := c.getJSON(ctx, "/store/product?"+query.Encode(), &product)
+	return product, found, err
+}
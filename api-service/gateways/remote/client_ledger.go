@@ -0,0 +1,58 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) StockMovements(ctx context.Context, productID string, reason domain.MovementReason, limit int) ([]domain.StockMovement, bool, error) {
+	query := url.Values{"id": {productID}, "limit": {strconv.Itoa(limit)}}
+	if reason != "" {
+		query.Set("reason", string(reason))
+	}
+
+	var movements []domain.StockMovement
+	found, err := c.getJSON(ctx, "/store/product/movements?"+query.Encode(), &movements)
+	return movements, found, err
+}
+
+func (c *Client) ReconcileStock(ctx context.Context, productID string) (domain.StockReconciliation, bool, error) {
+	query := url.Values{"id": {productID}}
+
+	var reconciliation domain.StockReconciliation
+	found, err := c.getJSON(ctx, "/store/product/stock/reconcile?"+query.Encode(), &reconciliation)
+	return reconciliation, found, err
+}
+
+// getJSON decodes the body of a GET on the store service into v.
+// It returns false without an error if the store answers 404.
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) (bool, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.send(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, json.Unmarshal(body, v)
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, storeError(res.StatusCode, body)
+}
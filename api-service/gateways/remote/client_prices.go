@@ -0,0 +1,80 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const pricesPath = "/store/prices"
+
+func (c *Client) SchedulePrice(ctx context.Context, request domain.PriceChangeRequest) (domain.PriceChange, bool, error) {
+	marshalledRequest, err := json.Marshal(request)
+	if err != nil {
+		return domain.PriceChange{}, false, err
+	}
+	return c.doPriceChange(ctx, http.MethodPost, pricesPath, bytes.NewReader(marshalledRequest))
+}
+
+func (c *Client) GetPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	return c.doPriceChange(ctx, http.MethodGet, pricesPath+"/"+url.PathEscape(id), nil)
+}
+
+func (c *Client) PriceSchedule(ctx context.Context, productID string) ([]domain.PriceChange, bool, error) {
+	query := url.Values{"id": {productID}}
+
+	var changes []domain.PriceChange
+	found, err := c.getJSON(ctx, "/store/product/prices?"+query.Encode(), &changes)
+	return changes, found, err
+}
+
+func (c *Client) CancelPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	return c.doPriceChange(ctx, http.MethodPost, pricesPath+"/"+url.PathEscape(id)+"/cancel", nil)
+}
+
+func (c *Client) PriceHistory(ctx context.Context, productID string, limit int) ([]domain.PricePoint, bool, error) {
+	query := url.Values{"id": {productID}, "limit": {strconv.Itoa(limit)}}
+
+	var points []domain.PricePoint
+	found, err := c.getJSON(ctx, "/store/product/prices/history?"+query.Encode(), &points)
+	return points, found, err
+}
+
+// doPriceChange calls a store service price change endpoint and decodes the change it answers with.
+// A 404 is reported as not found and a 409 as a change that is no longer pending.
+func (c *Client) doPriceChange(ctx context.Context, method, path string, body io.Reader) (domain.PriceChange, bool, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return domain.PriceChange{}, false, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := c.send(req)
+	if err != nil {
+		return domain.PriceChange{}, false, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return domain.PriceChange{}, false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var change domain.PriceChange
+		if err := json.Unmarshal(data, &change); err != nil {
+			return domain.PriceChange{}, false, err
+		}
+		return change, true, nil
+	case http.StatusNotFound:
+		return domain.PriceChange{}, false, nil
+	}
+	return domain.PriceChange{}, false, storeError(res.StatusCode, data)
+}
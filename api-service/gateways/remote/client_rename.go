@@ -0,0 +1,29 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"net/http"
+)
+
+// Rename asks the store service to rename the product. A 404 is reported as not found,
+// other errors are decoded from the problem the store answers with.
+func (c *Client) Rename(ctx context.Context, rename domain.ProductRename) (domain.RenameResult, bool, error) {
+	status, body, err := c.postJSON(ctx, "/store/product/rename", rename)
+	if err != nil {
+		return domain.RenameResult{}, false, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		var result domain.RenameResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return domain.RenameResult{}, false, err
+		}
+		return result, true, nil
+	case http.StatusNotFound:
+		return domain.RenameResult{}, false, nil
+	}
+	return domain.RenameResult{}, false, storeError(status, body)
+}
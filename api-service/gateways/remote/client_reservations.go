@@ -0,0 +1,67 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const reservationsPath = "/store/reservations"
+
+func (c *Client) Reserve(ctx context.Context, request domain.ReservationRequest) (domain.Reservation, bool, error) {
+	marshalledRequest, err := json.Marshal(request)
+	if err != nil {
+		return domain.Reservation{}, false, err
+	}
+	return c.doReservation(ctx, http.MethodPost, reservationsPath, bytes.NewReader(marshalledRequest))
+}
+
+func (c *Client) GetReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	return c.doReservation(ctx, http.MethodGet, reservationsPath+"/"+url.PathEscape(id), nil)
+}
+
+func (c *Client) ConfirmReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	return c.doReservation(ctx, http.MethodPost, reservationsPath+"/"+url.PathEscape(id)+"/confirm", nil)
+}
+
+func (c *Client) ReleaseReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	return c.doReservation(ctx, http.MethodPost, reservationsPath+"/"+url.PathEscape(id)+"/release", nil)
+}
+
+// doReservation calls a store service reservation endpoint and decodes the reservation it answers with.
+// A 404 is reported as not found, other errors are decoded from the problem the store answers with.
+func (c *Client) doReservation(ctx context.Context, method, path string, body io.Reader) (domain.Reservation, bool, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return domain.Reservation{}, false, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := c.send(req)
+	if err != nil {
+		return domain.Reservation{}, false, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return domain.Reservation{}, false, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusOK || res.StatusCode == http.StatusCreated:
+		var reservation domain.Reservation
+		if err := json.Unmarshal(data, &reservation); err != nil {
+			return domain.Reservation{}, false, err
+		}
+		return reservation, true, nil
+	case res.StatusCode == http.StatusNotFound:
+		return domain.Reservation{}, false, nil
+	}
+	return domain.Reservation{}, false, storeError(res.StatusCode, data)
+}
@@ -0,0 +1,318 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"exam-api/domain"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func mustSave(t *testing.T, c *Client, product domain.Product) string {
+	t.Helper()
+
+	id, exists, err := c.Save(context.Background(), product)
+	if err != nil || exists {
+		t.Fatalf("Save(%s) = %v, %v", product.Name, exists, err)
+	}
+	return id
+}
+
+func TestClientSave(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	laptop := domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5}
+
+	id := mustSave(t, c, laptop)
+	if again, exists, err := c.Save(ctx, laptop); err != nil || !exists || again != id {
+		t.Errorf("Save of a stored product = %q, %v, %v, want %q already stored", again, exists, err, id)
+	}
+	if _, _, err := c.Save(ctx, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: -1}); !errors.Is(err, domain.ErrValidation) || domain.ErrorCode(err) != "negative_price" {
+		t.Errorf("Save of a negative price = %v, want a negative_price validation error", err)
+	}
+}
+
+func TestClientGet(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	id := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5, Tags: []string{"computers"}})
+
+	product, found, err := c.Get(ctx, id)
+	if err != nil || !found {
+		t.Fatalf("Get = %v, %v", found, err)
+	}
+	if product.Name != "Laptop" || product.Price != 499999 || product.Stock != 5 || product.Version != 1 || product.Currency != "RON" {
+		t.Errorf("Get = %+v, want the saved product at version 1", product)
+	}
+
+	if _, found, err := c.Get(ctx, "missing"); err != nil || found {
+		t.Errorf("Get of a missing product = %v, %v, want not found", found, err)
+	}
+}
+
+func TestClientUpdate(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	id := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+
+	stale := int64(7)
+	tests := []struct {
+		name  string
+		id    string
+		diff  domain.ProductDiff
+		found bool
+		err   error
+	}{
+		{name: "applied", id: id, diff: domain.ProductDiff{Diff: domain.ProductPatch{Price: domain.Value(450000)}}, found: true},
+		{name: "missing", id: "missing", diff: domain.ProductDiff{Diff: domain.ProductPatch{Price: domain.Value(1)}}},
+		{name: "stale", id: id, diff: domain.ProductDiff{Version: &stale, Diff: domain.ProductPatch{Price: domain.Value(1)}}, found: true, err: domain.ErrVersionMismatch},
+		{name: "negative", id: id, diff: domain.ProductDiff{Diff: domain.ProductPatch{Stock: domain.Value(-1)}}, err: domain.ErrValidation},
+	}
+	for _, tt := range tests {
+		found, err := c.Update(ctx, tt.id, tt.diff)
+		if !errors.Is(err, tt.err) || (err == nil && found != tt.found) {
+			t.Errorf("%s: Update = %v, %v, want %v, %v", tt.name, found, err, tt.found, tt.err)
+		}
+	}
+
+	if product, _, _ := c.Get(ctx, id); product.Price != 450000 || product.Version != 2 {
+		t.Errorf("updated product %+v, want price 450000 at version 2", product)
+	}
+}
+
+func TestClientDelete(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	id := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+
+	if _, err := c.DeleteIfVersion(ctx, id, 4); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("DeleteIfVersion at a stale version = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if found, err := c.DeleteIfVersion(ctx, id, 1); err != nil || !found {
+		t.Errorf("DeleteIfVersion = %v, %v, want the product deleted", found, err)
+	}
+	if found, err := c.Delete(ctx, id); err != nil || found {
+		t.Errorf("Delete of a deleted product = %v, %v, want not found", found, err)
+	}
+
+	other := mustSave(t, c, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 5950})
+	if found, err := c.Delete(ctx, other); err != nil || !found {
+		t.Errorf("Delete = %v, %v, want the product deleted", found, err)
+	}
+}
+
+func TestClientListAndSearch(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	laptop := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+	mustSave(t, c, domain.Product{Name: "Mouse", Manufacturer: "Globex", Price: 5950})
+
+	page, err := c.List(ctx, domain.ProductQuery{Manufacturer: "Acme", Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != laptop {
+		t.Errorf("List = %+v, want only the laptop", page)
+	}
+	if _, err := c.List(ctx, domain.ProductQuery{SortBy: "colour"}); !errors.Is(err, domain.ErrValidation) {
+		t.Errorf("List sorted by an unknown field = %v, want a validation error", err)
+	}
+
+	results, err := c.Search(ctx, "lapt", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != laptop {
+		t.Errorf("Search = %+v, want the laptop", results)
+	}
+}
+
+func TestClientAdjustStock(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	id := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+
+	product, found, err := c.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -2})
+	if err != nil || !found || product.Stock != 3 {
+		t.Errorf("AdjustStock = %+v, %v, %v, want stock 3", product, found, err)
+	}
+	if _, _, err := c.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -4}); !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Errorf("AdjustStock below zero = %v, want %v", err, domain.ErrInsufficientStock)
+	}
+	if _, _, err := c.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: 1, Warehouse: "north"}); !errors.Is(err, domain.ErrUnknownWarehouse) {
+		t.Errorf("AdjustStock in an unknown warehouse = %v, want %v", err, domain.ErrUnknownWarehouse)
+	}
+	if _, found, err := c.AdjustStock(ctx, domain.StockAdjustment{ID: "missing", Delta: 1}); err != nil || found {
+		t.Errorf("AdjustStock of a missing product = %v, %v, want not found", found, err)
+	}
+}
+
+// statuses returns the status of every item of a batch in order
+func statuses(results []domain.BatchItemResult) []int {
+	s := make([]int, len(results))
+	for i, result := range results {
+		s[i] = result.Status
+	}
+	return s
+}
+
+func equalStatuses(got, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClientBatches(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	laptop := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+
+	saved, err := c.SaveBatch(ctx, []domain.Product{
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Stock: 1},
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999},
+	}, false)
+	if err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	if want := []int{http.StatusCreated, http.StatusConflict}; !equalStatuses(statuses(saved), want) || saved[1].ID != laptop {
+		t.Errorf("SaveBatch = %+v, want statuses %v", saved, want)
+	}
+	mouse := saved[0].ID
+
+	got, err := c.GetBatch(ctx, []string{laptop, "missing"})
+	if err != nil {
+		t.Fatalf("GetBatch: %v", err)
+	}
+	if want := []int{http.StatusOK, http.StatusNotFound}; !equalStatuses(statuses(got), want) || got[0].Product == nil || got[0].Product.Name != "Laptop" {
+		t.Errorf("GetBatch = %+v, want statuses %v", got, want)
+	}
+
+	stale := int64(9)
+	updated, err := c.UpdateBatch(ctx, []domain.ProductDiff{
+		{ID: laptop, Diff: domain.ProductPatch{Price: domain.Value(450000)}},
+		{ID: mouse, Version: &stale, Diff: domain.ProductPatch{Price: domain.Value(4950)}},
+		{ID: "missing", Diff: domain.ProductPatch{Price: domain.Value(1)}},
+	}, false)
+	if err != nil {
+		t.Fatalf("UpdateBatch: %v", err)
+	}
+	if want := []int{http.StatusOK, http.StatusPreconditionFailed, http.StatusNotFound}; !equalStatuses(statuses(updated), want) {
+		t.Errorf("UpdateBatch = %+v, want statuses %v", updated, want)
+	}
+
+	adjusted, err := c.AdjustStockBatch(ctx, []domain.StockAdjustment{
+		{ID: laptop, Delta: -1},
+		{ID: mouse, Delta: -5},
+	}, false)
+	if err != nil {
+		t.Fatalf("AdjustStockBatch: %v", err)
+	}
+	if want := []int{http.StatusOK, http.StatusConflict}; !equalStatuses(statuses(adjusted), want) {
+		t.Errorf("AdjustStockBatch = %+v, want statuses %v", adjusted, want)
+	}
+
+	deleted, err := c.DeleteBatch(ctx, []string{mouse, "missing"}, false)
+	if err != nil {
+		t.Fatalf("DeleteBatch: %v", err)
+	}
+	if want := []int{http.StatusOK, http.StatusNotFound}; !equalStatuses(statuses(deleted), want) {
+		t.Errorf("DeleteBatch = %+v, want statuses %v", deleted, want)
+	}
+}
+
+func TestClientAtomicBatchKeepsNothingOfAFailure(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	laptop := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+
+	results, err := c.UpdateBatch(ctx, []domain.ProductDiff{
+		{ID: laptop, Diff: domain.ProductPatch{Price: domain.Value(450000)}},
+		{ID: "missing", Diff: domain.ProductPatch{Price: domain.Value(1)}},
+	}, true)
+	if err != nil {
+		t.Fatalf("UpdateBatch: %v", err)
+	}
+	if results[1].Status != http.StatusNotFound {
+		t.Errorf("UpdateBatch = %+v, want the missing product reported", results)
+	}
+	if product, _, _ := c.Get(ctx, laptop); product.Price != 499999 {
+		t.Errorf("laptop priced %d, want the atomic batch rolled back", product.Price)
+	}
+}
+
+func TestClientTrash(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	laptop := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999})
+	mouse := mustSave(t, c, domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 5950})
+	for _, id := range []string{laptop, mouse} {
+		if _, err := c.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+
+	trash, err := c.Trash(ctx)
+	if err != nil || len(trash) != 2 {
+		t.Fatalf("Trash = %+v, %v, want both products", trash, err)
+	}
+
+	if found, err := c.Restore(ctx, laptop); err != nil || !found {
+		t.Errorf("Restore = %v, %v, want the laptop restored", found, err)
+	}
+	if found, err := c.Restore(ctx, laptop); err != nil || found {
+		t.Errorf("Restore of a product out of the trash = %v, %v, want not found", found, err)
+	}
+	if _, found, _ := c.Get(ctx, laptop); !found {
+		t.Error("restored laptop is not found")
+	}
+
+	if found, err := c.Purge(ctx, mouse); err != nil || !found {
+		t.Errorf("Purge = %v, %v, want the mouse purged", found, err)
+	}
+	if found, err := c.Purge(ctx, mouse); err != nil || found {
+		t.Errorf("Purge of a purged product = %v, %v, want not found", found, err)
+	}
+
+	if _, err := c.Delete(ctx, laptop); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if purged, err := c.PurgeTrash(ctx, time.Now().Add(time.Minute)); err != nil || purged != 1 {
+		t.Errorf("PurgeTrash = %d, %v, want the laptop purged", purged, err)
+	}
+}
+
+func TestClientLedger(t *testing.T) {
+	c := newStoreServer(t)
+	ctx := context.Background()
+	id := mustSave(t, c, domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 5})
+	if _, _, err := c.AdjustStock(ctx, domain.StockAdjustment{ID: id, Delta: -2, Reason: domain.MovementSale}); err != nil {
+		t.Fatalf("AdjustStock: %v", err)
+	}
+
+	movements, found, err := c.StockMovements(ctx, id, "", 10)
+	if err != nil || !found || len(movements) != 2 || movements[0].Delta != -2 {
+		t.Errorf("StockMovements = %+v, %v, %v, want the sale and the initial stock", movements, found, err)
+	}
+	sales, _, err := c.StockMovements(ctx, id, domain.MovementSale, 10)
+	if err != nil || len(sales) != 1 {
+		t.Errorf("StockMovements of sales = %+v, %v, want the sale", sales, err)
+	}
+	if _, found, err := c.StockMovements(ctx, "missing", "", 10); err != nil || found {
+		t.Errorf("StockMovements of a missing product = %v, %v, want not found", found, err)
+	}
+
+	reconciliation, found, err := c.ReconcileStock(ctx, id)
+	if err != nil || !found || reconciliation.Stock != 3 || reconciliation.LedgerStock != 3 {
+		t.Errorf("ReconcileStock = %+v, %v, %v, want stock and ledger at 3", reconciliation, found, err)
+	}
+	if _, found, err := c.ReconcileStock(ctx, "missing"); err != nil || found {
+		t.Errorf("ReconcileStock of a missing product = %v, %v, want not found", found, err)
+	}
+}
@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const trashPath = "/store/product/trash"
+
+func (c *Client) Trash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	var products []domain.TrashedProduct
+	found, err := c.getJSON(ctx, trashPath, &products)
+	if err == nil && !found {
+		err = fmt.Errorf("unexpected response from store, status=%d", http.StatusNotFound)
+	}
+	return products, err
+}
+
+func (c *Client) Restore(ctx context.Context, id string) (bool, error) {
+	query := url.Values{"id": {id}}
+	status, body, err := c.do(ctx, http.MethodPost, trashPath+"/restore?"+query.Encode())
+	if err != nil {
+		return false, err
+	}
+	return foundStatus(status, body)
+}
+
+func (c *Client) Purge(ctx context.Context, id string) (bool, error) {
+	query := url.Values{"id": {id}}
+	status, body, err := c.do(ctx, http.MethodDelete, trashPath+"?"+query.Encode())
+	if err != nil {
+		return false, err
+	}
+	return foundStatus(status, body)
+}
+
+func (c *Client) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	query := url.Values{"before": {before.Format(time.RFC3339Nano)}}
+	status, body, err := c.do(ctx, http.MethodDelete, trashPath+"?"+query.Encode())
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, storeError(status, body)
+	}
+
+	var result domain.PurgeResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+	return result.Purged, nil
+}
+
+// do sends a request without a body to the store service and returns the status and body it answers with
+func (c *Client) do(ctx context.Context, method, path string) (int, []byte, error) {
+	req, err := c.newRequest(ctx, method, path, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := c.send(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
+
+// foundStatus maps 200 to true and 404 to false, any other status is an error
+func foundStatus(status int, body []byte) (bool, error) {
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, storeError(status, body)
+}
@@ -0,0 +1,103 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"exam-api/domain"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const warehousesPath = "/store/warehouses"
+
+func (c *Client) CreateWarehouse(ctx context.Context, warehouse domain.Warehouse) (bool, error) {
+	status, body, err := c.postJSON(ctx, warehousesPath, warehouse)
+	if err != nil {
+		return false, err
+	}
+
+	switch status {
+	case http.StatusOK, http.StatusCreated:
+		return false, nil
+	case http.StatusConflict:
+		return true, nil
+	}
+	return false, storeError(status, body)
+}
+
+func (c *Client) GetWarehouse(ctx context.Context, id string) (domain.Warehouse, bool, error) {
+	var warehouse domain.Warehouse
+	found, err := c.getJSON(ctx, warehousesPath+"/"+url.PathEscape(id), &warehouse)
+	return warehouse, found, err
+}
+
+func (c *Client) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	var warehouses []domain.Warehouse
+	found, err := c.getJSON(ctx, warehousesPath, &warehouses)
+	if err == nil && !found {
+		err = fmt.Errorf("unexpected response from store, status=%d", http.StatusNotFound)
+	}
+	return warehouses, err
+}
+
+func (c *Client) StockLevels(ctx context.Context, productID string) ([]domain.StockLevel, bool, error) {
+	query := url.Values{"id": {productID}}
+
+	var levels []domain.StockLevel
+	found, err := c.getJSON(ctx, "/store/product/warehouses?"+query.Encode(), &levels)
+	return levels, found, err
+}
+
+func (c *Client) WarehouseStock(ctx context.Context, warehouseID string) ([]domain.StockLevel, bool, error) {
+	var levels []domain.StockLevel
+	found, err := c.getJSON(ctx, warehousesPath+"/"+url.PathEscape(warehouseID)+"/stock", &levels)
+	return levels, found, err
+}
+
+func (c *Client) TransferStock(ctx context.Context, transfer domain.StockTransfer) ([]domain.StockLevel, bool, error) {
+	status, body, err := c.postJSON(ctx, "/store/product/transfer", transfer)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		var levels []domain.StockLevel
+		if err := json.Unmarshal(body, &levels); err != nil {
+			return nil, false, err
+		}
+		return levels, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	}
+	return nil, false, storeError(status, body)
+}
+
+// postJSON posts v to the store service and returns the status and body it answers with
+func (c *Client) postJSON(ctx context.Context, path string, v interface{}) (int, []byte, error) {
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, bytes.NewReader(marshalled))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := c.send(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
@@ -0,0 +1,309 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"exam-api/domain"
+	"exam-api/gateways/memory"
+	storeapi "exam-store/api"
+	storedomain "exam-store/domain"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// newStoreServer starts the handlers of the store service backed by the in-memory store
+// and returns a client calling them
+func newStoreServer(t *testing.T) *Client {
+	t.Helper()
+
+	ids, err := domain.NewIDGenerator(domain.DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	storeIDs, err := storedomain.NewIDGenerator(storedomain.DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+
+	ws := new(restful.WebService)
+	storeapi.NewAPI(memoryStorage{memory.NewStore(ids)}, storedomain.ExchangeRates{}, storeIDs, nil, 0).RegisterRoutes(ws)
+	container := restful.NewContainer()
+	container.Add(ws)
+	container.ServiceErrorHandler(storeapi.WriteServiceError)
+
+	server := httptest.NewServer(container)
+	t.Cleanup(server.Close)
+	return NewClient(server.Client(), server.URL)
+}
+
+// memoryStorage serves the storage of the store service from the in-memory store of the api service.
+// The domains of both services share their json, so values cross over by being encoded and decoded,
+// and errors by their code. The batch calls apply their items one by one.
+type memoryStorage struct {
+	storage domain.Storage
+}
+
+// convert moves v to the domain of the other service
+func convert[T any](v interface{}) T {
+	var converted T
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, &converted)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return converted
+}
+
+// storeKindError gives err the known error of the store service with the same code
+func storeKindError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return storedomain.Problem{Code: domain.ErrorCode(err), Detail: err.Error()}.Err()
+}
+
+func (s memoryStorage) Save(product storedomain.Product) (string, bool, error) {
+	id, exists, err := s.storage.Save(context.Background(), convert[domain.Product](product))
+	return id, exists, storeKindError(err)
+}
+
+func (s memoryStorage) Get(id string) (storedomain.Product, bool, error) {
+	product, found, err := s.storage.Get(context.Background(), id)
+	return convert[storedomain.Product](product), found, storeKindError(err)
+}
+
+func (s memoryStorage) Update(id string, diff storedomain.ProductDiff) (bool, error) {
+	found, err := s.storage.Update(context.Background(), id, convert[domain.ProductDiff](diff))
+	return found, storeKindError(err)
+}
+
+func (s memoryStorage) Delete(id string) (bool, error) {
+	found, err := s.storage.Delete(context.Background(), id)
+	return found, storeKindError(err)
+}
+
+func (s memoryStorage) DeleteIfVersion(id string, version int64) (bool, error) {
+	found, err := s.storage.DeleteIfVersion(context.Background(), id, version)
+	return found, storeKindError(err)
+}
+
+func (s memoryStorage) Rename(rename storedomain.ProductRename) (storedomain.RenameResult, bool, error) {
+	result, found, err := s.storage.Rename(context.Background(), convert[domain.ProductRename](rename))
+	return convert[storedomain.RenameResult](result), found, storeKindError(err)
+}
+
+func (s memoryStorage) List(query storedomain.ProductQuery) (storedomain.ProductPage, error) {
+	page, err := s.storage.List(context.Background(), convert[domain.ProductQuery](query))
+	return convert[storedomain.ProductPage](page), storeKindError(err)
+}
+
+func (s memoryStorage) Search(text string, limit int) ([]storedomain.SearchResult, error) {
+	results, err := s.storage.Search(context.Background(), text, limit)
+	return convert[[]storedomain.SearchResult](results), storeKindError(err)
+}
+
+func (s memoryStorage) AdjustStock(adjustment storedomain.StockAdjustment) (storedomain.Product, bool, error) {
+	product, found, err := s.storage.AdjustStock(context.Background(), convert[domain.StockAdjustment](adjustment))
+	return convert[storedomain.Product](product), found, storeKindError(err)
+}
+
+func (s memoryStorage) StockMovements(productID string, reason storedomain.MovementReason, limit int) ([]storedomain.StockMovement, bool, error) {
+	movements, found, err := s.storage.StockMovements(context.Background(), productID, domain.MovementReason(reason), limit)
+	return convert[[]storedomain.StockMovement](movements), found, storeKindError(err)
+}
+
+func (s memoryStorage) ReconcileStock(productID string) (storedomain.StockReconciliation, bool, error) {
+	reconciliation, found, err := s.storage.ReconcileStock(context.Background(), productID)
+	return convert[storedomain.StockReconciliation](reconciliation), found, storeKindError(err)
+}
+
+func (s memoryStorage) History(productID string, limit int) ([]storedomain.ProductRevision, bool, error) {
+	revisions, found, err := s.storage.History(context.Background(), productID, limit)
+	return convert[[]storedomain.ProductRevision](revisions), found, storeKindError(err)
+}
+
+func (s memoryStorage) GetAsOf(id string, at time.Time) (storedomain.Product, bool, error) {
+	product, found, err := s.storage.GetAsOf(context.Background(), id, at)
+	return convert[storedomain.Product](product), found, storeKindError(err)
+}
+
+func (s memoryStorage) Trash() ([]storedomain.TrashedProduct, error) {
+	products, err := s.storage.Trash(context.Background())
+	return convert[[]storedomain.TrashedProduct](products), storeKindError(err)
+}
+
+func (s memoryStorage) Restore(id string) (bool, error) {
+	found, err := s.storage.Restore(context.Background(), id)
+	return found, storeKindError(err)
+}
+
+func (s memoryStorage) Purge(id string) (bool, error) {
+	found, err := s.storage.Purge(context.Background(), id)
+	return found, storeKindError(err)
+}
+
+func (s memoryStorage) PurgeTrash(before time.Time) (int, error) {
+	purged, err := s.storage.PurgeTrash(context.Background(), before)
+	return purged, storeKindError(err)
+}
+
+func (s memoryStorage) CreateWarehouse(warehouse storedomain.Warehouse) (bool, error) {
+	exists, err := s.storage.CreateWarehouse(context.Background(), convert[domain.Warehouse](warehouse))
+	return exists, storeKindError(err)
+}
+
+func (s memoryStorage) GetWarehouse(id string) (storedomain.Warehouse, bool, error) {
+	warehouse, found, err := s.storage.GetWarehouse(context.Background(), id)
+	return convert[storedomain.Warehouse](warehouse), found, storeKindError(err)
+}
+
+func (s memoryStorage) ListWarehouses() ([]storedomain.Warehouse, error) {
+	warehouses, err := s.storage.ListWarehouses(context.Background())
+	return convert[[]storedomain.Warehouse](warehouses), storeKindError(err)
+}
+
+func (s memoryStorage) StockLevels(productID string) ([]storedomain.StockLevel, bool, error) {
+	levels, found, err := s.storage.StockLevels(context.Background(), productID)
+	return convert[[]storedomain.StockLevel](levels), found, storeKindError(err)
+}
+
+func (s memoryStorage) WarehouseStock(warehouseID string) ([]storedomain.StockLevel, bool, error) {
+	levels, found, err := s.storage.WarehouseStock(context.Background(), warehouseID)
+	return convert[[]storedomain.StockLevel](levels), found, storeKindError(err)
+}
+
+func (s memoryStorage) TransferStock(transfer storedomain.StockTransfer) ([]storedomain.StockLevel, bool, error) {
+	levels, found, err := s.storage.TransferStock(context.Background(), convert[domain.StockTransfer](transfer))
+	return convert[[]storedomain.StockLevel](levels), found, storeKindError(err)
+}
+
+func (s memoryStorage) Reserve(request storedomain.ReservationRequest) (storedomain.Reservation, bool, error) {
+	reservation, found, err := s.storage.Reserve(context.Background(), convert[domain.ReservationRequest](request))
+	return convert[storedomain.Reservation](reservation), found, storeKindError(err)
+}
+
+func (s memoryStorage) GetReservation(id string) (storedomain.Reservation, bool, error) {
+	reservation, found, err := s.storage.GetReservation(context.Background(), id)
+	return convert[storedomain.Reservation](reservation), found, storeKindError(err)
+}
+
+func (s memoryStorage) ConfirmReservation(id string) (storedomain.Reservation, bool, error) {
+	reservation, found, err := s.storage.ConfirmReservation(context.Background(), id)
+	return convert[storedomain.Reservation](reservation), found, storeKindError(err)
+}
+
+func (s memoryStorage) ReleaseReservation(id string) (storedomain.Reservation, bool, error) {
+	reservation, found, err := s.storage.ReleaseReservation(context.Background(), id)
+	return convert[storedomain.Reservation](reservation), found, storeKindError(err)
+}
+
+func (s memoryStorage) SchedulePrice(request storedomain.PriceChangeRequest) (storedomain.PriceChange, bool, error) {
+	change, found, err := s.storage.SchedulePrice(context.Background(), convert[domain.PriceChangeRequest](request))
+	return convert[storedomain.PriceChange](change), found, storeKindError(err)
+}
+
+func (s memoryStorage) GetPriceChange(id string) (storedomain.PriceChange, bool, error) {
+	change, found, err := s.storage.GetPriceChange(context.Background(), id)
+	return convert[storedomain.PriceChange](change), found, storeKindError(err)
+}
+
+func (s memoryStorage) PriceSchedule(productID string) ([]storedomain.PriceChange, bool, error) {
+	changes, found, err := s.storage.PriceSchedule(context.Background(), productID)
+	return convert[[]storedomain.PriceChange](changes), found, storeKindError(err)
+}
+
+func (s memoryStorage) CancelPriceChange(id string) (storedomain.PriceChange, bool, error) {
+	change, found, err := s.storage.CancelPriceChange(context.Background(), id)
+	return convert[storedomain.PriceChange](change), found, storeKindError(err)
+}
+
+func (s memoryStorage) PriceHistory(productID string, limit int) ([]storedomain.PricePoint, bool, error) {
+	points, found, err := s.storage.PriceHistory(context.Background(), productID, limit)
+	return convert[[]storedomain.PricePoint](points), found, storeKindError(err)
+}
+
+// SaveBatch reports an error saving a product with the product, like a product the sql repository cannot give an id
+func (s memoryStorage) SaveBatch(products []storedomain.Product) ([]storedomain.SavedProduct, error) {
+	saved := make([]storedomain.SavedProduct, len(products))
+	for i, product := range products {
+		id, exists, err := s.Save(product)
+		saved[i] = storedomain.SavedProduct{ID: id, Created: !exists && err == nil, Err: err}
+	}
+	return saved, nil
+}
+
+func (s memoryStorage) GetBatch(ids []string) (map[string]storedomain.Product, error) {
+	products := make(map[string]storedomain.Product, len(ids))
+	for _, id := range ids {
+		product, found, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			products[id] = product
+		}
+	}
+	return products, nil
+}
+
+// UpdateBatch leaves out the diffs the sql statement would not apply, at another version or taking away reserved stock
+func (s memoryStorage) UpdateBatch(diffs []storedomain.ProductDiff) ([]string, error) {
+	var updated []string
+	for _, diff := range diffs {
+		found, err := s.Update(diff.ID, diff)
+		if errors.Is(err, storedomain.ErrVersionMismatch) || errors.Is(err, storedomain.ErrInsufficientStock) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			updated = append(updated, diff.ID)
+		}
+	}
+	return updated, nil
+}
+
+func (s memoryStorage) DeleteBatch(ids []string) ([]string, error) {
+	var deleted []string
+	for _, id := range ids {
+		found, err := s.Delete(id)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+// AdjustStockBatch leaves out the adjustments the sql statement would not apply, taking away more than is available
+func (s memoryStorage) AdjustStockBatch(adjustments []storedomain.StockAdjustment) (map[string]storedomain.Product, error) {
+	products := make(map[string]storedomain.Product, len(adjustments))
+	for _, adjustment := range adjustments {
+		product, found, err := s.AdjustStock(adjustment)
+		if errors.Is(err, storedomain.ErrInsufficientStock) || errors.Is(err, storedomain.ErrUnknownWarehouse) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			products[adjustment.ID] = product
+		}
+	}
+	return products, nil
+}
+
+func (s memoryStorage) Atomically(fn func(tx storedomain.Storage) error) error {
+	return s.storage.(domain.Transactional).Atomically(func(tx domain.Storage) error {
+		return fn(memoryStorage{tx})
+	})
+}
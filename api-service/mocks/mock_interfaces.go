@@ -5,8 +5,10 @@
 package mocks
 
 import (
+	context "context"
 	domain "exam-api/domain"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -47,3 +49,800 @@ func (mr *MockQueueMockRecorder) Add(batch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockQueue)(nil).Add), batch)
 }
+
+// Delete mocks base method.
+func (m *MockQueue) Delete(ids []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockQueueMockRecorder) Delete(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockQueue)(nil).Delete), ids)
+}
+
+// JobResults mocks base method.
+func (m *MockQueue) JobResults(ctx context.Context, jobID string, timeout time.Duration) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JobResults", ctx, jobID, timeout)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// JobResults indicates an expected call of JobResults.
+func (mr *MockQueueMockRecorder) JobResults(ctx, jobID, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JobResults", reflect.TypeOf((*MockQueue)(nil).JobResults), ctx, jobID, timeout)
+}
+
+// Push mocks base method.
+func (m *MockQueue) Push(msg domain.QueueMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Push", msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Push indicates an expected call of Push.
+func (mr *MockQueueMockRecorder) Push(msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockQueue)(nil).Push), msg)
+}
+
+// Update mocks base method.
+func (m *MockQueue) Update(batch []domain.ProductDiff) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", batch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockQueueMockRecorder) Update(batch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockQueue)(nil).Update), batch)
+}
+
+// MockStorage is a mock of Storage interface.
+type MockStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorageMockRecorder
+}
+
+// MockStorageMockRecorder is the mock recorder for MockStorage.
+type MockStorageMockRecorder struct {
+	mock *MockStorage
+}
+
+// NewMockStorage creates a new mock instance.
+func NewMockStorage(ctrl *gomock.Controller) *MockStorage {
+	mock := &MockStorage{ctrl: ctrl}
+	mock.recorder = &MockStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
+	return m.recorder
+}
+
+// AdjustStock mocks base method.
+func (m *MockStorage) AdjustStock(ctx context.Context, adjustment domain.StockAdjustment) (domain.Product, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustStock", ctx, adjustment)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AdjustStock indicates an expected call of AdjustStock.
+func (mr *MockStorageMockRecorder) AdjustStock(ctx, adjustment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustStock", reflect.TypeOf((*MockStorage)(nil).AdjustStock), ctx, adjustment)
+}
+
+// CancelPriceChange mocks base method.
+func (m *MockStorage) CancelPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPriceChange", ctx, id)
+	ret0, _ := ret[0].(domain.PriceChange)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CancelPriceChange indicates an expected call of CancelPriceChange.
+func (mr *MockStorageMockRecorder) CancelPriceChange(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPriceChange", reflect.TypeOf((*MockStorage)(nil).CancelPriceChange), ctx, id)
+}
+
+// ConfirmReservation mocks base method.
+func (m *MockStorage) ConfirmReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmReservation", ctx, id)
+	ret0, _ := ret[0].(domain.Reservation)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ConfirmReservation indicates an expected call of ConfirmReservation.
+func (mr *MockStorageMockRecorder) ConfirmReservation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmReservation", reflect.TypeOf((*MockStorage)(nil).ConfirmReservation), ctx, id)
+}
+
+// CreateWarehouse mocks base method.
+func (m *MockStorage) CreateWarehouse(ctx context.Context, warehouse domain.Warehouse) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWarehouse", ctx, warehouse)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWarehouse indicates an expected call of CreateWarehouse.
+func (mr *MockStorageMockRecorder) CreateWarehouse(ctx, warehouse interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWarehouse", reflect.TypeOf((*MockStorage)(nil).CreateWarehouse), ctx, warehouse)
+}
+
+// Delete mocks base method.
+func (m *MockStorage) Delete(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorageMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorage)(nil).Delete), ctx, id)
+}
+
+// DeleteIfVersion mocks base method.
+func (m *MockStorage) DeleteIfVersion(ctx context.Context, id string, version int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIfVersion", ctx, id, version)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIfVersion indicates an expected call of DeleteIfVersion.
+func (mr *MockStorageMockRecorder) DeleteIfVersion(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIfVersion", reflect.TypeOf((*MockStorage)(nil).DeleteIfVersion), ctx, id, version)
+}
+
+// Get mocks base method.
+func (m *MockStorage) Get(ctx context.Context, id string) (domain.Product, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockStorageMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), ctx, id)
+}
+
+// GetAsOf mocks base method.
+func (m *MockStorage) GetAsOf(ctx context.Context, id string, at time.Time) (domain.Product, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAsOf", ctx, id, at)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAsOf indicates an expected call of GetAsOf.
+func (mr *MockStorageMockRecorder) GetAsOf(ctx, id, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAsOf", reflect.TypeOf((*MockStorage)(nil).GetAsOf), ctx, id, at)
+}
+
+// GetPriceChange mocks base method.
+func (m *MockStorage) GetPriceChange(ctx context.Context, id string) (domain.PriceChange, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPriceChange", ctx, id)
+	ret0, _ := ret[0].(domain.PriceChange)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPriceChange indicates an expected call of GetPriceChange.
+func (mr *MockStorageMockRecorder) GetPriceChange(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPriceChange", reflect.TypeOf((*MockStorage)(nil).GetPriceChange), ctx, id)
+}
+
+// GetReservation mocks base method.
+func (m *MockStorage) GetReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReservation", ctx, id)
+	ret0, _ := ret[0].(domain.Reservation)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetReservation indicates an expected call of GetReservation.
+func (mr *MockStorageMockRecorder) GetReservation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReservation", reflect.TypeOf((*MockStorage)(nil).GetReservation), ctx, id)
+}
+
+// GetWarehouse mocks base method.
+func (m *MockStorage) GetWarehouse(ctx context.Context, id string) (domain.Warehouse, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWarehouse", ctx, id)
+	ret0, _ := ret[0].(domain.Warehouse)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetWarehouse indicates an expected call of GetWarehouse.
+func (mr *MockStorageMockRecorder) GetWarehouse(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWarehouse", reflect.TypeOf((*MockStorage)(nil).GetWarehouse), ctx, id)
+}
+
+// History mocks base method.
+func (m *MockStorage) History(ctx context.Context, productID string, limit int) ([]domain.ProductRevision, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, productID, limit)
+	ret0, _ := ret[0].([]domain.ProductRevision)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// History indicates an expected call of History.
+func (mr *MockStorageMockRecorder) History(ctx, productID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockStorage)(nil).History), ctx, productID, limit)
+}
+
+// List mocks base method.
+func (m *MockStorage) List(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, query)
+	ret0, _ := ret[0].(domain.ProductPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockStorageMockRecorder) List(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStorage)(nil).List), ctx, query)
+}
+
+// ListWarehouses mocks base method.
+func (m *MockStorage) ListWarehouses(ctx context.Context) ([]domain.Warehouse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWarehouses", ctx)
+	ret0, _ := ret[0].([]domain.Warehouse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWarehouses indicates an expected call of ListWarehouses.
+func (mr *MockStorageMockRecorder) ListWarehouses(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWarehouses", reflect.TypeOf((*MockStorage)(nil).ListWarehouses), ctx)
+}
+
+// PriceHistory mocks base method.
+func (m *MockStorage) PriceHistory(ctx context.Context, productID string, limit int) ([]domain.PricePoint, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PriceHistory", ctx, productID, limit)
+	ret0, _ := ret[0].([]domain.PricePoint)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PriceHistory indicates an expected call of PriceHistory.
+func (mr *MockStorageMockRecorder) PriceHistory(ctx, productID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PriceHistory", reflect.TypeOf((*MockStorage)(nil).PriceHistory), ctx, productID, limit)
+}
+
+// PriceSchedule mocks base method.
+func (m *MockStorage) PriceSchedule(ctx context.Context, productID string) ([]domain.PriceChange, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PriceSchedule", ctx, productID)
+	ret0, _ := ret[0].([]domain.PriceChange)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PriceSchedule indicates an expected call of PriceSchedule.
+func (mr *MockStorageMockRecorder) PriceSchedule(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PriceSchedule", reflect.TypeOf((*MockStorage)(nil).PriceSchedule), ctx, productID)
+}
+
+// Purge mocks base method.
+func (m *MockStorage) Purge(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorageMockRecorder) Purge(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorage)(nil).Purge), ctx, id)
+}
+
+// PurgeTrash mocks base method.
+func (m *MockStorage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTrash", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeTrash indicates an expected call of PurgeTrash.
+func (mr *MockStorageMockRecorder) PurgeTrash(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTrash", reflect.TypeOf((*MockStorage)(nil).PurgeTrash), ctx, before)
+}
+
+// ReconcileStock mocks base method.
+func (m *MockStorage) ReconcileStock(ctx context.Context, productID string) (domain.StockReconciliation, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileStock", ctx, productID)
+	ret0, _ := ret[0].(domain.StockReconciliation)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReconcileStock indicates an expected call of ReconcileStock.
+func (mr *MockStorageMockRecorder) ReconcileStock(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileStock", reflect.TypeOf((*MockStorage)(nil).ReconcileStock), ctx, productID)
+}
+
+// ReleaseReservation mocks base method.
+func (m *MockStorage) ReleaseReservation(ctx context.Context, id string) (domain.Reservation, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseReservation", ctx, id)
+	ret0, _ := ret[0].(domain.Reservation)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReleaseReservation indicates an expected call of ReleaseReservation.
+func (mr *MockStorageMockRecorder) ReleaseReservation(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseReservation", reflect.TypeOf((*MockStorage)(nil).ReleaseReservation), ctx, id)
+}
+
+// Rename mocks base method.
+func (m *MockStorage) Rename(ctx context.Context, rename domain.ProductRename) (domain.RenameResult, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rename", ctx, rename)
+	ret0, _ := ret[0].(domain.RenameResult)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Rename indicates an expected call of Rename.
+func (mr *MockStorageMockRecorder) Rename(ctx, rename interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rename", reflect.TypeOf((*MockStorage)(nil).Rename), ctx, rename)
+}
+
+// Reserve mocks base method.
+func (m *MockStorage) Reserve(ctx context.Context, request domain.ReservationRequest) (domain.Reservation, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, request)
+	ret0, _ := ret[0].(domain.Reservation)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockStorageMockRecorder) Reserve(ctx, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockStorage)(nil).Reserve), ctx, request)
+}
+
+// Restore mocks base method.
+func (m *MockStorage) Restore(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorageMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorage)(nil).Restore), ctx, id)
+}
+
+// Save mocks base method.
+func (m *MockStorage) Save(ctx context.Context, product domain.Product) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, product)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockStorageMockRecorder) Save(ctx, product interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockStorage)(nil).Save), ctx, product)
+}
+
+// SchedulePrice mocks base method.
+func (m *MockStorage) SchedulePrice(ctx context.Context, request domain.PriceChangeRequest) (domain.PriceChange, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SchedulePrice", ctx, request)
+	ret0, _ := ret[0].(domain.PriceChange)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SchedulePrice indicates an expected call of SchedulePrice.
+func (mr *MockStorageMockRecorder) SchedulePrice(ctx, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SchedulePrice", reflect.TypeOf((*MockStorage)(nil).SchedulePrice), ctx, request)
+}
+
+// Search mocks base method.
+func (m *MockStorage) Search(ctx context.Context, text string, limit int) ([]domain.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, text, limit)
+	ret0, _ := ret[0].([]domain.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockStorageMockRecorder) Search(ctx, text, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockStorage)(nil).Search), ctx, text, limit)
+}
+
+// StockLevels mocks base method.
+func (m *MockStorage) StockLevels(ctx context.Context, productID string) ([]domain.StockLevel, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StockLevels", ctx, productID)
+	ret0, _ := ret[0].([]domain.StockLevel)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// StockLevels indicates an expected call of StockLevels.
+func (mr *MockStorageMockRecorder) StockLevels(ctx, productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StockLevels", reflect.TypeOf((*MockStorage)(nil).StockLevels), ctx, productID)
+}
+
+// StockMovements mocks base method.
+func (m *MockStorage) StockMovements(ctx context.Context, productID string, reason domain.MovementReason, limit int) ([]domain.StockMovement, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StockMovements", ctx, productID, reason, limit)
+	ret0, _ := ret[0].([]domain.StockMovement)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// StockMovements indicates an expected call of StockMovements.
+func (mr *MockStorageMockRecorder) StockMovements(ctx, productID, reason, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StockMovements", reflect.TypeOf((*MockStorage)(nil).StockMovements), ctx, productID, reason, limit)
+}
+
+// TransferStock mocks base method.
+func (m *MockStorage) TransferStock(ctx context.Context, transfer domain.StockTransfer) ([]domain.StockLevel, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferStock", ctx, transfer)
+	ret0, _ := ret[0].([]domain.StockLevel)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TransferStock indicates an expected call of TransferStock.
+func (mr *MockStorageMockRecorder) TransferStock(ctx, transfer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferStock", reflect.TypeOf((*MockStorage)(nil).TransferStock), ctx, transfer)
+}
+
+// Trash mocks base method.
+func (m *MockStorage) Trash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx)
+	ret0, _ := ret[0].([]domain.TrashedProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockStorageMockRecorder) Trash(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockStorage)(nil).Trash), ctx)
+}
+
+// Update mocks base method.
+func (m *MockStorage) Update(ctx context.Context, id string, diff domain.ProductDiff) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, diff)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorageMockRecorder) Update(ctx, id, diff interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorage)(nil).Update), ctx, id, diff)
+}
+
+// WarehouseStock mocks base method.
+func (m *MockStorage) WarehouseStock(ctx context.Context, warehouseID string) ([]domain.StockLevel, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WarehouseStock", ctx, warehouseID)
+	ret0, _ := ret[0].([]domain.StockLevel)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WarehouseStock indicates an expected call of WarehouseStock.
+func (mr *MockStorageMockRecorder) WarehouseStock(ctx, warehouseID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WarehouseStock", reflect.TypeOf((*MockStorage)(nil).WarehouseStock), ctx, warehouseID)
+}
+
+// MockBatchStorage is a mock of BatchStorage interface.
+type MockBatchStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchStorageMockRecorder
+}
+
+// MockBatchStorageMockRecorder is the mock recorder for MockBatchStorage.
+type MockBatchStorageMockRecorder struct {
+	mock *MockBatchStorage
+}
+
+// NewMockBatchStorage creates a new mock instance.
+func NewMockBatchStorage(ctrl *gomock.Controller) *MockBatchStorage {
+	mock := &MockBatchStorage{ctrl: ctrl}
+	mock.recorder = &MockBatchStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchStorage) EXPECT() *MockBatchStorageMockRecorder {
+	return m.recorder
+}
+
+// AdjustStockBatch mocks base method.
+func (m *MockBatchStorage) AdjustStockBatch(ctx context.Context, adjustments []domain.StockAdjustment, atomic bool) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustStockBatch", ctx, adjustments, atomic)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustStockBatch indicates an expected call of AdjustStockBatch.
+func (mr *MockBatchStorageMockRecorder) AdjustStockBatch(ctx, adjustments, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustStockBatch", reflect.TypeOf((*MockBatchStorage)(nil).AdjustStockBatch), ctx, adjustments, atomic)
+}
+
+// DeleteBatch mocks base method.
+func (m *MockBatchStorage) DeleteBatch(ctx context.Context, ids []string, atomic bool) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBatch", ctx, ids, atomic)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBatch indicates an expected call of DeleteBatch.
+func (mr *MockBatchStorageMockRecorder) DeleteBatch(ctx, ids, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBatch", reflect.TypeOf((*MockBatchStorage)(nil).DeleteBatch), ctx, ids, atomic)
+}
+
+// GetBatch mocks base method.
+func (m *MockBatchStorage) GetBatch(ctx context.Context, ids []string) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBatch", ctx, ids)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBatch indicates an expected call of GetBatch.
+func (mr *MockBatchStorageMockRecorder) GetBatch(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatch", reflect.TypeOf((*MockBatchStorage)(nil).GetBatch), ctx, ids)
+}
+
+// SaveBatch mocks base method.
+func (m *MockBatchStorage) SaveBatch(ctx context.Context, products []domain.Product, atomic bool) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBatch", ctx, products, atomic)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBatch indicates an expected call of SaveBatch.
+func (mr *MockBatchStorageMockRecorder) SaveBatch(ctx, products, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBatch", reflect.TypeOf((*MockBatchStorage)(nil).SaveBatch), ctx, products, atomic)
+}
+
+// UpdateBatch mocks base method.
+func (m *MockBatchStorage) UpdateBatch(ctx context.Context, diffs []domain.ProductDiff, atomic bool) ([]domain.BatchItemResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBatch", ctx, diffs, atomic)
+	ret0, _ := ret[0].([]domain.BatchItemResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateBatch indicates an expected call of UpdateBatch.
+func (mr *MockBatchStorageMockRecorder) UpdateBatch(ctx, diffs, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBatch", reflect.TypeOf((*MockBatchStorage)(nil).UpdateBatch), ctx, diffs, atomic)
+}
+
+// MockTransactional is a mock of Transactional interface.
+type MockTransactional struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionalMockRecorder
+}
+
+// MockTransactionalMockRecorder is the mock recorder for MockTransactional.
+type MockTransactionalMockRecorder struct {
+	mock *MockTransactional
+}
+
+// NewMockTransactional creates a new mock instance.
+func NewMockTransactional(ctrl *gomock.Controller) *MockTransactional {
+	mock := &MockTransactional{ctrl: ctrl}
+	mock.recorder = &MockTransactionalMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactional) EXPECT() *MockTransactionalMockRecorder {
+	return m.recorder
+}
+
+// Atomically mocks base method.
+func (m *MockTransactional) Atomically(fn func(domain.Storage) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Atomically", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Atomically indicates an expected call of Atomically.
+func (mr *MockTransactionalMockRecorder) Atomically(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Atomically", reflect.TypeOf((*MockTransactional)(nil).Atomically), fn)
+}
+
+// MockIdempotencyStore is a mock of IdempotencyStore interface.
+type MockIdempotencyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyStoreMockRecorder
+}
+
+// MockIdempotencyStoreMockRecorder is the mock recorder for MockIdempotencyStore.
+type MockIdempotencyStoreMockRecorder struct {
+	mock *MockIdempotencyStore
+}
+
+// NewMockIdempotencyStore creates a new mock instance.
+func NewMockIdempotencyStore(ctrl *gomock.Controller) *MockIdempotencyStore {
+	mock := &MockIdempotencyStore{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyStore) EXPECT() *MockIdempotencyStoreMockRecorder {
+	return m.recorder
+}
+
+// Begin mocks base method.
+func (m *MockIdempotencyStore) Begin(key, fingerprint string, ttl time.Duration) (*domain.IdempotentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", key, fingerprint, ttl)
+	ret0, _ := ret[0].(*domain.IdempotentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockIdempotencyStoreMockRecorder) Begin(key, fingerprint, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockIdempotencyStore)(nil).Begin), key, fingerprint, ttl)
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyStore) Complete(key string, response domain.IdempotentResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", key, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyStoreMockRecorder) Complete(key, response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyStore)(nil).Complete), key, response)
+}
+
+// ExpireIdempotencyKeys mocks base method.
+func (m *MockIdempotencyStore) ExpireIdempotencyKeys(now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireIdempotencyKeys", now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireIdempotencyKeys indicates an expected call of ExpireIdempotencyKeys.
+func (mr *MockIdempotencyStoreMockRecorder) ExpireIdempotencyKeys(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireIdempotencyKeys", reflect.TypeOf((*MockIdempotencyStore)(nil).ExpireIdempotencyKeys), now)
+}
+
+// Release mocks base method.
+func (m *MockIdempotencyStore) Release(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIdempotencyStoreMockRecorder) Release(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIdempotencyStore)(nil).Release), key)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"exam-api/domain"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Config holds the settings of the api service.
+// Every field can be overridden through the environment variable next to it.
+type Config struct {
+	// BatchWorkers is the global limit of batch items processed at once (BATCH_WORKERS)
+	BatchWorkers int
+	// BatchQueueSize is how many batch items may wait for a worker (BATCH_QUEUE_SIZE)
+	BatchQueueSize int
+	// ReservationSweepInterval is how often expired reservations hand their stock back (RESERVATION_SWEEP_SECONDS)
+	ReservationSweepInterval time.Duration
+	// PriceSweepInterval is how often due price changes are applied (PRICE_SWEEP_SECONDS)
+	PriceSweepInterval time.Duration
+	// TrashRetention is how long deleted products stay in the trash before they are purged,
+	// zero keeps them until they are purged by hand (TRASH_RETENTION_HOURS)
+	TrashRetention time.Duration
+	// ExchangeRates convert prices on reads asking for another currency. They are given against
+	// DefaultCurrency as comma separated currency=rate pairs, such as "EUR=0.2010,USD=0.2185" (EXCHANGE_RATES)
+	ExchangeRates domain.ExchangeRates
+	// IDs assigns the id of a new product by one of the strategies of domain.NewIDGenerator,
+	// which has to be the same in both services (ID_STRATEGY)
+	IDs domain.IDGenerator
+	// IdempotencyTTL is how long the response to a write carrying an Idempotency-Key is replayed (IDEMPOTENCY_TTL_HOURS)
+	IdempotencyTTL time.Duration
+	// JobTimeout is how long a redis job waits for the store service to apply its items, those it has
+	// not applied by then are reported as failed (JOB_TIMEOUT_SECONDS)
+	JobTimeout time.Duration
+	// RedisAddr is the address of the Redis server redis batches are queued on (REDIS_ADDR)
+	RedisAddr string
+	// StoreURL is where the http routes reach the store service (STORE_URL)
+	StoreURL string
+	// StoreTimeout is how long a call to the store service may take before it is given up (STORE_TIMEOUT_SECONDS)
+	StoreTimeout time.Duration
+	// StoreMaxIdleConns is how many keep-alive connections to the store service are pooled (STORE_MAX_IDLE_CONNS)
+	StoreMaxIdleConns int
+	// StoreIdleConnTimeout is how long a pooled connection to the store service stays open unused (STORE_IDLE_CONN_TIMEOUT_SECONDS)
+	StoreIdleConnTimeout time.Duration
+}
+
+// LoadConfig reads the configuration from the environment, falling back to defaults
+func LoadConfig() Config {
+	return Config{
+		BatchWorkers:             envInt("BATCH_WORKERS", 64),
+		BatchQueueSize:           envInt("BATCH_QUEUE_SIZE", 1024),
+		ReservationSweepInterval: time.Duration(envInt("RESERVATION_SWEEP_SECONDS", 10)) * time.Second,
+		PriceSweepInterval:       time.Duration(envInt("PRICE_SWEEP_SECONDS", 10)) * time.Second,
+		TrashRetention:           time.Duration(envInt("TRASH_RETENTION_HOURS", 720)) * time.Hour,
+		ExchangeRates:            envExchangeRates("EXCHANGE_RATES"),
+		IDs:                      envIDGenerator("ID_STRATEGY"),
+		IdempotencyTTL:           time.Duration(envInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour,
+		JobTimeout:               time.Duration(envInt("JOB_TIMEOUT_SECONDS", 600)) * time.Second,
+		RedisAddr:                envString("REDIS_ADDR", "localhost:6379"),
+		StoreURL:                 envString("STORE_URL", "http://localhost:8081"),
+		StoreTimeout:             time.Duration(envInt("STORE_TIMEOUT_SECONDS", 10)) * time.Second,
+		StoreMaxIdleConns:        envInt("STORE_MAX_IDLE_CONNS", 100),
+		StoreIdleConnTimeout:     time.Duration(envInt("STORE_IDLE_CONN_TIMEOUT_SECONDS", 90)) * time.Second,
+	}
+}
+
+func envString(key string, fallback string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	return value
+}
+
+func envInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Errorf("Invalid value %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return i
+}
+
+func envExchangeRates(key string) domain.ExchangeRates {
+	rates, err := domain.ParseExchangeRates(domain.DefaultCurrency, os.Getenv(key))
+	if err != nil {
+		log.Errorf("Invalid value for %s, prices will only be served in %s, err=%v", key, domain.DefaultCurrency, err)
+		return domain.ExchangeRates{Base: domain.DefaultCurrency}
+	}
+	return rates
+}
+
+func envIDGenerator(key string) domain.IDGenerator {
+	strategy, ok := os.LookupEnv(key)
+	if !ok {
+		strategy = domain.DefaultIDStrategy
+	}
+
+	ids, err := domain.NewIDGenerator(strategy)
+	if err != nil {
+		log.Errorf("Invalid value %q for %s, using %s", strategy, key, domain.DefaultIDStrategy)
+		ids, _ = domain.NewIDGenerator(domain.DefaultIDStrategy)
+	}
+	return ids
+}
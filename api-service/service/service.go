@@ -1,21 +1,31 @@
 package service
 
 import (
+	"context"
+	"exam-api/domain"
 	"exam-api/gateways/api"
 	"exam-api/gateways/memory"
+	"exam-api/gateways/pool"
+	"exam-api/gateways/queue"
+	"exam-api/gateways/remote"
 	"net/http"
 	"os"
+	"time"
 
 	runtime "github.com/banzaicloud/logrus-runtime-formatter"
 	"github.com/emicklei/go-restful/v3"
+	"github.com/go-redis/redis/v8"
 	log "github.com/sirupsen/logrus"
 )
 
 type Service struct {
+	config Config
 }
 
 func NewService() *Service {
-	return &Service{}
+	return &Service{
+		config: LoadConfig(),
+	}
 }
 
 func (s *Service) StartWebService() {
@@ -30,12 +40,109 @@ func (s *Service) StartWebService() {
 
 	ws := new(restful.WebService)
 	restful.Add(ws)
+	restful.DefaultContainer.ServiceErrorHandler(api.WriteServiceError)
 
-	storage := memory.NewStore()
+	storage := memory.NewStore(s.config.IDs)
+	go sweepReservations(storage, s.config.ReservationSweepInterval)
+	go sweepPrices(storage, s.config.PriceSweepInterval)
+	go sweepTrash(storage, s.config.TrashRetention)
 
-	apiManager := api.NewAPI(storage)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: s.config.RedisAddr,
+	})
+	productQueue := queue.NewRedisRepo(redisClient)
+
+	workers := pool.NewPool(s.config.BatchWorkers, s.config.BatchQueueSize)
+
+	keys := memory.NewIdempotencyKeys()
+	go sweepIdempotencyKeys(keys)
+
+	storeClient := remote.NewClient(
+		remote.NewHTTPClient(s.config.StoreTimeout, s.config.StoreMaxIdleConns, s.config.StoreIdleConnTimeout),
+		s.config.StoreURL)
+
+	apiManager := api.NewAPI(storage, storeClient, productQueue, workers, s.config.ExchangeRates, s.config.IDs, keys, s.config.IdempotencyTTL, s.config.JobTimeout)
 	apiManager.RegisterRoutes(ws)
 
 	log.Printf("Started api service on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
+
+// sweepReservations hands the stock of expired reservations of the memory store back every interval
+func sweepReservations(storage *memory.Store, interval time.Duration) {
+	if interval <= 0 {
+		log.Errorf("Reservation sweep interval must be positive, reservations will only expire on confirmation")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if expired := storage.ExpireReservations(now); expired > 0 {
+			log.Infof("Expired %d reservations", expired)
+		}
+	}
+}
+
+// sweepPrices applies the price changes of the memory store that fell due every interval
+func sweepPrices(storage *memory.Store, interval time.Duration) {
+	if interval <= 0 {
+		log.Errorf("Price sweep interval must be positive, scheduled price changes will not be applied")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if applied := storage.ApplyPriceChanges(now); applied > 0 {
+			log.Infof("Applied %d scheduled price changes", applied)
+		}
+	}
+}
+
+// trashSweepInterval is how often products past the trash retention are purged
+const trashSweepInterval = time.Minute
+
+// sweepTrash purges products that have been in the trash for longer than retention
+func sweepTrash(storage domain.Storage, retention time.Duration) {
+	if retention <= 0 {
+		log.Infof("Trash retention is not positive, deleted products are kept until purged")
+		return
+	}
+
+	ticker := time.NewTicker(trashSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		purged, err := storage.PurgeTrash(context.Background(), now.Add(-retention))
+		if err != nil {
+			log.Errorf("Failed to purge trash, err=%v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Infof("Purged %d products from trash", purged)
+		}
+	}
+}
+
+// idempotencySweepInterval is how often expired idempotency keys are forgotten
+const idempotencySweepInterval = time.Minute
+
+// sweepIdempotencyKeys forgets the idempotency keys whose ttl has passed
+func sweepIdempotencyKeys(keys domain.IdempotencyStore) {
+	ticker := time.NewTicker(idempotencySweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		expired, err := keys.ExpireIdempotencyKeys(now)
+		if err != nil {
+			log.Errorf("Failed to expire idempotency keys, err=%v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Infof("Expired %d idempotency keys", expired)
+		}
+	}
+}
@@ -8,6 +8,244 @@ CREATE TABLE IF NOT EXISTS products(
     tags varchar(64)[]
     );
 
+-- Incremented by every update, used for optimistic concurrency
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+-- Prices are in the minor units of an ISO 4217 currency, products stored before it was recorded are priced in bani
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RON' CHECK (currency ~ '^[A-Z]{3}$');
+
+-- Price and stock were optional at first, products stored without them read as 0 and are backfilled with it
+UPDATE products SET price = COALESCE(price, 0), stock = COALESCE(stock, 0) WHERE price IS NULL OR stock IS NULL;
+ALTER TABLE products ALTER COLUMN price SET DEFAULT 0, ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN stock SET DEFAULT 0, ALTER COLUMN stock SET NOT NULL;
+
+-- Set when a product is moved to the trash, trashed products are left out of reads until restored or purged
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS products_trash_idx ON products (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Indexes backing product listings (filters and keyset pagination). Names and ids are ordered
+-- byte by byte with the "C" collation, the order the memory store lists them in.
+DROP INDEX IF EXISTS products_name_idx;
+CREATE INDEX IF NOT EXISTS products_id_c_idx ON products ((id COLLATE "C"));
+CREATE INDEX IF NOT EXISTS products_name_c_idx ON products ((name COLLATE "C"), (id COLLATE "C"));
+DROP INDEX IF EXISTS products_price_idx;
+CREATE INDEX IF NOT EXISTS products_price_c_idx ON products (COALESCE(price, 0), (id COLLATE "C"));
+DROP INDEX IF EXISTS products_stock_idx;
+CREATE INDEX IF NOT EXISTS products_stock_c_idx ON products (COALESCE(stock, 0), (id COLLATE "C"));
+CREATE INDEX IF NOT EXISTS products_manufacturer_idx ON products (manufacturer);
+CREATE INDEX IF NOT EXISTS products_tags_idx ON products USING GIN (tags);
+
+-- Full-text and fuzzy search, insensitive to case and diacritics
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- unaccent is only STABLE, wrap it so it can be used in indexes and generated columns
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text AS
+    $$ SELECT public.unaccent('public.unaccent', $1) $$
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE OR REPLACE FUNCTION products_search_vector(name text, manufacturer text, tags varchar(64)[]) RETURNS tsvector AS
+    $$ SELECT setweight(to_tsvector('simple', immutable_unaccent(coalesce(name, ''))), 'A') ||
+              setweight(to_tsvector('simple', immutable_unaccent(coalesce(manufacturer, ''))), 'B') ||
+              setweight(to_tsvector('simple', immutable_unaccent(coalesce(array_to_string(tags, ' '), ''))), 'C') $$
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (products_search_vector(name, manufacturer, tags)) STORED;
+
+CREATE INDEX IF NOT EXISTS products_search_idx ON products USING GIN (search);
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING GIN (immutable_unaccent(lower(name)) gin_trgm_ops);
+
+-- Stock held by pending reservations, kept in step with the reservations table
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS reservations(
+    id varchar(64) primary key,
+    product_id varchar(64) not null references products(id) on delete cascade,
+    quantity integer not null check (quantity > 0),
+    status varchar(16) not null,
+    created_at timestamptz not null,
+    expires_at timestamptz not null
+    );
+
+CREATE INDEX IF NOT EXISTS reservations_pending_idx ON reservations (expires_at) WHERE status = 'pending';
+
+-- Ledger of every change to the stock of a product, the deltas of a product sum up to its stock
+CREATE TABLE IF NOT EXISTS stock_movements(
+    id bigserial primary key,
+    product_id varchar(64) not null references products(id) on delete cascade,
+    delta integer not null,
+    reason varchar(16) not null,
+    actor varchar(64),
+    reference varchar(64),
+    created_at timestamptz not null default now()
+    );
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_idx ON stock_movements (product_id, id);
+
+-- Products created before the ledger existed open it with their current stock
+INSERT INTO stock_movements (product_id, delta, reason)
+    SELECT id, stock, 'initial' FROM products
+    WHERE COALESCE(stock, 0) <> 0
+        AND NOT EXISTS (SELECT 1 FROM stock_movements WHERE product_id = products.id);
+
+-- Stock is held in warehouses, the stock and reserved columns of a product are the sums of its levels
+CREATE TABLE IF NOT EXISTS warehouses(
+    id varchar(64) primary key,
+    name varchar(64) not null,
+    location varchar(128)
+    );
+
+INSERT INTO warehouses (id, name) VALUES ('main', 'main') ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS stock_levels(
+    product_id varchar(64) not null references products(id) on delete cascade,
+    warehouse_id varchar(64) not null references warehouses(id),
+    quantity integer not null default 0,
+    reserved integer not null default 0,
+    primary key (product_id, warehouse_id)
+    );
+
+CREATE INDEX IF NOT EXISTS stock_levels_warehouse_idx ON stock_levels (warehouse_id, product_id);
+
+-- Products created before warehouses keep all their stock in main
+INSERT INTO stock_levels (product_id, warehouse_id, quantity, reserved)
+    SELECT id, 'main', COALESCE(stock, 0), reserved FROM products
+    ON CONFLICT (product_id, warehouse_id) DO NOTHING;
+
+-- Products inserted by other means than the store service get their main row as well,
+-- statements that insert it themselves take precedence
+CREATE OR REPLACE FUNCTION level_new_product() RETURNS trigger AS
+    $$ BEGIN
+        INSERT INTO stock_levels (product_id, warehouse_id, quantity, reserved)
+        VALUES (NEW.id, 'main', COALESCE(NEW.stock, 0), NEW.reserved)
+        ON CONFLICT (product_id, warehouse_id) DO NOTHING;
+        RETURN NEW;
+    END $$
+    LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_stock_level ON products;
+CREATE TRIGGER products_stock_level AFTER INSERT ON products
+    FOR EACH ROW EXECUTE FUNCTION level_new_product();
+
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS warehouse_id varchar(64) NOT NULL DEFAULT 'main' REFERENCES warehouses(id);
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS warehouse_id varchar(64) NOT NULL DEFAULT 'main' REFERENCES warehouses(id);
+
+-- Append-only history of every product, rows are not tied to the product so they outlive its deletion.
+-- It is kept by triggers so that no statement writing products can skip it,
+-- an update is recorded whenever it moves the version on or moves the product in or out of the trash.
+CREATE TABLE IF NOT EXISTS product_history(
+    id bigserial primary key,
+    product_id varchar(64) not null,
+    event varchar(16) not null,
+    name varchar(64),
+    manufacturer varchar(64),
+    price integer,
+    stock integer,
+    tags varchar(64)[],
+    version bigint not null,
+    reserved integer not null,
+    recorded_at timestamptz not null default now()
+    );
+
+ALTER TABLE product_history ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RON';
+
+CREATE INDEX IF NOT EXISTS product_history_product_idx ON product_history (product_id, recorded_at, id);
+
+CREATE OR REPLACE FUNCTION record_product_history() RETURNS trigger AS
+    $$ BEGIN
+        IF TG_OP = 'DELETE' THEN
+            -- purging a trashed product adds nothing, its deletion is already recorded
+            IF OLD.deleted_at IS NULL THEN
+                INSERT INTO product_history (product_id, event, name, manufacturer, price, currency, stock, tags, version, reserved)
+                VALUES (OLD.id, 'deleted', OLD.name, OLD.manufacturer, OLD.price, OLD.currency, OLD.stock, OLD.tags, OLD.version, OLD.reserved);
+            END IF;
+            RETURN OLD;
+        END IF;
+        INSERT INTO product_history (product_id, event, name, manufacturer, price, currency, stock, tags, version, reserved)
+        VALUES (NEW.id,
+                CASE
+                    WHEN TG_OP = 'INSERT' THEN 'created'
+                    WHEN NEW.deleted_at IS NOT NULL THEN 'deleted'
+                    WHEN OLD.deleted_at IS NOT NULL THEN 'restored'
+                    WHEN OLD.name IS DISTINCT FROM NEW.name OR OLD.manufacturer IS DISTINCT FROM NEW.manufacturer THEN 'renamed'
+                    ELSE 'updated'
+                END,
+                NEW.name, NEW.manufacturer, NEW.price, NEW.currency, NEW.stock, NEW.tags, NEW.version, NEW.reserved);
+        RETURN NEW;
+    END $$
+    LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_history ON products;
+CREATE TRIGGER products_history AFTER INSERT OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_product_history();
+
+DROP TRIGGER IF EXISTS products_history_update ON products;
+CREATE TRIGGER products_history_update AFTER UPDATE ON products
+    FOR EACH ROW WHEN (OLD.version IS DISTINCT FROM NEW.version OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+    EXECUTE FUNCTION record_product_history();
+
+-- Products created before the history existed open it with their current state
+INSERT INTO product_history (product_id, event, name, manufacturer, price, currency, stock, tags, version, reserved)
+    SELECT id, 'created', name, manufacturer, price, currency, stock, tags, version, reserved FROM products
+    WHERE NOT EXISTS (SELECT 1 FROM product_history WHERE product_id = products.id);
+
+-- Price changes scheduled ahead of time, the store service applies them once effective_from has passed
+CREATE TABLE IF NOT EXISTS price_changes(
+    id varchar(64) primary key,
+    product_id varchar(64) not null references products(id) on delete cascade,
+    price integer not null check (price >= 0),
+    effective_from timestamptz not null,
+    status varchar(16) not null,
+    created_at timestamptz not null default now(),
+    applied_at timestamptz
+    );
+
+CREATE INDEX IF NOT EXISTS price_changes_pending_idx ON price_changes (effective_from) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS price_changes_product_idx ON price_changes (product_id, effective_from);
+
+-- A change sets the currency along with the price, changes scheduled before it was recorded keep the currency of their product
+ALTER TABLE price_changes ADD COLUMN IF NOT EXISTS currency char(3) CHECK (currency ~ '^[A-Z]{3}$');
+UPDATE price_changes SET currency = products.currency FROM products WHERE price_changes.product_id = products.id AND price_changes.currency IS NULL;
+ALTER TABLE price_changes ALTER COLUMN currency SET NOT NULL;
+
+-- Renaming a product can move it to another id, what refers to it follows the id along
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_product_id_fkey,
+    ADD CONSTRAINT reservations_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_product_id_fkey,
+    ADD CONSTRAINT stock_movements_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE stock_levels DROP CONSTRAINT IF EXISTS stock_levels_product_id_fkey,
+    ADD CONSTRAINT stock_levels_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE price_changes DROP CONSTRAINT IF EXISTS price_changes_product_id_fkey,
+    ADD CONSTRAINT price_changes_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- The ids renamed products had before, so that they can still be reached under them
+CREATE TABLE IF NOT EXISTS product_aliases(
+    alias varchar(64) primary key,
+    product_id varchar(64) not null references products(id) on delete cascade on update cascade
+    );
+
+CREATE INDEX IF NOT EXISTS product_aliases_product_idx ON product_aliases (product_id);
+
+-- A product stored under id itself takes precedence over an alias
+CREATE OR REPLACE FUNCTION resolve_product_id(id varchar) RETURNS varchar AS
+    $$ SELECT COALESCE((SELECT product_id FROM product_aliases
+                        WHERE alias = $1 AND NOT EXISTS (SELECT 1 FROM products WHERE products.id = $1)), $1) $$
+    LANGUAGE sql STABLE;
+
+-- Responses to writes carrying an Idempotency-Key, status is null while the first request is running
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+    key varchar(255) primary key,
+    fingerprint varchar(64) not null,
+    status integer,
+    header jsonb,
+    body bytea,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null
+    );
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
 -- Example insert
 insert into products(id, name, tags) values('sha256', 'Lapte', ARRAY['lactate', 'uht']);
 
@@ -16,7 +254,7 @@ select * from products;
 select id, name, tags from products ;
 select id, name, from products where name='Lapte';
 
--- Example update
+-- Example update, prices are in minor units so this is 150 lei
 update products set price = 15000 WHERE name = 'Lapte';
 
 -- Example delete
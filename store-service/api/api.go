@@ -3,26 +3,85 @@ package api
 import (
 	"exam-store/domain"
 	"github.com/emicklei/go-restful/v3"
+	"time"
 )
 
 const (
 	productPath = "/product"
+	searchPath  = "/search"
+	stockPath   = "/stock"
+	ledgerPath  = "/movements"
+
+	versionBatch = "/batch"
+
+	reservationsPath = "/reservations"
+	warehousesPath   = "/warehouses"
+	transferPath     = "/transfer"
+	historyPath      = "/history"
+	trashPath        = "/trash"
+	pricesPath       = "/prices"
+	renamePath       = "/rename"
 )
 
 type API struct {
 	storage domain.Storage
+	// rates convert prices on reads asking for another currency
+	rates domain.ExchangeRates
+	// ids checks a new product carries what its id is derived from
+	ids domain.IDGenerator
+	// keys holds the responses to writes carrying an Idempotency-Key for keyTTL
+	keys   domain.IdempotencyStore
+	keyTTL time.Duration
 }
 
-func NewAPI(store domain.Storage) *API {
+func NewAPI(store domain.Storage, rates domain.ExchangeRates, ids domain.IDGenerator, keys domain.IdempotencyStore, keyTTL time.Duration) *API {
 	return &API{
 		storage: store,
+		rates:   rates,
+		ids:     ids,
+		keys:    keys,
+		keyTTL:  keyTTL,
 	}
 }
 
 func (api *API) RegisterRoutes(ws *restful.WebService) {
 	ws.Path("/store")
+	ws.Filter(api.idempotent)
 	ws.Route(ws.POST(productPath).To(api.createProductSingle))
 	ws.Route(ws.GET(productPath).To(api.getProductSingle))
 	ws.Route(ws.PATCH(productPath).To(api.updateProductSingle))
 	ws.Route(ws.DELETE(productPath).To(api.deleteProductSingle))
+	ws.Route(ws.POST(productPath + renamePath).To(api.renameProduct))
+	ws.Route(ws.GET(productPath + searchPath).To(api.searchProducts))
+	ws.Route(ws.POST(productPath + stockPath).To(api.adjustStockSingle))
+	ws.Route(ws.GET(productPath + stockPath + "/reconcile").To(api.reconcileStock))
+	ws.Route(ws.GET(productPath + ledgerPath).To(api.listStockMovements))
+	ws.Route(ws.GET(productPath + historyPath).To(api.listProductHistory))
+	ws.Route(ws.GET(productPath + warehousesPath).To(api.stockLevels))
+	ws.Route(ws.POST(productPath + transferPath).To(api.transferStock))
+	ws.Route(ws.GET(productPath + trashPath).To(api.listTrash))
+	ws.Route(ws.POST(productPath + trashPath + "/restore").To(api.restoreProduct))
+	ws.Route(ws.DELETE(productPath + trashPath).To(api.purgeTrash))
+
+	ws.Route(ws.POST(productPath + versionBatch).To(api.createProductBatch))
+	ws.Route(ws.GET(productPath + versionBatch).To(api.getProductBatch))
+	ws.Route(ws.PATCH(productPath + versionBatch).To(api.updateProductBatch))
+	ws.Route(ws.DELETE(productPath + versionBatch).To(api.deleteProductBatch))
+	ws.Route(ws.POST(productPath + versionBatch + stockPath).To(api.adjustStockBatch))
+
+	ws.Route(ws.POST(reservationsPath).To(api.createReservation))
+	ws.Route(ws.GET(reservationsPath + "/{id}").To(api.getReservation))
+	ws.Route(ws.POST(reservationsPath + "/{id}/confirm").To(api.confirmReservation))
+	ws.Route(ws.POST(reservationsPath + "/{id}/release").To(api.releaseReservation))
+
+	ws.Route(ws.POST(pricesPath).To(api.schedulePrice))
+	ws.Route(ws.GET(pricesPath + "/{id}").To(api.getPriceChange))
+	ws.Route(ws.POST(pricesPath + "/{id}/cancel").To(api.cancelPriceChange))
+	ws.Route(ws.GET(productPath + pricesPath).To(api.listPriceSchedule))
+	ws.Route(ws.GET(productPath + pricesPath + historyPath).To(api.listPriceHistory))
+
+	ws.Route(ws.POST(warehousesPath).To(api.createWarehouse))
+	ws.Route(ws.GET(warehousesPath).To(api.listWarehouses))
+	ws.Route(ws.GET(warehousesPath + "/{id}").To(api.getWarehouse))
+	ws.Route(ws.GET(warehousesPath + "/{id}" + stockPath).To(api.warehouseStock))
 }
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// errRolledBack aborts the transaction of an atomic batch in which an item failed
+var errRolledBack = errors.New("batch rolled back")
+
+func (api *API) createProductBatch(req *restful.Request, resp *restful.Response) {
+	products, ok := readBatch[domain.Product](req, resp)
+	if !ok || !validateProducts(req, resp, api.ids, products) {
+		return
+	}
+
+	results, err := api.applyBatch(isAtomic(req), func(storage domain.Storage) ([]domain.BatchItemResult, error) {
+		return domain.CreateResults(storage, products)
+	})
+	if err != nil {
+		log.Errorf("Failed to save batch, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	writeBatchResults(resp, results)
+	log.Infof("Batch of %d products processed", len(products))
+}
+
+func (api *API) getProductBatch(req *restful.Request, resp *restful.Response) {
+	ids := req.QueryParameters("id")
+	if len(ids) == 0 {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	products, err := api.storage.GetBatch(ids)
+	if err != nil {
+		log.Errorf("Failed to get batch, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	results := make([]domain.BatchItemResult, len(ids))
+	for i, id := range ids {
+		product, ok := products[id]
+		if !ok {
+			results[i] = domain.BatchItemResult{Index: i, ID: id, Status: http.StatusNotFound, Error: "product is not available"}
+			continue
+		}
+		if !api.convertPrices(req, resp, currency, &product) {
+			return
+		}
+		results[i] = domain.BatchItemResult{Index: i, ID: id, Status: http.StatusOK, Product: &product}
+	}
+
+	writeBatchResults(resp, results)
+	log.Infof("Batch of %d products got", len(products))
+}
+
+func (api *API) updateProductBatch(req *restful.Request, resp *restful.Response) {
+	productDiffs, ok := readBatch[domain.ProductDiff](req, resp)
+	if !ok {
+		return
+	}
+
+	results, err := api.applyBatch(isAtomic(req), func(storage domain.Storage) ([]domain.BatchItemResult, error) {
+		return domain.UpdateResults(storage, productDiffs)
+	})
+	if err != nil {
+		log.Errorf("Failed to update batch, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	writeBatchResults(resp, results)
+	log.Infof("Batch of %d products processed", len(productDiffs))
+}
+
+func (api *API) deleteProductBatch(req *restful.Request, resp *restful.Response) {
+	ids := req.QueryParameters("id")
+	if len(ids) == 0 {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	results, err := api.applyBatch(isAtomic(req), func(storage domain.Storage) ([]domain.BatchItemResult, error) {
+		return domain.DeleteResults(storage, ids)
+	})
+	if err != nil {
+		log.Errorf("Failed to delete batch, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	writeBatchResults(resp, results)
+	log.Infof("Batch of %d products processed", len(ids))
+}
+
+// applyBatch runs apply against the storage. In atomic mode it runs inside a transaction
+// that is rolled back if any item failed, leaving the catalogue untouched.
+func (api *API) applyBatch(atomic bool, apply func(storage domain.Storage) ([]domain.BatchItemResult, error)) ([]domain.BatchItemResult, error) {
+	if !atomic {
+		return apply(api.storage)
+	}
+
+	var results []domain.BatchItemResult
+	err := api.storage.Atomically(func(tx domain.Storage) error {
+		var err error
+		results, err = apply(tx)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if !result.Succeeded() {
+				return errRolledBack
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errRolledBack) {
+		markRolledBack(results)
+		return results, nil
+	}
+	return results, err
+}
+
+// isAtomic reports whether the caller asked for an all-or-nothing batch
+func isAtomic(req *restful.Request) bool {
+	atomic, _ := strconv.ParseBool(req.QueryParameter("atomic"))
+	return atomic
+}
+
+// markRolledBack flags the items that had succeeded as not applied,
+// since the transaction they were part of was rolled back
+func markRolledBack(results []domain.BatchItemResult) {
+	for i := range results {
+		if results[i].Succeeded() {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = "rolled back"
+			results[i].Product = nil
+		}
+	}
+}
+
+// validateProducts checks every product of a create batch.
+// On failure it writes a 400 response naming the first invalid product and returns false.
+func validateProducts(req *restful.Request, resp *restful.Response, ids domain.IDGenerator, products []domain.Product) bool {
+	for i := range products {
+		if err := products[i].Validate(ids); err != nil {
+			log.Errorf("Failed to validate product %d, err=%v", i, err)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("product %d: %w", i, err))
+			return false
+		}
+	}
+	return true
+}
+
+// readBatch decodes a non-empty JSON array from the request body.
+// On failure it writes a 400 response and returns false.
+func readBatch[T any](req *restful.Request, resp *restful.Response) ([]T, bool) {
+	if req.Request.Body == nil {
+		log.Errorf("Failed to read batch, empty body")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("request body must be provided"))
+		return nil, false
+	}
+	defer req.Request.Body.Close()
+
+	var batch []T
+	if err := json.NewDecoder(req.Request.Body).Decode(&batch); err != nil {
+		log.Errorf("Failed to read batch, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return nil, false
+	}
+
+	if len(batch) == 0 {
+		log.Errorf("Failed to read batch, no items")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("at least one item must be provided"))
+		return nil, false
+	}
+	return batch, true
+}
+
+// writeBatchResults answers with the per-item results and an overall status:
+// 200 if every item succeeded, the status of the culprit if an atomic batch was rolled back,
+// the shared status if every item failed the same way and 207 Multi-Status otherwise
+func writeBatchResults(resp *restful.Response, results []domain.BatchItemResult) {
+	_ = resp.WriteHeaderAndJson(batchStatus(results), results, restful.MIME_JSON)
+}
+
+func batchStatus(results []domain.BatchItemResult) int {
+	succeeded := 0
+	for _, result := range results {
+		if result.Succeeded() {
+			succeeded++
+		}
+	}
+
+	if succeeded == len(results) {
+		return http.StatusOK
+	}
+
+	if succeeded == 0 {
+		// a rolled back batch takes the status of the first item that caused the rollback
+		for _, result := range results {
+			if result.Status == http.StatusFailedDependency {
+				return rollbackStatus(results)
+			}
+		}
+
+		status := results[0].Status
+		for _, result := range results[1:] {
+			if result.Status != status {
+				return http.StatusMultiStatus
+			}
+		}
+		return status
+	}
+
+	return http.StatusMultiStatus
+}
+
+func rollbackStatus(results []domain.BatchItemResult) int {
+	for _, result := range results {
+		if result.Status != http.StatusFailedDependency {
+			return result.Status
+		}
+	}
+	return http.StatusFailedDependency
+}
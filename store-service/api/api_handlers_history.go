@@ -0,0 +1,90 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listProductHistory(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit, err := historyLimit(req.QueryParameter("limit"))
+	if err != nil {
+		log.Errorf("Failed to read history limit, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	revisions, isProductThere, err := api.storage.History(id, limit)
+	if err != nil {
+		log.Errorf("Failed to get product history, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product has no history in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.WriteAsJson(revisions)
+	log.Infof("History of product %v got", id)
+}
+
+// getProductAsOf converts a price to currency using the exchange rates configured now
+func (api *API) getProductAsOf(req *restful.Request, resp *restful.Response, id string, asOf string, currency string) {
+	at, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		log.Errorf("Failed to read asOf %q", asOf)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("asOf must be an RFC 3339 timestamp"))
+		return
+	}
+
+	product, isProductThere, err := api.storage.GetAsOf(id, at)
+	if err != nil {
+		log.Errorf("Failed to get product as of %v, err=%v", asOf, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not in database as of %v", asOf)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product is not available"))
+		return
+	}
+
+	if !api.convertPrices(req, resp, currency, &product) {
+		return
+	}
+
+	resp.WriteAsJson(product)
+	log.Infof("Product %v as of %v got", id, asOf)
+}
+
+// historyLimit reads the limit of a history listing, capped at MaxHistoryLimit
+func historyLimit(param string) (int, error) {
+	if param == "" {
+		return domain.DefaultHistoryLimit, nil
+	}
+
+	i, err := strconv.Atoi(param)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if i > domain.MaxHistoryLimit {
+		i = domain.MaxHistoryLimit
+	}
+	return i, nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listStockMovements(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit := domain.DefaultMovementLimit
+	if param := req.QueryParameter("limit"); param != "" {
+		i, err := strconv.Atoi(param)
+		if err != nil || i < 1 {
+			log.Errorf("Failed to read movement limit %q", param)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		if i > domain.MaxMovementLimit {
+			i = domain.MaxMovementLimit
+		}
+		limit = i
+	}
+
+	movements, isProductThere, err := api.storage.StockMovements(id, domain.MovementReason(req.QueryParameter("reason")), limit)
+	if err != nil {
+		log.Errorf("Failed to get stock movements, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.WriteAsJson(movements)
+	log.Infof("Stock movements of product %v got", id)
+}
+
+func (api *API) reconcileStock(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	reconciliation, isProductThere, err := api.storage.ReconcileStock(id)
+	if err != nil {
+		log.Errorf("Failed to reconcile stock, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	if !reconciliation.Consistent {
+		log.Warnf("Stock of product %v is off its ledger by %v", id, reconciliation.Difference)
+	}
+	resp.WriteAsJson(reconciliation)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"exam-store/domain"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listProducts(req *restful.Request, resp *restful.Response) {
+	query, err := domain.ParseProductQuery(req.Request.URL.Query())
+	if err != nil {
+		log.Errorf("Failed to read listing query, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	page, err := api.storage.List(query)
+	if err != nil {
+		log.Errorf("Failed to list products, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	products := make([]*domain.Product, len(page.Items))
+	for i := range page.Items {
+		products[i] = &page.Items[i].Product
+	}
+	if !api.convertPrices(req, resp, currency, products...) {
+		return
+	}
+
+	resp.WriteAsJson(page)
+	log.Infof("Listed %d products", len(page.Items))
+}
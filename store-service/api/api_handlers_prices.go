@@ -0,0 +1,148 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) schedulePrice(req *restful.Request, resp *restful.Response) {
+	request := domain.PriceChangeRequest{}
+	err := req.ReadEntity(&request)
+	if err != nil {
+		log.Errorf("Failed to read price change, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		log.Errorf("Failed to validate price change, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	change, isProductThere, err := api.storage.SchedulePrice(request)
+	if err != nil {
+		log.Errorf("Failed to schedule price change, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+change.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, change, restful.MIME_JSON)
+	log.Infof("Price change %v sets product %v to %v from %v", change.ID, change.ProductID, change.Price, change.EffectiveFrom)
+}
+
+func (api *API) getPriceChange(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	change, isChangeThere, err := api.storage.GetPriceChange(id)
+	if err != nil {
+		log.Errorf("Failed to get price change, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isChangeThere {
+		log.Errorf("Price change %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("price change not found"))
+		return
+	}
+
+	resp.WriteAsJson(change)
+	log.Infof("Price change %v got", id)
+}
+
+// cancelPriceChange cancels the price change named in the path,
+// answering 409 Conflict if it is no longer pending
+func (api *API) cancelPriceChange(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	change, isChangeThere, err := api.storage.CancelPriceChange(id)
+	if err != nil {
+		log.Errorf("Failed to cancel price change %v, err=%v", id, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isChangeThere {
+		log.Errorf("Price change %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("price change not found"))
+		return
+	}
+
+	resp.WriteAsJson(change)
+	log.Infof("Price change %v cancelled", id)
+}
+
+func (api *API) listPriceSchedule(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	changes, isProductThere, err := api.storage.PriceSchedule(id)
+	if err != nil {
+		log.Errorf("Failed to get price schedule, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.WriteAsJson(changes)
+	log.Infof("Price schedule of product %v got", id)
+}
+
+func (api *API) listPriceHistory(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	limit, err := historyLimit(req.QueryParameter("limit"))
+	if err != nil {
+		log.Errorf("Failed to read history limit, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	points, isProductThere, err := api.storage.PriceHistory(id, limit)
+	if err != nil {
+		log.Errorf("Failed to get price history, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product has no history in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	if !api.convertPricePoints(req, resp, currency, points) {
+		return
+	}
+	resp.WriteAsJson(points)
+	log.Infof("Price history of product %v got", id)
+}
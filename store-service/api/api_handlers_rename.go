@@ -0,0 +1,43 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) renameProduct(req *restful.Request, resp *restful.Response) {
+	rename := domain.ProductRename{}
+	err := req.ReadEntity(&rename)
+	if err != nil {
+		log.Errorf("Failed to read rename, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := rename.Validate(); err != nil {
+		log.Errorf("Failed to validate rename, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	result, isProductThere, err := api.storage.Rename(rename)
+	if err != nil {
+		log.Errorf("Failed to rename product %v, err=%v", rename.ID, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.AddHeader("ETag", etag(result.Product.Version, ""))
+	resp.WriteAsJson(result)
+	log.Infof("Product %v renamed, now stored as %v", rename.ID, result.ID)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) createReservation(req *restful.Request, resp *restful.Response) {
+	request := domain.ReservationRequest{}
+	err := req.ReadEntity(&request)
+	if err != nil {
+		log.Errorf("Failed to read reservation, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		log.Errorf("Failed to validate reservation, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	reservation, isProductThere, err := api.storage.Reserve(request)
+	if err != nil {
+		log.Errorf("Failed to reserve %v of product %v in warehouse %v, err=%v", request.Quantity, request.ProductID, request.WarehouseID(), err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+reservation.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, reservation, restful.MIME_JSON)
+	log.Infof("Reservation %v holds %v of product %v", reservation.ID, reservation.Quantity, reservation.ProductID)
+}
+
+func (api *API) getReservation(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	reservation, isReservationThere, err := api.storage.GetReservation(id)
+	if err != nil {
+		log.Errorf("Failed to get reservation, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isReservationThere {
+		log.Errorf("Reservation %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("reservation not found"))
+		return
+	}
+
+	resp.WriteAsJson(reservation)
+	log.Infof("Reservation %v got", id)
+}
+
+func (api *API) confirmReservation(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.storage.ConfirmReservation)
+}
+
+func (api *API) releaseReservation(req *restful.Request, resp *restful.Response) {
+	closeReservation(req, resp, api.storage.ReleaseReservation)
+}
+
+// closeReservation confirms or releases the reservation named in the path,
+// answering 409 Conflict if it is no longer pending
+func closeReservation(req *restful.Request, resp *restful.Response, close func(id string) (domain.Reservation, bool, error)) {
+	id := req.PathParameter("id")
+	reservation, isReservationThere, err := close(id)
+	if err != nil {
+		log.Errorf("Failed to close reservation %v, err=%v", id, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isReservationThere {
+		log.Errorf("Reservation %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("reservation not found"))
+		return
+	}
+
+	resp.WriteAsJson(reservation)
+	log.Infof("Reservation %v %v", id, reservation.Status)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) searchProducts(req *restful.Request, resp *restful.Response) {
+	text := req.QueryParameter("q")
+	if text == "" {
+		log.Errorf("Failed to read search text")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("q must be provided"))
+		return
+	}
+
+	limit := domain.DefaultSearchLimit
+	if param := req.QueryParameter("limit"); param != "" {
+		i, err := strconv.Atoi(param)
+		if err != nil || i < 1 {
+			log.Errorf("Failed to read search limit %q", param)
+			writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		if i > domain.MaxSearchLimit {
+			i = domain.MaxSearchLimit
+		}
+		limit = i
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	results, err := api.storage.Search(text, limit)
+	if err != nil {
+		log.Errorf("Failed to search products, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	products := make([]*domain.Product, len(results))
+	for i := range results {
+		products[i] = &results[i].Product
+	}
+	if !api.convertPrices(req, resp, currency, products...) {
+		return
+	}
+
+	resp.WriteAsJson(results)
+	log.Infof("Search for %q matched %d products", text, len(results))
+}
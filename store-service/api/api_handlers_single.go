@@ -14,7 +14,13 @@ func (api *API) createProductSingle(req *restful.Request, resp *restful.Response
 	err := req.ReadEntity(&product)
 	if err != nil {
 		log.Errorf("Failed to read product, err=%v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("read error: %v", err))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := product.Validate(api.ids); err != nil {
+		log.Errorf("Failed to validate product, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
 		return
 	}
 
@@ -22,13 +28,13 @@ func (api *API) createProductSingle(req *restful.Request, resp *restful.Response
 
 	if err != nil {
 		log.Errorf("Failed to save product, err=%v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("save error: %v", err))
+		writeProblem(req, resp, statusOf(err), fmt.Errorf("save error: %w", err))
 		return
 	}
 
 	if alreadyInDatabase {
-		log.Errorf("Failed to save product, err=%v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("already in database"))
+		log.Errorf("Product %v already in database", id)
+		writeAlreadyExists(req, resp, id, domain.NewError(domain.ErrAlreadyExists, "", "already in database"))
 		return
 	}
 
@@ -41,55 +47,85 @@ func (api *API) getProductSingle(req *restful.Request, resp *restful.Response) {
 	id := req.QueryParameter("id")
 	product := domain.Product{}
 	if id == "" {
-		log.Errorf("Failed to read id")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		// without an id the route lists products instead
+		api.listProducts(req, resp)
+		return
+	}
+
+	currency, ok := readCurrency(req, resp)
+	if !ok {
+		return
+	}
+
+	if asOf := req.QueryParameter("asOf"); asOf != "" {
+		api.getProductAsOf(req, resp, id, asOf, currency)
 		return
 	}
 
 	product, isProductThere, err := api.storage.Get(id)
 	if err != nil {
 		log.Errorf("Failed to get product, err=%v", err)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("failed to get product %v", err))
+		writeProblem(req, resp, statusOf(err), fmt.Errorf("failed to get product: %w", err))
 		return
 	}
 
 	if !isProductThere {
-		log.Errorf("Failed to get product, err=%v", err)
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product is not available"))
+		log.Errorf("Product %v not found in database", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product is not available"))
+		return
+	}
+
+	if !api.convertPrices(req, resp, currency, &product) {
 		return
 	}
 
+	resp.AddHeader("ETag", etag(product.Version, currency))
 	resp.WriteAsJson(product)
 	log.Infof("Product %v got", id)
 }
 
 func (api *API) updateProductSingle(req *restful.Request, resp *restful.Response) {
-	product := exam_api_domain.Product{}
-	err := req.ReadEntity(&product)
+	diff := exam_api_domain.ProductDiff{}
+	err := req.ReadEntity(&diff)
 	if err != nil {
-		log.Errorf("Failed to read product, err=%v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("read error: %v", err))
+		log.Errorf("Failed to read product diff, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
 		return
 	}
 
-	id := product.Name
+	id := diff.ID
 	if id == "" {
 		log.Errorf("Failed to read id")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id not ok"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id not ok"))
+		return
+	}
+	if err := diff.Diff.Validate(); err != nil {
+		log.Errorf("Invalid product diff, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	expected, err := ifMatchVersion(req, api.storage, id)
+	if err != nil {
+		log.Errorf("Failed to check If-Match, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
 		return
 	}
+	if expected != nil {
+		diff.Version = expected
+	}
 
-	alreadyInDatabase, err := api.storage.Update(id, product)
+	alreadyInDatabase, err := api.storage.Update(id, diff)
 
 	if err != nil {
-		log.Errorf("Failed to insert in database: %v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("read error: %v", err))
+		log.Errorf("Failed to update product %v in database: %v", id, err)
+		writeProblem(req, resp, statusOf(err), err)
 		return
 	}
 
 	if !alreadyInDatabase {
 		log.Errorf("Product not found in database")
-		_ = resp.WriteError(http.StatusNotFound, fmt.Errorf("product not found"))
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	}
 
@@ -102,21 +138,33 @@ func (api *API) deleteProductSingle(req *restful.Request, resp *restful.Response
 	id := req.QueryParameter("id")
 	if id == "" {
 		log.Errorf("Failed to read id")
-		_ = resp.WriteError(http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
 		return
 	}
 
-	productFound, err := api.storage.Delete(id)
+	expected, err := ifMatchVersion(req, api.storage, id)
+	if err != nil {
+		log.Errorf("Failed to check If-Match, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	var productFound bool
+	if expected != nil {
+		productFound, err = api.storage.DeleteIfVersion(id, *expected)
+	} else {
+		productFound, err = api.storage.Delete(id)
+	}
 
 	if err != nil {
-		log.Errorf("Failed to find product in database: %v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("read error: %v", err))
+		log.Errorf("Failed to delete product %v in database: %v", id, err)
+		writeProblem(req, resp, statusOf(err), err)
 		return
 	}
 
 	if !productFound {
-		log.Errorf("Failed to find product in database: %v", err)
-		resp.WriteError(http.StatusConflict, fmt.Errorf("read error: %v", err))
+		log.Errorf("Product %v not found in database", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
 		return
 	}
 
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"exam-store/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// failingStorage fails every save and get with err
+type failingStorage struct {
+	domain.Storage
+
+	err error
+}
+
+func (s failingStorage) Save(domain.Product) (string, bool, error) {
+	return "", false, s.err
+}
+
+func (s failingStorage) Get(string) (domain.Product, bool, error) {
+	return domain.Product{}, false, s.err
+}
+
+func TestSingleProductHandlersKeepTheStorageErrorCode(t *testing.T) {
+	unavailable := domain.NewError(domain.ErrUnavailable, "database_down", "database is down")
+	ids, err := domain.NewIDGenerator(domain.DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	api := NewAPI(failingStorage{err: unavailable}, domain.ExchangeRates{}, ids, nil, 0)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		handler restful.RouteFunction
+	}{
+		{"create", httptest.NewRequest(http.MethodPost, "/store/product", strings.NewReader(`{"name": "Laptop", "manufacturer": "Acme"}`)), api.createProductSingle},
+		{"get", httptest.NewRequest(http.MethodGet, "/store/product?id=laptop", nil), api.getProductSingle},
+	}
+	for _, tt := range tests {
+		tt.request.Header.Set("Content-Type", restful.MIME_JSON)
+		w := httptest.NewRecorder()
+		tt.handler(restful.NewRequest(tt.request), restful.NewResponse(w))
+
+		problem := domain.Problem{}
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decoding problem: %v", tt.name, err)
+		}
+		if w.Code != http.StatusServiceUnavailable || problem.Code != "database_down" || !errors.Is(problem.Err(), domain.ErrUnavailable) {
+			t.Errorf("%s answered %d with %+v, want 503 database_down", tt.name, w.Code, problem)
+		}
+	}
+}
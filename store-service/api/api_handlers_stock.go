@@ -0,0 +1,140 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) adjustStockSingle(req *restful.Request, resp *restful.Response) {
+	adjustment := domain.StockAdjustment{}
+	err := req.ReadEntity(&adjustment)
+	if err != nil {
+		log.Errorf("Failed to read stock adjustment, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if adjustment.ID == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	if err := adjustment.Validate(); err != nil {
+		log.Errorf("Failed to validate stock adjustment, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	product, isProductThere, err := api.storage.AdjustStock(adjustment)
+	if err != nil {
+		log.Errorf("Failed to adjust stock of product %v by %v, err=%v", adjustment.ID, adjustment.Delta, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.AddHeader("ETag", etag(product.Version, ""))
+	resp.WriteAsJson(product)
+	log.Infof("Stock of product %v adjusted by %v", adjustment.ID, adjustment.Delta)
+}
+
+func (api *API) adjustStockBatch(req *restful.Request, resp *restful.Response) {
+	adjustments, ok := readBatch[domain.StockAdjustment](req, resp)
+	if !ok {
+		return
+	}
+
+	results, err := api.applyBatch(isAtomic(req), func(storage domain.Storage) ([]domain.BatchItemResult, error) {
+		return stockResults(storage, adjustments)
+	})
+	if err != nil {
+		log.Errorf("Failed to adjust stock batch, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	writeBatchResults(resp, results)
+	log.Infof("Batch of %d stock adjustments processed", len(adjustments))
+}
+
+func stockResults(storage domain.Storage, adjustments []domain.StockAdjustment) ([]domain.BatchItemResult, error) {
+	warehouses, err := storage.ListWarehouses()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(warehouses))
+	for _, warehouse := range warehouses {
+		known[warehouse.ID] = true
+	}
+
+	// a statement updates each row at most once, so an id may only appear once per batch
+	results := make([]domain.BatchItemResult, len(adjustments))
+	seen := make(map[string]bool, len(adjustments))
+	valid := make([]domain.StockAdjustment, 0, len(adjustments))
+	for i, adjustment := range adjustments {
+		switch {
+		case adjustment.ID == "":
+			results[i] = domain.BatchItemResult{Index: i, Status: http.StatusBadRequest, Error: "id must be provided"}
+		case seen[adjustment.ID]:
+			results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusBadRequest, Error: "duplicate id in batch"}
+		case adjustment.Validate() != nil:
+			results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusBadRequest, Error: adjustment.Validate().Error()}
+		case !known[adjustment.WarehouseID()]:
+			results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusUnprocessableEntity, Error: "warehouse not found"}
+		default:
+			seen[adjustment.ID] = true
+			valid = append(valid, adjustment)
+		}
+	}
+
+	var adjusted map[string]domain.Product
+	if len(valid) > 0 {
+		var err error
+		adjusted, err = storage.AdjustStockBatch(valid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// an adjustment that was not applied either targets a missing product or one short on stock
+	var missed []string
+	for _, adjustment := range valid {
+		if _, ok := adjusted[adjustment.ID]; !ok {
+			missed = append(missed, adjustment.ID)
+		}
+	}
+	var existing map[string]domain.Product
+	if len(missed) > 0 {
+		var err error
+		existing, err = storage.GetBatch(missed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, adjustment := range adjustments {
+		if results[i].Status != 0 {
+			continue
+		}
+		if product, ok := adjusted[adjustment.ID]; ok {
+			results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusOK, Product: &product}
+			continue
+		}
+		if _, ok := existing[adjustment.ID]; ok {
+			results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusConflict, Error: "insufficient stock"}
+			continue
+		}
+		results[i] = domain.BatchItemResult{Index: i, ID: adjustment.ID, Status: http.StatusNotFound, Error: "product not found"}
+	}
+	return results, nil
+}
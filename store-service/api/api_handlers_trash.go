@@ -0,0 +1,103 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) listTrash(req *restful.Request, resp *restful.Response) {
+	products, err := api.storage.Trash()
+	if err != nil {
+		log.Errorf("Failed to list trash, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	resp.WriteAsJson(products)
+	log.Infof("Trash listed, %d products", len(products))
+}
+
+func (api *API) restoreProduct(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	isProductThere, err := api.storage.Restore(id)
+	if err != nil {
+		log.Errorf("Failed to restore product, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not in trash")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not in trash"))
+		return
+	}
+
+	resp.WriteAsJson("Product " + id + " restored from trash")
+	log.Infof("Product %v restored from trash", id)
+}
+
+// purgeTrash removes the product named by id from the trash for good,
+// or every product deleted before the RFC 3339 time in before
+func (api *API) purgeTrash(req *restful.Request, resp *restful.Response) {
+	id, before := req.QueryParameter("id"), req.QueryParameter("before")
+	switch {
+	case id != "" && before != "":
+		log.Errorf("Both id and before provided")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("only one of id and before may be provided"))
+	case id != "":
+		api.purgeProduct(req, resp, id)
+	case before != "":
+		api.purgeProductsBefore(req, resp, before)
+	default:
+		log.Errorf("Neither id nor before provided")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id or before must be provided"))
+	}
+}
+
+func (api *API) purgeProduct(req *restful.Request, resp *restful.Response, id string) {
+	isProductThere, err := api.storage.Purge(id)
+	if err != nil {
+		log.Errorf("Failed to purge product, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not in trash")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not in trash"))
+		return
+	}
+
+	resp.WriteAsJson("Product " + id + " purged from trash")
+	log.Infof("Product %v purged from trash", id)
+}
+
+func (api *API) purgeProductsBefore(req *restful.Request, resp *restful.Response, before string) {
+	at, err := time.Parse(time.RFC3339Nano, before)
+	if err != nil {
+		log.Errorf("Failed to read before %q", before)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("before must be an RFC 3339 timestamp"))
+		return
+	}
+
+	purged, err := api.storage.PurgeTrash(at)
+	if err != nil {
+		log.Errorf("Failed to purge trash, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	resp.WriteAsJson(domain.PurgeResult{Purged: purged})
+	log.Infof("Purged %d products deleted before %v", purged, before)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+func (api *API) createWarehouse(req *restful.Request, resp *restful.Response) {
+	warehouse := domain.Warehouse{}
+	err := req.ReadEntity(&warehouse)
+	if err != nil {
+		log.Errorf("Failed to read warehouse, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := warehouse.Validate(); err != nil {
+		log.Errorf("Failed to validate warehouse, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	isWarehouseThere, err := api.storage.CreateWarehouse(warehouse)
+	if err != nil {
+		log.Errorf("Failed to create warehouse, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if isWarehouseThere {
+		log.Errorf("Warehouse %v already in database", warehouse.ID)
+		writeProblem(req, resp, http.StatusConflict, domain.NewError(domain.ErrAlreadyExists, "", "warehouse already exists"))
+		return
+	}
+
+	resp.AddHeader("Location", req.Request.URL.Path+"/"+warehouse.ID)
+	_ = resp.WriteHeaderAndJson(http.StatusCreated, warehouse, restful.MIME_JSON)
+	log.Infof("Warehouse %v created", warehouse.ID)
+}
+
+func (api *API) getWarehouse(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	warehouse, isWarehouseThere, err := api.storage.GetWarehouse(id)
+	if err != nil {
+		log.Errorf("Failed to get warehouse, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isWarehouseThere {
+		log.Errorf("Warehouse %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("warehouse not found"))
+		return
+	}
+
+	resp.WriteAsJson(warehouse)
+	log.Infof("Warehouse %v got", id)
+}
+
+func (api *API) listWarehouses(req *restful.Request, resp *restful.Response) {
+	warehouses, err := api.storage.ListWarehouses()
+	if err != nil {
+		log.Errorf("Failed to list warehouses, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	resp.WriteAsJson(warehouses)
+	log.Infof("%d warehouses listed", len(warehouses))
+}
+
+func (api *API) warehouseStock(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	levels, isWarehouseThere, err := api.storage.WarehouseStock(id)
+	if err != nil {
+		log.Errorf("Failed to get warehouse stock, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isWarehouseThere {
+		log.Errorf("Warehouse %v not found", id)
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("warehouse not found"))
+		return
+	}
+
+	resp.WriteAsJson(levels)
+	log.Infof("Stock of warehouse %v got", id)
+}
+
+func (api *API) stockLevels(req *restful.Request, resp *restful.Response) {
+	id := req.QueryParameter("id")
+	if id == "" {
+		log.Errorf("Failed to read id")
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("id must be provided"))
+		return
+	}
+
+	levels, isProductThere, err := api.storage.StockLevels(id)
+	if err != nil {
+		log.Errorf("Failed to get stock levels, err=%v", err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.WriteAsJson(levels)
+	log.Infof("Stock levels of product %v got", id)
+}
+
+func (api *API) transferStock(req *restful.Request, resp *restful.Response) {
+	transfer := domain.StockTransfer{}
+	err := req.ReadEntity(&transfer)
+	if err != nil {
+		log.Errorf("Failed to read stock transfer, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("read error: %v", err))
+		return
+	}
+
+	if err := transfer.Validate(); err != nil {
+		log.Errorf("Failed to validate stock transfer, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+
+	levels, isProductThere, err := api.storage.TransferStock(transfer)
+	if err != nil {
+		log.Errorf("Failed to transfer %v of product %v from %v to %v, err=%v", transfer.Quantity, transfer.ID, transfer.From, transfer.To, err)
+		writeProblem(req, resp, statusOf(err), err)
+		return
+	}
+
+	if !isProductThere {
+		log.Errorf("Product not found in database")
+		writeProblem(req, resp, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	resp.WriteAsJson(levels)
+	log.Infof("Moved %v of product %v from warehouse %v to %v", transfer.Quantity, transfer.ID, transfer.From, transfer.To)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"exam-store/domain"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// readCurrency reads the currency prices are to be converted to from ?currency=,
+// which is empty when they are left in the currency they are stored in.
+// On failure it writes a 400 response and returns false.
+func readCurrency(req *restful.Request, resp *restful.Response) (string, bool) {
+	currency := req.QueryParameter("currency")
+	if err := domain.ValidateCurrency(currency); err != nil {
+		log.Errorf("Failed to read currency, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return "", false
+	}
+	return currency, true
+}
+
+// convertPrices converts the price of every product to currency using the configured exchange rates.
+// On failure it writes a 422 response and returns false.
+func (api *API) convertPrices(req *restful.Request, resp *restful.Response, currency string, products ...*domain.Product) bool {
+	if currency == "" {
+		return true
+	}
+
+	for _, product := range products {
+		converted, err := api.rates.ConvertProduct(*product, currency)
+		if err != nil {
+			log.Errorf("Failed to convert price to %v, err=%v", currency, err)
+			writeProblem(req, resp, http.StatusUnprocessableEntity, err)
+			return false
+		}
+		*product = converted
+	}
+	return true
+}
+
+// convertPricePoints converts every price of a price history to currency like convertPrices does for products.
+// On failure it writes a 422 response and returns false.
+func (api *API) convertPricePoints(req *restful.Request, resp *restful.Response, currency string, points []domain.PricePoint) bool {
+	if currency == "" {
+		return true
+	}
+
+	for i := range points {
+		converted, err := api.rates.ConvertPricePoint(points[i], currency)
+		if err != nil {
+			log.Errorf("Failed to convert price to %v, err=%v", currency, err)
+			writeProblem(req, resp, http.StatusUnprocessableEntity, err)
+			return false
+		}
+		points[i] = converted
+	}
+	return true
+}
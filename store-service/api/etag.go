@@ -0,0 +1,79 @@
+package api
+
+import (
+	"exam-store/domain"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// errInvalidIfMatch is returned for an If-Match header that is not a list of product versions
+var errInvalidIfMatch = domain.NewError(domain.ErrValidation, "invalid_if_match", "If-Match must hold product versions")
+
+// etag renders a product version as a strong entity tag. A product whose price was converted to currency
+// is another representation of the same version, so it gets a tag of its own that If-Match still reads as the version.
+func etag(version int64, currency string) string {
+	tag := strconv.FormatInt(version, 10)
+	if currency != "" {
+		tag += "-" + currency
+	}
+	return strconv.Quote(tag)
+}
+
+// parseIfMatch reads the versions accepted by an If-Match header, a comma separated list of entity tags.
+// It returns nil if the header is absent or matches any version.
+// Tags are compared strongly, so weak tags never match and a header holding only weak tags fails with domain.ErrVersionMismatch.
+func parseIfMatch(header string) ([]int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := make([]int64, 0)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return nil, errInvalidIfMatch
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return nil, domain.ErrVersionMismatch
+	}
+	return versions, nil
+}
+
+// ifMatchVersion returns the version a conditional write of the product id expects after the If-Match header of req,
+// nil if any version will do. Out of several versions it picks the one the product is at now, which the write checks again.
+// A product that is not there is left for the write to report.
+func ifMatchVersion(req *restful.Request, storage domain.Storage, id string) (*int64, error) {
+	versions, err := parseIfMatch(req.HeaderParameter("If-Match"))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(versions) == 0:
+		return nil, nil
+	case len(versions) == 1:
+		return &versions[0], nil
+	}
+
+	product, exists, err := storage.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &versions[0], nil
+	}
+	for _, version := range versions {
+		if version == product.Version {
+			return &version, nil
+		}
+	}
+	return nil, domain.ErrVersionMismatch
+}
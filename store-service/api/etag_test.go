@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"exam-store/domain"
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int64
+		err    error
+	}{
+		{header: "", want: nil},
+		{header: "*", want: nil},
+		{header: `"3"`, want: []int64{3}},
+		{header: `"3", "5"`, want: []int64{3, 5}},
+		{header: `"3-EUR"`, want: []int64{3}},
+		{header: `W/"3", "5"`, want: []int64{5}},
+		{header: `W/"3"`, err: domain.ErrVersionMismatch},
+		{header: `"three"`, err: errInvalidIfMatch},
+	}
+	for _, tt := range tests {
+		got, err := parseIfMatch(tt.header)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseIfMatch(%q) error = %v, want %v", tt.header, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIfMatch(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestETagTellsConvertedRepresentationsApart(t *testing.T) {
+	stored, converted := etag(3, ""), etag(3, "EUR")
+	if stored == converted {
+		t.Fatalf("etag of the stored and converted product are both %s", stored)
+	}
+
+	versions, err := parseIfMatch(converted)
+	if err != nil || !reflect.DeepEqual(versions, []int64{3}) {
+		t.Errorf("parseIfMatch(%s) = %v, %v, want the version it was taken from", converted, versions, err)
+	}
+}
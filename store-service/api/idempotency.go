@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"exam-store/domain"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+// replayedHeader marks a response replayed for a repeated Idempotency-Key
+const replayedHeader = "Idempotent-Replayed"
+
+// idempotent is a filter making writes that carry an Idempotency-Key safe to retry. The first response
+// to a key is stored and replayed to repeats of the same request, a key sent with another request
+// answers 422 and one whose request is still running 409. Server errors and panics are not stored, so they can be retried.
+func (api *API) idempotent(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	key := req.HeaderParameter(domain.IdempotencyKeyHeader)
+	if key == "" || !isWrite(req.Request.Method) {
+		chain.ProcessFilter(req, resp)
+		return
+	}
+
+	if len(key) > domain.MaxIdempotencyKeyLength {
+		log.Infof("Idempotency key of %d characters in request", len(key))
+		writeProblem(req, resp, http.StatusBadRequest, fmt.Errorf("idempotency key must be at most %d characters", domain.MaxIdempotencyKeyLength))
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		log.Errorf("Failed to read request body, err=%v", err)
+		writeProblem(req, resp, http.StatusBadRequest, err)
+		return
+	}
+	req.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	fingerprint := domain.RequestFingerprint(req.Request.Method, req.Request.URL.Path, req.Request.URL.RawQuery,
+		req.HeaderParameter("If-Match"), body)
+	stored, err := api.keys.Begin(key, fingerprint, api.keyTTL)
+	if err != nil {
+		writeStorageError(req, resp, err, "failed to claim idempotency key")
+		return
+	}
+
+	if stored != nil {
+		log.Infof("Replaying response to idempotency key %s", key)
+		replay(resp, *stored)
+		return
+	}
+
+	// a handler that panics leaves no response to store, the key is released before the panic goes on
+	defer func() {
+		if r := recover(); r != nil {
+			api.releaseKey(key)
+			panic(r)
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: resp.ResponseWriter}
+	resp.ResponseWriter = recorder
+	chain.ProcessFilter(req, resp)
+
+	if recorder.status >= http.StatusInternalServerError {
+		api.releaseKey(key)
+		return
+	}
+	if err := api.keys.Complete(key, recorder.response()); err != nil {
+		log.Errorf("Failed to store response to idempotency key, err=%v", err)
+	}
+}
+
+// releaseKey lets the key be claimed again by a retry of a request that got no response worth storing
+func (api *API) releaseKey(key string) {
+	if err := api.keys.Release(key); err != nil {
+		log.Errorf("Failed to release idempotency key, err=%v", err)
+	}
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// replay writes a stored response as it was first given
+func replay(resp *restful.Response, stored domain.IdempotentResponse) {
+	for name, values := range stored.Header {
+		resp.Header()[name] = values
+	}
+	resp.Header().Set(replayedHeader, "true")
+	resp.WriteHeader(stored.Status)
+	_, _ = resp.Write(stored.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) response() domain.IdempotentResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return domain.IdempotentResponse{Status: status, Header: r.Header().Clone(), Body: r.body.Bytes()}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	apidomain "exam-api/domain"
+	"exam-store/domain"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// mapKeys keeps claimed idempotency keys in a map, failing every call with err if it is set
+type mapKeys struct {
+	mu           sync.Mutex
+	fingerprints map[string]string
+	responses    map[string]domain.IdempotentResponse
+	err          error
+}
+
+func newMapKeys() *mapKeys {
+	return &mapKeys{fingerprints: make(map[string]string), responses: make(map[string]domain.IdempotentResponse)}
+}
+
+func (k *mapKeys) Begin(key, fingerprint string, _ time.Duration) (*domain.IdempotentResponse, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.err != nil {
+		return nil, k.err
+	}
+	claimed, ok := k.fingerprints[key]
+	if !ok {
+		k.fingerprints[key] = fingerprint
+		return nil, nil
+	}
+	if claimed != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	response, ok := k.responses[key]
+	if !ok {
+		return nil, domain.ErrIdempotencyKeyInProgress
+	}
+	return &response, nil
+}
+
+func (k *mapKeys) Complete(key string, response domain.IdempotentResponse) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.responses[key] = response
+	return nil
+}
+
+func (k *mapKeys) Release(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.responses[key]; !ok {
+		delete(k.fingerprints, key)
+	}
+	return nil
+}
+
+func (k *mapKeys) ExpireIdempotencyKeys(time.Time) (int, error) { return 0, nil }
+
+// newIdempotentContainer serves handler behind the idempotency filter at POST /product
+func newIdempotentContainer(keys domain.IdempotencyStore, handler restful.RouteFunction) *restful.Container {
+	api := &API{keys: keys, keyTTL: time.Minute}
+
+	ws := new(restful.WebService)
+	ws.Filter(api.idempotent)
+	ws.Route(ws.POST("/product").To(handler))
+
+	container := restful.NewContainer()
+	container.Add(ws)
+	return container
+}
+
+// serveKeyed sends a POST /product with the idempotency key and If-Match header,
+// answering 500 like the server would if the handler panics
+func serveKeyed(container *restful.Container, key, ifMatch string) (status int) {
+	r := httptest.NewRequest(http.MethodPost, "/product", nil)
+	r.Header.Set(domain.IdempotencyKeyHeader, key)
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+
+	defer func() {
+		if recover() != nil {
+			status = http.StatusInternalServerError
+		}
+	}()
+	container.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestIdempotentReleasesTheKeyOfAHandlerThatPanics(t *testing.T) {
+	calls := 0
+	container := newIdempotentContainer(newMapKeys(), func(req *restful.Request, resp *restful.Response) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		resp.WriteHeader(http.StatusCreated)
+	})
+
+	if status := serveKeyed(container, "retry", ""); status != http.StatusInternalServerError {
+		t.Fatalf("first request answered %d, want the panic", status)
+	}
+	if status := serveKeyed(container, "retry", ""); status != http.StatusCreated {
+		t.Errorf("retry answered %d, want %d as the key was released", status, http.StatusCreated)
+	}
+}
+
+func TestIdempotentTellsIfMatchApart(t *testing.T) {
+	container := newIdempotentContainer(newMapKeys(), func(req *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusNoContent)
+	})
+
+	if status := serveKeyed(container, "delete", `"3"`); status != http.StatusNoContent {
+		t.Fatalf("first request answered %d, want %d", status, http.StatusNoContent)
+	}
+	if status := serveKeyed(container, "delete", `"3"`); status != http.StatusNoContent {
+		t.Errorf("repeat answered %d, want the response replayed", status)
+	}
+	if status := serveKeyed(container, "delete", `"4"`); status != http.StatusUnprocessableEntity {
+		t.Errorf("request with another If-Match answered %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotentAnswersAFailingKeyStoreWithoutCallingTheHandler(t *testing.T) {
+	keys := newMapKeys()
+	keys.err = errors.New("database is down")
+	called := false
+	container := newIdempotentContainer(keys, func(req *restful.Request, resp *restful.Response) {
+		called = true
+	})
+
+	if status := serveKeyed(container, "create", ""); status != http.StatusInternalServerError {
+		t.Errorf("request answered %d, want %d", status, http.StatusInternalServerError)
+	}
+	if called {
+		t.Error("handler ran without the key claimed")
+	}
+}
+
+// Both services fingerprint requests alike, so a key is told apart the same way whichever one it reaches
+func TestRequestFingerprintMatchesTheAPIService(t *testing.T) {
+	tests := []struct {
+		method, path, query, ifMatch, body string
+	}{
+		{method: http.MethodPost, path: "/store/product", body: `{"name": "Laptop"}`},
+		{method: http.MethodDelete, path: "/store/product", query: "id=laptop", ifMatch: `"3"`},
+		{method: http.MethodPatch, path: "/store/product", query: "id=laptop&x=1"},
+	}
+	for _, tt := range tests {
+		got := domain.RequestFingerprint(tt.method, tt.path, tt.query, tt.ifMatch, []byte(tt.body))
+		want := apidomain.RequestFingerprint(tt.method, tt.path, tt.query, tt.ifMatch, []byte(tt.body))
+		if got != want {
+			t.Errorf("%s %s?%s fingerprinted %s, want %s like the api service", tt.method, tt.path, tt.query, got, want)
+		}
+	}
+}
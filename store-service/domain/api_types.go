@@ -3,29 +3,113 @@ package domain
 import (
 	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxTextLength is the length of the name, manufacturer and tag columns
+const MaxTextLength = 64
+
+var (
+	// ErrNegativePrice is returned for a product or patch with a price below zero
+	ErrNegativePrice = NewError(ErrValidation, "negative_price", "price must not be negative")
+	// ErrNegativeStock is returned for a product or patch with a stock below zero
+	ErrNegativeStock = NewError(ErrValidation, "negative_stock", "stock must not be negative")
+	// ErrTextTooLong is returned for a name, manufacturer or tag longer than the columns holding them
+	ErrTextTooLong = NewError(ErrValidation, "text_too_long", fmt.Sprintf("must be at most %d characters", MaxTextLength))
 )
 
 // Product is the API representation of a product object
 type Product struct {
-	Name         string   `json:"name"`
-	Manufacturer string   `json:"manufacturer"`
-	Price        int      `json:"price"`
-	Stock        int      `json:"stock"`
-	Tags         []string `json:"tags"`
+	Name         string `json:"name"`
+	Manufacturer string `json:"manufacturer"`
+	// Price is in the minor units of Currency, an ISO 4217 code that is DefaultCurrency if left out on create
+	Price    int      `json:"price"`
+	Currency string   `json:"currency"`
+	Stock    int      `json:"stock"`
+	Tags     []string `json:"tags"`
+	// Version starts at 1 and is incremented by every update
+	Version int64 `json:"version"`
+	// Reserved is the stock held by pending reservations and Available is what is left of
+	// the stock besides it. Both are maintained by the storage and ignored on writes.
+	Reserved  int `json:"reserved"`
+	Available int `json:"available"`
+	// SKU is the caller's identifier of the product. It is only read on create,
+	// and is the id of the product when ids are assigned by the sku strategy.
+	SKU string `json:"sku,omitempty"`
 }
 
+// ProductDiff is a partial update of the product with the given id
 type ProductDiff struct {
-	ID   string `json:"id"`
-	Diff struct {
-		Price int      `json:"price"`
-		Stock int      `json:"stock"`
-		Tags  []string `json:"tags"`
-	} `json:"diff"`
+	ID string `json:"id"`
+	// Version, when set, is the version the caller expects the product to be at
+	Version *int64       `json:"version,omitempty"`
+	Diff    ProductPatch `json:"diff"`
 }
 
-// GetHash returns a sha1 value over the name and manufacturer fields of a Product
+// GetHash returns a sha1 value over the name and manufacturer fields of a Product.
+// It is the id under the sha1 strategy, which gives ("ab", "c") and ("a", "bc") the same id.
 func (p *Product) GetHash() string {
 	h := sha1.New()
 	h.Write([]byte(p.Name + p.Manufacturer))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// Validate checks the fields of a product given on create, returning a message fit for the caller.
+// When its id is derived from them, they must be enough for ids to tell it.
+func (p *Product) Validate(ids IDGenerator) error {
+	if err := validateTexts(p.Name, p.Manufacturer, p.Tags); err != nil {
+		return err
+	}
+	if ids.Derived() {
+		if _, err := ids.NewID(*p); err != nil {
+			return err
+		}
+	} else if p.SKU != "" {
+		if err := ValidateSKU(p.SKU); err != nil {
+			return err
+		}
+	}
+	if err := validateAmounts(p.Price, p.Stock); err != nil {
+		return err
+	}
+	return ValidateCurrency(p.Currency)
+}
+
+// validateTexts checks the name, manufacturer and tags a product is given fit their columns,
+// so a product is turned down before it is queued rather than failing in the store
+func validateTexts(name, manufacturer string, tags []string) error {
+	switch {
+	case utf8.RuneCountInString(name) > MaxTextLength:
+		return fmt.Errorf("name %w", ErrTextTooLong)
+	case utf8.RuneCountInString(manufacturer) > MaxTextLength:
+		return fmt.Errorf("manufacturer %w", ErrTextTooLong)
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > MaxTextLength {
+			return fmt.Errorf("tag %w", ErrTextTooLong)
+		}
+	}
+	return nil
+}
+
+// validateAmounts checks the price and stock a product is given, on create or by a patch
+func validateAmounts(price, stock int) error {
+	switch {
+	case price < 0:
+		return ErrNegativePrice
+	case stock < 0:
+		return ErrNegativeStock
+	}
+	return nil
+}
+
+// CurrencyCode returns the currency of the price, DefaultCurrency if none was given
+func (p *Product) CurrencyCode() string {
+	return currencyOrDefault(p.Currency)
+}
+
+// PriceMoney returns the price of the product along with its currency
+func (p *Product) PriceMoney() Money {
+	return Money{Amount: p.Price, Currency: p.CurrencyCode()}
+}
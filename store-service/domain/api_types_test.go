@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProductValidateChecksTheColumnLengths(t *testing.T) {
+	ids, err := NewIDGenerator(DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	long := strings.Repeat("a", MaxTextLength+1)
+	fits := strings.Repeat("ă", MaxTextLength)
+
+	tests := []struct {
+		name    string
+		product Product
+		valid   bool
+	}{
+		{"fits", Product{Name: fits, Manufacturer: fits, Tags: []string{fits}}, true},
+		{"name", Product{Name: long, Manufacturer: "Acme"}, false},
+		{"manufacturer", Product{Name: "Laptop", Manufacturer: long}, false},
+		{"tag", Product{Name: "Laptop", Manufacturer: "Acme", Tags: []string{"computers", long}}, false},
+	}
+	for _, tt := range tests {
+		err := tt.product.Validate(ids)
+		if tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrValidation)) {
+			t.Errorf("%s: Validate = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	if err := (ProductPatch{Tags: Value([]string{long})}).Validate(); !errors.Is(err, ErrTextTooLong) {
+		t.Errorf("Validate of a patch with a long tag = %v, want %v", err, ErrTextTooLong)
+	}
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+)
+
+// BatchItemResult is the outcome of a single item of a batch.
+// Status holds the HTTP status code the item would have had as a single request.
+type BatchItemResult struct {
+	Index   int      `json:"index"`
+	ID      string   `json:"id,omitempty"`
+	Status  int      `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Product *Product `json:"product,omitempty"`
+}
+
+// SavedProduct is the outcome of a product of a create batch, the id it is stored under
+// and whether the batch created it. Err is set, and ID empty, if the product could not be given one.
+type SavedProduct struct {
+	ID      string
+	Created bool
+	Err     error
+}
+
+// Succeeded reports whether the item was applied
+func (r BatchItemResult) Succeeded() bool {
+	return r.Status < 400
+}
+
+// CreateResults saves products in a single batch and returns the outcome of every product,
+// with the created products as they were stored
+func CreateResults(storage Storage, products []Product) ([]BatchItemResult, error) {
+	saved, err := storage.SaveBatch(products)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdIDs []string
+	for _, s := range saved {
+		if s.Created {
+			createdIDs = append(createdIDs, s.ID)
+		}
+	}
+	var stored map[string]Product
+	if len(createdIDs) > 0 {
+		stored, err = storage.GetBatch(createdIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BatchItemResult, len(products))
+	for i := range products {
+		id := saved[i].ID
+		if err := saved[i].Err; err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrValidation) {
+				status = http.StatusBadRequest
+			}
+			results[i] = BatchItemResult{Index: i, Status: status, Error: err.Error()}
+			continue
+		}
+		if !saved[i].Created {
+			results[i] = BatchItemResult{Index: i, ID: id, Status: http.StatusConflict, Error: "already in database"}
+			continue
+		}
+
+		results[i] = BatchItemResult{Index: i, ID: id, Status: http.StatusCreated}
+		if product, ok := stored[id]; ok {
+			results[i].Product = &product
+		}
+	}
+	return results, nil
+}
+
+// UpdateResults applies the diffs in a single batch and returns the outcome of every diff,
+// telling apart why those that were not applied were not
+func UpdateResults(storage Storage, productDiffs []ProductDiff) ([]BatchItemResult, error) {
+	results := make([]BatchItemResult, len(productDiffs))
+	seen := make(map[string]bool, len(productDiffs))
+	valid := make([]ProductDiff, 0, len(productDiffs))
+	for i, productDiff := range productDiffs {
+		invalid := productDiff.Diff.Validate()
+		switch {
+		case productDiff.ID == "":
+			results[i] = BatchItemResult{Index: i, Status: http.StatusBadRequest, Error: "id must be provided"}
+		case seen[productDiff.ID]:
+			results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusBadRequest, Error: "duplicate id in batch"}
+		case invalid != nil:
+			results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusBadRequest, Error: invalid.Error()}
+		default:
+			seen[productDiff.ID] = true
+			valid = append(valid, productDiff)
+		}
+	}
+
+	var updated map[string]bool
+	if len(valid) > 0 {
+		ids, err := storage.UpdateBatch(valid)
+		if err != nil {
+			return nil, err
+		}
+		updated = toSet(ids)
+	}
+
+	// a diff that was not applied targets a missing product, a stale version
+	// or would leave main with less stock than it has reserved
+	var missed []string
+	for _, productDiff := range valid {
+		if !updated[productDiff.ID] {
+			missed = append(missed, productDiff.ID)
+		}
+	}
+	var existing map[string]Product
+	if len(missed) > 0 {
+		var err error
+		existing, err = storage.GetBatch(missed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, productDiff := range productDiffs {
+		if results[i].Status != 0 {
+			continue
+		}
+		if updated[productDiff.ID] {
+			results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusOK}
+			continue
+		}
+		current, ok := existing[productDiff.ID]
+		if !ok {
+			results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusNotFound, Error: "product not found"}
+			continue
+		}
+		if productDiff.Version != nil && *productDiff.Version != current.Version {
+			results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusPreconditionFailed, Error: "version mismatch"}
+			continue
+		}
+		results[i] = BatchItemResult{Index: i, ID: productDiff.ID, Status: http.StatusConflict, Error: "insufficient stock"}
+	}
+	return results, nil
+}
+
+// DeleteResults deletes the products in a single batch and returns the outcome of every id
+func DeleteResults(storage Storage, ids []string) ([]BatchItemResult, error) {
+	deletedIDs, err := storage.DeleteBatch(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := toSet(deletedIDs)
+	results := make([]BatchItemResult, len(ids))
+	for i, id := range ids {
+		if !deleted[id] {
+			results[i] = BatchItemResult{Index: i, ID: id, Status: http.StatusNotFound, Error: "product not found"}
+			continue
+		}
+
+		// a repeated id is reported as deleted only once
+		delete(deleted, id)
+		results[i] = BatchItemResult{Index: i, ID: id, Status: http.StatusOK}
+	}
+	return results, nil
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
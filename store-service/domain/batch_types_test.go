@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// savingStorage answers a create batch with saved and reads the products back from stored
+type savingStorage struct {
+	Storage
+
+	saved  []SavedProduct
+	stored map[string]Product
+}
+
+func (s savingStorage) SaveBatch([]Product) ([]SavedProduct, error) {
+	return s.saved, nil
+}
+
+func (s savingStorage) GetBatch(ids []string) (map[string]Product, error) {
+	products := make(map[string]Product, len(ids))
+	for _, id := range ids {
+		if product, ok := s.stored[id]; ok {
+			products[id] = product
+		}
+	}
+	return products, nil
+}
+
+func TestCreateResultsReportsEveryItem(t *testing.T) {
+	storage := savingStorage{
+		saved: []SavedProduct{
+			{ID: "laptop", Created: true},
+			{ID: "mouse"},
+			{Err: ErrInvalidSKU},
+			{Err: errors.New("entropy source failed")},
+		},
+		stored: map[string]Product{
+			"laptop": {Name: "Laptop", Manufacturer: "Acme", Price: 499999, Currency: "USD", Version: 1},
+		},
+	}
+	products := []Product{
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999},
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950},
+		{Name: "Keyboard", Manufacturer: "Acme", SKU: "not a sku"},
+		{Name: "Monitor", Manufacturer: "Acme"},
+	}
+
+	results, err := CreateResults(storage, products)
+	if err != nil {
+		t.Fatalf("CreateResults: %v", err)
+	}
+
+	want := []struct {
+		id     string
+		status int
+	}{
+		{"laptop", http.StatusCreated},
+		{"mouse", http.StatusConflict},
+		{"", http.StatusBadRequest},
+		{"", http.StatusInternalServerError},
+	}
+	for i, w := range want {
+		if results[i].Index != i || results[i].ID != w.id || results[i].Status != w.status {
+			t.Errorf("result %d = %+v, want id %q and status %d", i, results[i], w.id, w.status)
+		}
+	}
+	if p := results[0].Product; p == nil || p.Version != 1 || p.Currency != "USD" {
+		t.Errorf("created product %+v, want it as it was stored", p)
+	}
+}
+
+// updatingStorage applies every diff it is given
+type updatingStorage struct {
+	Storage
+}
+
+func (updatingStorage) UpdateBatch(diffs []ProductDiff) ([]string, error) {
+	ids := make([]string, len(diffs))
+	for i := range diffs {
+		ids[i] = diffs[i].ID
+	}
+	return ids, nil
+}
+
+func TestUpdateResultsRejectsNegativeAmounts(t *testing.T) {
+	results, err := UpdateResults(updatingStorage{}, []ProductDiff{
+		{ID: "laptop", Diff: ProductPatch{Price: Value(-1)}},
+		{ID: "mouse", Diff: ProductPatch{Stock: Value(-5)}},
+		{ID: "keyboard", Diff: ProductPatch{Price: Value(2999), Stock: Value(0)}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateResults: %v", err)
+	}
+
+	for i, want := range []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusOK} {
+		if results[i].Status != want {
+			t.Errorf("result %d = %+v, want status %d", i, results[i], want)
+		}
+	}
+}
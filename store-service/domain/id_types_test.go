@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultIDStrategyKeepsSHA1IDs(t *testing.T) {
+	ids, err := NewIDGenerator(DefaultIDStrategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+
+	product := Product{Name: "Laptop", Manufacturer: "Acme"}
+	if id, _ := ids.NewID(product); id != product.GetHash() {
+		t.Errorf("default strategy gives id %q, want the sha1 id %q", id, product.GetHash())
+	}
+}
+
+func mustIDGenerator(t *testing.T, strategy string) IDGenerator {
+	t.Helper()
+
+	ids, err := NewIDGenerator(strategy)
+	if err != nil {
+		t.Fatalf("NewIDGenerator(%s): %v", strategy, err)
+	}
+	return ids
+}
+
+func TestSHA256IDs(t *testing.T) {
+	ids := mustIDGenerator(t, IDStrategySHA256)
+	id := func(name, manufacturer string) string {
+		t.Helper()
+		id, err := ids.NewID(Product{Name: name, Manufacturer: manufacturer})
+		if err != nil {
+			t.Fatalf("NewID: %v", err)
+		}
+		return id
+	}
+
+	laptop := id("Laptop", "Acme")
+	if !regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(laptop) {
+		t.Errorf("id %q is not 64 hex digits", laptop)
+	}
+	if other := id("  LAPTOP ", "acme"); other != laptop {
+		t.Errorf("spellings told apart by case and white space get %q and %q", laptop, other)
+	}
+	if id("ab", "c") == id("a", "bc") {
+		t.Error(`("ab", "c") and ("a", "bc") get the same id`)
+	}
+	product := Product{Name: "Laptop", Manufacturer: "Acme"}
+	if previous := ids.PreviousIDs(product); len(previous) != 1 || previous[0] != product.GetHash() {
+		t.Errorf("PreviousIDs = %v, want the sha1 id %q", previous, product.GetHash())
+	}
+}
+
+func TestTimeOrderedIDs(t *testing.T) {
+	tests := []struct {
+		strategy string
+		format   *regexp.Regexp
+	}{
+		{strategy: IDStrategyUUIDv7, format: regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+		// the first digit carries the two always zero leading bits, so it is at most 7
+		{strategy: IDStrategyULID, format: regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			ids := mustIDGenerator(t, tt.strategy)
+			if ids.Derived() {
+				t.Error("Derived() = true, want every product to get a new id")
+			}
+
+			seen := make(map[string]bool)
+			var ordered []string
+			for i := 0; i < 10000; i++ {
+				id, err := ids.NewID(Product{Name: "Laptop", Manufacturer: "Acme"})
+				if err != nil {
+					t.Fatalf("NewID: %v", err)
+				}
+				if !tt.format.MatchString(id) {
+					t.Fatalf("id %q is malformed", id)
+				}
+				if seen[id] {
+					t.Fatalf("id %q given twice", id)
+				}
+				seen[id] = true
+
+				// ids are only ordered across milliseconds, the random bits order those within one
+				if i%2000 == 0 {
+					ordered = append(ordered, id)
+					time.Sleep(2 * time.Millisecond)
+				}
+			}
+			if !sort.StringsAreSorted(ordered) {
+				t.Errorf("ids given milliseconds apart are out of order: %v", ordered)
+			}
+		})
+	}
+}
+
+func TestULIDEncodesTheTime(t *testing.T) {
+	before := time.Now().UnixMilli()
+	id, err := mustIDGenerator(t, IDStrategyULID).NewID(Product{})
+	if err != nil {
+		t.Fatalf("NewID: %v", err)
+	}
+	after := time.Now().UnixMilli()
+
+	// the first ten digits hold the 48 bit milliseconds
+	var ms int64
+	for _, c := range id[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	if ms < before || ms > after {
+		t.Errorf("id %s encodes %d, want a time between %d and %d", id, ms, before, after)
+	}
+}
+
+func TestSKUIDs(t *testing.T) {
+	ids := mustIDGenerator(t, IDStrategySKU)
+	if !ids.Derived() {
+		t.Error("Derived() = false, want the id known from the product")
+	}
+
+	tests := []struct {
+		sku     string
+		wantErr bool
+	}{
+		{sku: "LAP-001_a.b"},
+		{sku: strings.Repeat("x", MaxSKULength)},
+		{sku: "", wantErr: true},
+		{sku: strings.Repeat("x", MaxSKULength+1), wantErr: true},
+		{sku: "lap 001", wantErr: true},
+		{sku: "lap/001", wantErr: true},
+		{sku: "lăp", wantErr: true},
+	}
+	for _, tt := range tests {
+		id, err := ids.NewID(Product{Name: "Laptop", Manufacturer: "Acme", SKU: tt.sku})
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSKU) {
+				t.Errorf("NewID(sku %q) = %q, %v, want %v", tt.sku, id, err, ErrInvalidSKU)
+			}
+			continue
+		}
+		if err != nil || id != tt.sku {
+			t.Errorf("NewID(sku %q) = %q, %v, want the sku", tt.sku, id, err)
+		}
+	}
+
+	// products sharing a sku are the same product, whatever their name
+	a, _ := ids.NewID(Product{Name: "Laptop", Manufacturer: "Acme", SKU: "LAP-001"})
+	b, _ := ids.NewID(Product{Name: "Notebook", Manufacturer: "Other", SKU: "LAP-001"})
+	if a != b {
+		t.Errorf("the same sku gives ids %q and %q", a, b)
+	}
+	if renamed, _ := ids.RenamedID("LAP-001", Product{Name: "Notebook"}); renamed != "LAP-001" {
+		t.Errorf("RenamedID = %q, want the sku kept", renamed)
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// IdempotencyKeyHeader names the header a client sets to make a write safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const MaxIdempotencyKeyLength = 255
+
+// ErrIdempotencyKeyReused is returned when a key is used again for a request other than the one it was first used with
+var ErrIdempotencyKeyReused = NewError(ErrValidation, "idempotency_key_reused", "idempotency key was used with another request")
+
+// ErrIdempotencyKeyInProgress is returned when the request first sent with a key has not been answered yet
+var ErrIdempotencyKeyInProgress = NewError(ErrConflict, "idempotency_key_in_progress", "a request with this idempotency key is in progress")
+
+// IdempotentResponse is the response given to the first request carrying an Idempotency-Key,
+// replayed to every repeat of the request until the key expires
+type IdempotentResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+// RequestFingerprint identifies a request by its method, path, query, If-Match header and body,
+// so that a key used again can be told apart from a repeat of the same request
+func RequestFingerprint(method, path, query, ifMatch string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "?" + query + "\n"))
+	h.Write([]byte("If-Match: " + ifMatch + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Field is a JSON member that tells an absent member (Set is false)
+// apart from an explicit null (Null is true) and a value
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// Value returns a Field holding v
+func Value[T any](v T) Field[T] {
+	return Field[T]{Set: true, Value: v}
+}
+
+// UnmarshalJSON is only called by encoding/json when the member is present
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+func (f Field[T]) MarshalJSON() ([]byte, error) {
+	if f.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.Value)
+}
+
+// ProductPatch is an RFC 7396 JSON Merge Patch over the mutable fields of a product.
+// Absent members are left untouched and null resets a member to its zero value.
+type ProductPatch struct {
+	Price Field[int]      `json:"price"`
+	Stock Field[int]      `json:"stock"`
+	Tags  Field[[]string] `json:"tags"`
+}
+
+// MarshalJSON leaves out absent members, which the struct tags alone cannot express
+func (p ProductPatch) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{})
+	if p.Price.Set {
+		members["price"] = p.Price
+	}
+	if p.Stock.Set {
+		members["stock"] = p.Stock
+	}
+	if p.Tags.Set {
+		members["tags"] = p.Tags
+	}
+	return json.Marshal(members)
+}
+
+// Validate checks the members the patch sets with the rules Product.Validate applies to them,
+// so a patch cannot leave a product in a state it could not have been created in
+func (p ProductPatch) Validate() error {
+	if err := validateTexts("", "", p.Tags.Value); err != nil {
+		return err
+	}
+	return validateAmounts(p.Price.Value, p.Stock.Value)
+}
+
+// Apply returns product with the patch merged into it
+func (p ProductPatch) Apply(product Product) Product {
+	if p.Price.Set {
+		product.Price = p.Price.Value
+	}
+	if p.Stock.Set {
+		product.Stock = p.Stock.Value
+	}
+	if p.Tags.Set {
+		product.Tags = p.Tags.Value
+	}
+	return product
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPriceChangeClosed is returned when cancelling a price change that is no longer pending
+var ErrPriceChangeClosed = NewError(ErrConflict, "price_change_closed", "price change is no longer pending")
+
+// PriceChangeStatus is the lifecycle state of a scheduled price change
+type PriceChangeStatus string
+
+const (
+	PriceChangePending   PriceChangeStatus = "pending"
+	PriceChangeApplied   PriceChangeStatus = "applied"
+	PriceChangeCancelled PriceChangeStatus = "cancelled"
+	// PriceChangeSuperseded marks a change that fell due together with a later one for the same product,
+	// only the change with the latest effective time is applied
+	PriceChangeSuperseded PriceChangeStatus = "superseded"
+)
+
+// PriceChange sets the price of a product, in the minor units of Currency, once EffectiveFrom has passed.
+// The product is priced in Currency from then on. AppliedAt is only set on applied changes.
+type PriceChange struct {
+	ID            string            `json:"id"`
+	ProductID     string            `json:"productId"`
+	Price         int               `json:"price"`
+	Currency      string            `json:"currency"`
+	EffectiveFrom time.Time         `json:"effectiveFrom"`
+	Status        PriceChangeStatus `json:"status"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	AppliedAt     *time.Time        `json:"appliedAt,omitempty"`
+}
+
+// PriceChangeRequest schedules Price for a product from EffectiveFrom on.
+// Price is in the minor units of Currency, which is the currency the product is priced in when the change is scheduled if left out.
+// A time already past is applied on the next sweep.
+type PriceChangeRequest struct {
+	ProductID     string    `json:"productId"`
+	Price         int       `json:"price"`
+	Currency      string    `json:"currency,omitempty"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+}
+
+// Validate checks the request, returning a message fit for the caller
+func (r PriceChangeRequest) Validate() error {
+	switch {
+	case r.ProductID == "":
+		return errors.New("productId must be provided")
+	case r.Price < 0:
+		return errors.New("price must not be negative")
+	case r.EffectiveFrom.IsZero():
+		return errors.New("effectiveFrom must be provided")
+	}
+	return ValidateCurrency(r.Currency)
+}
+
+// NewPriceChangeID returns a random identifier for a price change
+func NewPriceChangeID() string {
+	return NewReservationID()
+}
+
+// PricePoint is a price a product had from Since on, starting at Version
+type PricePoint struct {
+	Price    int       `json:"price"`
+	Currency string    `json:"currency"`
+	Version  int64     `json:"version"`
+	Since    time.Time `json:"since"`
+}
+
+// PriceMoney returns the price along with its currency
+func (p PricePoint) PriceMoney() Money {
+	return Money{Amount: p.Price, Currency: currencyOrDefault(p.Currency)}
+}
+
+// PriceHistory picks the prices a product had out of its revisions, oldest first.
+// A revision that only moves the price to another currency starts a price of its own.
+// It returns at most limit of them newest first.
+func PriceHistory(revisions []ProductRevision, limit int) []PricePoint {
+	points := make([]PricePoint, 0)
+	for i, revision := range revisions {
+		if i > 0 && revision.Product.PriceMoney() == revisions[i-1].Product.PriceMoney() {
+			continue
+		}
+		points = append(points, PricePoint{
+			Price:    revision.Product.Price,
+			Currency: revision.Product.CurrencyCode(),
+			Version:  revision.Product.Version,
+			Since:    revision.RecordedAt,
+		})
+	}
+
+	history := make([]PricePoint, 0, limit)
+	for i := len(points) - 1; i >= 0 && len(history) < limit; i-- {
+		history = append(history, points[i])
+	}
+	return history
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of the error responses of both services
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response. Code is machine readable and names the error,
+// the errors of the domain keep their code from one service to the other.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// ID names the entity a conflict is about, such as the product a create found already stored
+	ID string `json:"id,omitempty"`
+}
+
+// knownErrors are the errors a problem is decoded back into by its code
+var knownErrors = []error{
+	ErrNotFound, ErrAlreadyExists, ErrConflict, ErrValidation, ErrUnavailable,
+	ErrVersionMismatch, ErrInsufficientStock, ErrUnknownWarehouse, ErrUnknownCurrency, ErrNoExchangeRate,
+	ErrInvalidSKU, ErrIDTaken, ErrReservationClosed, ErrPriceChangeClosed,
+	ErrIdempotencyKeyReused, ErrIdempotencyKeyInProgress,
+}
+
+// NewProblem describes err answered with status to the request for instance.
+// An error outside the domain is coded after the status, e.g. bad_request.
+func NewProblem(status int, err error, instance string) Problem {
+	code := ErrorCode(err)
+	if code == "" {
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+	return Problem{
+		Type:     "/problems/" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: instance,
+		Code:     code,
+	}
+}
+
+// Err returns the error the problem describes, which errors.Is matches with the known error of its code
+// or, for other codes, with the kind of error its status stands for
+func (p Problem) Err() error {
+	detail := p.Detail
+	if detail == "" {
+		detail = p.Title
+	}
+	for _, known := range knownErrors {
+		if ErrorCode(known) == p.Code {
+			return NewError(known, "", detail)
+		}
+	}
+
+	switch p.Status {
+	case http.StatusNotFound:
+		return NewError(ErrNotFound, p.Code, detail)
+	case http.StatusConflict:
+		return NewError(ErrConflict, p.Code, detail)
+	case http.StatusPreconditionFailed:
+		return NewError(ErrVersionMismatch, p.Code, detail)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return NewError(ErrValidation, p.Code, detail)
+	case http.StatusServiceUnavailable:
+		return NewError(ErrUnavailable, p.Code, detail)
+	}
+	return errors.New(detail)
+}
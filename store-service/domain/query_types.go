@@ -0,0 +1,291 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultListLimit is the page size used when the caller does not ask for one
+	DefaultListLimit = 50
+	// MaxListLimit caps the page size a caller can ask for
+	MaxListLimit = 500
+)
+
+// Sort keys a product listing can be ordered by. Ties are always broken by id.
+const (
+	SortByID    = "id"
+	SortByName  = "name"
+	SortByPrice = "price"
+	SortByStock = "stock"
+)
+
+// ProductQuery filters, sorts and paginates a product listing.
+// Zero values mean no filter.
+type ProductQuery struct {
+	Manufacturer string
+	Tag          string
+	NamePrefix   string
+	MinPrice     *int
+	MaxPrice     *int
+	MinStock     *int
+	MaxStock     *int
+	SortBy       string
+	Descending   bool
+	Cursor       string
+	Limit        int
+}
+
+// ListedProduct is a product together with its id, as returned by listings
+type ListedProduct struct {
+	ID string `json:"id"`
+	Product
+}
+
+// ProductPage is one page of a product listing.
+// NextCursor is empty on the last page.
+type ProductPage struct {
+	Items      []ListedProduct `json:"items"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+var (
+	// ErrMalformedCursor is returned for a cursor that was not handed out by a listing
+	ErrMalformedCursor = NewError(ErrValidation, "malformed_cursor", "malformed cursor")
+	// ErrCursorMismatch is returned for a cursor handed out by a listing in another sort order
+	ErrCursorMismatch = NewError(ErrValidation, "cursor_mismatch", "cursor does not match the requested sort order")
+)
+
+// Cursor marks the last product of a page, so the next page starts right after it
+type Cursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	ID         string `json:"id"`
+	Name       string `json:"n,omitempty"`
+	Value      int    `json:"v,omitempty"`
+}
+
+// NewCursor builds the cursor pointing right after product p for the given query
+func NewCursor(q ProductQuery, p ListedProduct) Cursor {
+	c := Cursor{SortBy: q.SortBy, Descending: q.Descending, ID: p.ID}
+	switch q.SortBy {
+	case SortByName:
+		c.Name = p.Name
+	case SortByPrice:
+		c.Value = p.Price
+	case SortByStock:
+		c.Value = p.Stock
+	}
+	return c
+}
+
+// Encode returns the opaque representation of the cursor handed to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by a previous listing of the same query
+func (q ProductQuery) DecodeCursor() (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return Cursor{}, ErrMalformedCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, ErrMalformedCursor
+	}
+
+	if c.SortBy != q.SortBy || c.Descending != q.Descending {
+		return Cursor{}, ErrCursorMismatch
+	}
+	return c, nil
+}
+
+// ParseProductQuery reads a listing query from url query parameters:
+// manufacturer, tag, namePrefix, minPrice, maxPrice, minStock, maxStock,
+// sort (a sort key, prefixed with - for descending order), cursor and limit
+func ParseProductQuery(values url.Values) (ProductQuery, error) {
+	q := ProductQuery{
+		Manufacturer: values.Get("manufacturer"),
+		Tag:          values.Get("tag"),
+		NamePrefix:   values.Get("namePrefix"),
+		SortBy:       SortByID,
+		Cursor:       values.Get("cursor"),
+		Limit:        DefaultListLimit,
+	}
+
+	bounds := []struct {
+		name string
+		dst  **int
+	}{
+		{"minPrice", &q.MinPrice},
+		{"maxPrice", &q.MaxPrice},
+		{"minStock", &q.MinStock},
+		{"maxStock", &q.MaxStock},
+	}
+	for _, bound := range bounds {
+		value := values.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return ProductQuery{}, fmt.Errorf("%s must be an integer", bound.name)
+		}
+		*bound.dst = &i
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		q.Descending = strings.HasPrefix(sort, "-")
+		q.SortBy = strings.TrimPrefix(sort, "-")
+		switch q.SortBy {
+		case SortByID, SortByName, SortByPrice, SortByStock:
+		default:
+			return ProductQuery{}, fmt.Errorf("cannot sort by %q", q.SortBy)
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		i, err := strconv.Atoi(limit)
+		if err != nil || i < 1 {
+			return ProductQuery{}, fmt.Errorf("limit must be a positive integer")
+		}
+		if i > MaxListLimit {
+			i = MaxListLimit
+		}
+		q.Limit = i
+	}
+
+	if q.Cursor != "" {
+		if _, err := q.DecodeCursor(); err != nil {
+			return ProductQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+// Values is the inverse of ParseProductQuery
+func (q ProductQuery) Values() url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			values.Set(key, strconv.Itoa(*value))
+		}
+	}
+
+	set("manufacturer", q.Manufacturer)
+	set("tag", q.Tag)
+	set("namePrefix", q.NamePrefix)
+	setInt("minPrice", q.MinPrice)
+	setInt("maxPrice", q.MaxPrice)
+	setInt("minStock", q.MinStock)
+	setInt("maxStock", q.MaxStock)
+	if q.SortBy != "" {
+		sort := q.SortBy
+		if q.Descending {
+			sort = "-" + sort
+		}
+		values.Set("sort", sort)
+	}
+	set("cursor", q.Cursor)
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return values
+}
+
+// Page cuts the sorted items down to one page, setting the cursor of the next
+// page if there are more items than the query's limit
+func (q ProductQuery) Page(items []ListedProduct) ProductPage {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	if len(items) <= limit {
+		return ProductPage{Items: items}
+	}
+
+	items = items[:limit]
+	return ProductPage{
+		Items:      items,
+		NextCursor: NewCursor(q, items[limit-1]).Encode(),
+	}
+}
+
+// Matches reports whether p passes every filter of the query
+func (q ProductQuery) Matches(p Product) bool {
+	if q.Manufacturer != "" && p.Manufacturer != q.Manufacturer {
+		return false
+	}
+	if q.NamePrefix != "" && !strings.HasPrefix(p.Name, q.NamePrefix) {
+		return false
+	}
+	if q.Tag != "" && !containsTag(p.Tags, q.Tag) {
+		return false
+	}
+	if (q.MinPrice != nil && p.Price < *q.MinPrice) || (q.MaxPrice != nil && p.Price > *q.MaxPrice) {
+		return false
+	}
+	if (q.MinStock != nil && p.Stock < *q.MinStock) || (q.MaxStock != nil && p.Stock > *q.MaxStock) {
+		return false
+	}
+	return true
+}
+
+// Less reports whether a is listed before b in the query's sort order
+func (q ProductQuery) Less(a, b ListedProduct) bool {
+	return q.compare(NewCursor(q, a), NewCursor(q, b)) < 0
+}
+
+// After reports whether p is listed after the position marked by c
+func (q ProductQuery) After(p ListedProduct, c Cursor) bool {
+	return q.compare(NewCursor(q, p), c) > 0
+}
+
+func (q ProductQuery) compare(a, b Cursor) int {
+	cmp := 0
+	switch q.SortBy {
+	case SortByName:
+		cmp = strings.Compare(a.Name, b.Name)
+	case SortByPrice, SortByStock:
+		cmp = compareInts(a.Value, b.Value)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+	if q.Descending {
+		cmp = -cmp
+	}
+	return cmp
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
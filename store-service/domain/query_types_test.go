@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func listed(ids ...string) []ListedProduct {
+	items := make([]ListedProduct, len(ids))
+	for i, id := range ids {
+		items[i] = ListedProduct{ID: id, Product: Product{Name: id}}
+	}
+	return items
+}
+
+func TestPageCutsAtTheLimit(t *testing.T) {
+	q := ProductQuery{SortBy: SortByName, Limit: 2}
+
+	if page := q.Page(listed("a", "b")); len(page.Items) != 2 || page.NextCursor != "" {
+		t.Errorf("Page of exactly the limit = %+v, want the last page", page)
+	}
+
+	page := q.Page(listed("a", "b", "c"))
+	if len(page.Items) != 2 || page.NextCursor == "" {
+		t.Fatalf("Page over the limit = %+v, want 2 items and a cursor", page)
+	}
+	q.Cursor = page.NextCursor
+	cursor, err := q.DecodeCursor()
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if cursor.ID != "b" || cursor.Name != "b" {
+		t.Errorf("cursor %+v, want it at the last item of the page", cursor)
+	}
+}
+
+func TestAfterStartsRightAfterTheCursorInBothDirections(t *testing.T) {
+	items := listed("a", "b", "c")
+	for _, descending := range []bool{false, true} {
+		q := ProductQuery{SortBy: SortByName, Descending: descending}
+		cursor := NewCursor(q, items[1])
+
+		before, after := items[0], items[2]
+		if descending {
+			before, after = after, before
+		}
+		if q.After(items[1], cursor) || q.After(before, cursor) || !q.After(after, cursor) {
+			t.Errorf("descending %v: only %s should be after the cursor at b", descending, after.ID)
+		}
+	}
+}
+
+func TestLessComparesNamesByteByByte(t *testing.T) {
+	q := ProductQuery{SortBy: SortByName}
+	if !q.Less(ListedProduct{ID: "1", Product: Product{Name: "Zebra"}}, ListedProduct{ID: "2", Product: Product{Name: "apple"}}) {
+		t.Error("Zebra is not listed before apple, want upper case letters first like the \"C\" collation")
+	}
+}
+
+func TestDecodeCursorRejectsTamperedCursors(t *testing.T) {
+	q := ProductQuery{SortBy: SortByPrice}
+	tests := []struct {
+		cursor string
+		err    error
+	}{
+		{"!!!", ErrMalformedCursor},
+		{"bm90IGpzb24", ErrMalformedCursor},
+		{NewCursor(ProductQuery{SortBy: SortByPrice, Descending: true}, ListedProduct{ID: "a"}).Encode(), ErrCursorMismatch},
+		{NewCursor(ProductQuery{SortBy: SortByName}, ListedProduct{ID: "a"}).Encode(), ErrCursorMismatch},
+	}
+	for _, tt := range tests {
+		q.Cursor = tt.cursor
+		if _, err := q.DecodeCursor(); !errors.Is(err, tt.err) {
+			t.Errorf("DecodeCursor(%q) = %v, want %v", tt.cursor, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// ProductQueueKey is the Redis list shared by the api and store services.
+// Batches are pushed on its left and consumed from its right.
+const ProductQueueKey = "store:product:batch"
+
+// ProductProcessingKey is the Redis list holding the batches the store service is applying
+const ProductProcessingKey = "store:product:batch:processing"
+
+// JobResultsTTL is how long the results of a job are kept on Redis for the api service to read
+const JobResultsTTL = time.Hour
+
+// JobResultsKey is the Redis list on which the store service pushes the outcome of the items
+// of the job's batches, one JSON array of BatchItemResult per batch
+func JobResultsKey(jobID string) string {
+	return "store:job:" + jobID + ":results"
+}
+
+// QueueOperation tells the consumer what to do with a queued batch
+type QueueOperation string
+
+const (
+	QueueOperationCreate QueueOperation = "create"
+	QueueOperationUpdate QueueOperation = "update"
+	QueueOperationDelete QueueOperation = "delete"
+)
+
+// QueueMessage is the envelope pushed on the queue for every batch.
+// Only the field matching Operation is populated.
+type QueueMessage struct {
+	Operation QueueOperation `json:"operation"`
+	Products  []Product      `json:"products,omitempty"`
+	Diffs     []ProductDiff  `json:"diffs,omitempty"`
+	IDs       []string       `json:"ids,omitempty"`
+	// JobID is set for the batches of a job, whose results are pushed on JobResultsKey(JobID)
+	// with Offset, the index of the first item of the batch in the job, added to their Index
+	JobID  string `json:"jobId,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	// Attempts counts the times the store service failed to apply the batch for a reason
+	// that may pass, it is queued again with Attempts incremented until it is given up
+	Attempts int `json:"attempts,omitempty"`
+}
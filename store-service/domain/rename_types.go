@@ -0,0 +1,50 @@
+package domain
+
+import "errors"
+
+// ErrIDTaken is returned when renaming a product would give it the id of another product
+var ErrIDTaken = NewError(ErrConflict, "id_taken", "id taken by another product")
+
+// ProductRename changes the name or manufacturer of the product with the given id,
+// members left out keep their value. Renaming a product moves it to the id its strategy
+// derives from the new name, its previous id stays an alias of it.
+type ProductRename struct {
+	ID string `json:"id"`
+	// Version, when set, is the version the caller expects the product to be at
+	Version      *int64  `json:"version,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	Manufacturer *string `json:"manufacturer,omitempty"`
+}
+
+// Validate checks the rename carries an id and something to change, returning a message fit for the caller
+func (r ProductRename) Validate() error {
+	switch {
+	case r.ID == "":
+		return errors.New("id must be provided")
+	case r.Name == nil && r.Manufacturer == nil:
+		return errors.New("name or manufacturer must be provided")
+	case r.Name != nil && *r.Name == "":
+		return errors.New("name must not be empty")
+	}
+	renamed := r.Apply(Product{})
+	return validateTexts(renamed.Name, renamed.Manufacturer, nil)
+}
+
+// Apply returns the product with the name and manufacturer of the rename
+func (r ProductRename) Apply(p Product) Product {
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Manufacturer != nil {
+		p.Manufacturer = *r.Manufacturer
+	}
+	return p
+}
+
+// RenameResult is a renamed product along with the id it is stored under now.
+// PreviousID is set when the rename moved it from another id.
+type RenameResult struct {
+	ID         string  `json:"id"`
+	PreviousID string  `json:"previousId,omitempty"`
+	Product    Product `json:"product"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// TrashedProduct is a deleted product waiting in the trash to be restored or purged
+type TrashedProduct struct {
+	ListedProduct
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// PurgeResult reports how many products a purge of the trash removed
+type PurgeResult struct {
+	Purged int `json:"purged"`
+}
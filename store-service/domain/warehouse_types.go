@@ -0,0 +1,71 @@
+package domain
+
+import "errors"
+
+// DefaultWarehouse is the warehouse stock goes to when no other is named.
+// It always exists and holds the stock of products created before warehouses.
+const DefaultWarehouse = "main"
+
+// ErrUnknownWarehouse is returned when a stock operation names a warehouse that does not exist
+var ErrUnknownWarehouse = NewError(ErrValidation, "unknown_warehouse", "unknown warehouse")
+
+// Warehouse is a location holding stock
+type Warehouse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Location string `json:"location,omitempty"`
+}
+
+// Validate checks the warehouse, returning a message fit for the caller
+func (w Warehouse) Validate() error {
+	switch {
+	case w.ID == "":
+		return errors.New("id must be provided")
+	case w.Name == "":
+		return errors.New("name must be provided")
+	}
+	return nil
+}
+
+// StockLevel is the stock of a product in a single warehouse.
+// The stock of a product is the sum of its levels across warehouses.
+type StockLevel struct {
+	ProductID string `json:"productId"`
+	Warehouse string `json:"warehouse"`
+	Quantity  int    `json:"quantity"`
+	Reserved  int    `json:"reserved"`
+	Available int    `json:"available"`
+}
+
+// StockTransfer moves Quantity of a product's available stock from one warehouse to another
+type StockTransfer struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Quantity  int    `json:"quantity"`
+	Actor     string `json:"actor,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// Validate checks the transfer, returning a message fit for the caller
+func (t StockTransfer) Validate() error {
+	switch {
+	case t.ID == "":
+		return errors.New("id must be provided")
+	case t.From == "" || t.To == "":
+		return errors.New("from and to must be provided")
+	case t.From == t.To:
+		return errors.New("from and to must be different warehouses")
+	case t.Quantity < 1:
+		return errors.New("quantity must be a positive integer")
+	}
+	return nil
+}
+
+// warehouseOrDefault returns id, or DefaultWarehouse if it is empty
+func warehouseOrDefault(id string) string {
+	if id == "" {
+		return DefaultWarehouse
+	}
+	return id
+}
@@ -0,0 +1,252 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"exam-store/domain"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// popTimeout bounds each blocking pop so cancellation is noticed promptly
+	popTimeout = 5 * time.Second
+	// retryDelay is how long the consumer backs off after a redis error
+	retryDelay = time.Second
+	// maxAttempts is how many times a batch failing for a reason that may pass is applied before it is given up
+	maxAttempts = 5
+)
+
+// errMalformedMessage marks a message that can never be applied, so it is not queued again
+var errMalformedMessage = errors.New("malformed message")
+
+// Consumer drains the batches queued by the api service and applies them to storage
+type Consumer struct {
+	client  redis.Cmdable
+	storage domain.Storage
+}
+
+// NewConsumer accepts any redis.Cmdable so it can be pointed at a real
+// server or at an in-process stand-in such as miniredis
+func NewConsumer(client redis.Cmdable, storage domain.Storage) *Consumer {
+	return &Consumer{
+		client:  client,
+		storage: storage,
+	}
+}
+
+// Run blocks, consuming messages until the context is cancelled.
+// A message stays on the processing list while it is applied and is only dropped once it was,
+// so a batch that fails for a reason that may pass is queued again and one whose consumer stopped
+// midway is taken back on start.
+func (c *Consumer) Run(ctx context.Context) {
+	if err := c.requeueUnfinished(ctx); err != nil {
+		log.Errorf("Failed to requeue unfinished batches, err=%v", err)
+	}
+
+	for ctx.Err() == nil {
+		data, err := c.client.BRPopLPush(ctx, domain.ProductQueueKey, domain.ProductProcessingKey, popTimeout).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to pop from queue, err=%v", err)
+			time.Sleep(retryDelay)
+			continue
+		}
+
+		if err := c.handle(ctx, data); err != nil {
+			log.Errorf("Failed to process queued batch, err=%v", err)
+			time.Sleep(retryDelay)
+		}
+	}
+}
+
+// Drain processes every message currently in the queue without blocking
+// and returns the number of messages handled. It stops at the first batch
+// that fails, leaving it queued.
+func (c *Consumer) Drain(ctx context.Context) (int, error) {
+	processed := 0
+	for {
+		data, err := c.client.RPopLPush(ctx, domain.ProductQueueKey, domain.ProductProcessingKey).Bytes()
+		if err == redis.Nil {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, err
+		}
+
+		if err := c.handle(ctx, data); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+}
+
+// handle applies a message taken onto the processing list and removes it from there.
+// A message that failed for a reason that may pass is queued again, up to maxAttempts times.
+// One that can never be applied, or failed too often, is dropped with its items reported as failed.
+func (c *Consumer) handle(ctx context.Context, data []byte) error {
+	var msg domain.QueueMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Errorf("Dropping malformed queued batch, err=%v", err)
+		return c.client.LRem(ctx, domain.ProductProcessingKey, 1, data).Err()
+	}
+
+	err := c.apply(ctx, msg)
+	status, permanent := permanentStatus(err)
+	switch {
+	case err == nil:
+	case errors.Is(err, errMalformedMessage):
+		log.Errorf("Dropping queued batch, err=%v", err)
+	case permanent:
+		log.Errorf("Dropping queued batch that can never be applied, err=%v", err)
+		c.reportFailed(ctx, msg, status, err)
+	case msg.Attempts+1 >= maxAttempts:
+		log.Errorf("Dropping queued batch after %d attempts, err=%v", maxAttempts, err)
+		c.reportFailed(ctx, msg, http.StatusServiceUnavailable, err)
+	default:
+		c.requeue(ctx, data, msg)
+		return err
+	}
+	return c.client.LRem(ctx, domain.ProductProcessingKey, 1, data).Err()
+}
+
+// requeue moves the message from the processing list back on the queue with one more attempt counted
+func (c *Consumer) requeue(ctx context.Context, data []byte, msg domain.QueueMessage) {
+	msg.Attempts++
+	retry, err := json.Marshal(msg)
+	if err != nil {
+		log.Errorf("Failed to encode batch, it is queued again as it was, err=%v", err)
+		retry = data
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, domain.ProductProcessingKey, 1, data)
+		pipe.LPush(ctx, domain.ProductQueueKey, retry)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Failed to requeue batch, it is left on %s, err=%v", domain.ProductProcessingKey, err)
+	}
+}
+
+// permanentStatus returns the status the items of a batch that failed with err are reported with
+// when applying it again can not help, false for errors that may pass
+func permanentStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusBadRequest, true
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
+
+// requeueUnfinished queues again the messages left on the processing list by a consumer that stopped midway
+func (c *Consumer) requeueUnfinished(ctx context.Context) error {
+	for {
+		err := c.client.RPopLPush(ctx, domain.ProductProcessingKey, domain.ProductQueueKey).Err()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Process applies a single queued message to storage with one bulk statement.
+// The outcome of the items of a job's message is pushed on the job's results.
+func (c *Consumer) Process(ctx context.Context, data []byte) error {
+	var msg domain.QueueMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedMessage, err)
+	}
+	return c.apply(ctx, msg)
+}
+
+// apply applies a decoded message to storage and reports the outcome of its items to its job
+func (c *Consumer) apply(ctx context.Context, msg domain.QueueMessage) error {
+	var results []domain.BatchItemResult
+	var err error
+	switch msg.Operation {
+	case domain.QueueOperationCreate:
+		results, err = domain.CreateResults(c.storage, msg.Products)
+	case domain.QueueOperationUpdate:
+		results, err = domain.UpdateResults(c.storage, msg.Diffs)
+	case domain.QueueOperationDelete:
+		results, err = domain.DeleteResults(c.storage, msg.IDs)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", errMalformedMessage, msg.Operation)
+	}
+	if err != nil {
+		return fmt.Errorf("%s batch: %w", msg.Operation, err)
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Succeeded() {
+			succeeded++
+		}
+	}
+	log.Infof("Queued %s batch applied %d of %d items", msg.Operation, succeeded, len(results))
+
+	if msg.JobID != "" {
+		c.report(ctx, msg, results)
+	}
+	return nil
+}
+
+// reportFailed reports every item of a job's message that is given up as failed with status
+func (c *Consumer) reportFailed(ctx context.Context, msg domain.QueueMessage, status int, err error) {
+	if msg.JobID == "" {
+		return
+	}
+
+	results := make([]domain.BatchItemResult, len(msg.Products)+len(msg.Diffs)+len(msg.IDs))
+	for i := range results {
+		results[i] = domain.BatchItemResult{Index: i, Status: status, Error: err.Error()}
+		switch {
+		case i < len(msg.Diffs):
+			results[i].ID = msg.Diffs[i].ID
+		case i < len(msg.IDs):
+			results[i].ID = msg.IDs[i]
+		}
+	}
+	c.report(ctx, msg, results)
+}
+
+// report pushes the results of a job's message on the job's results, indexed within the job.
+// The batch is applied by then, so a failure is only logged rather than having it queued again.
+func (c *Consumer) report(ctx context.Context, msg domain.QueueMessage, results []domain.BatchItemResult) {
+	for i := range results {
+		results[i].Index += msg.Offset
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		log.Errorf("Failed to encode results of job %s, err=%v", msg.JobID, err)
+		return
+	}
+
+	key := domain.JobResultsKey(msg.JobID)
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, domain.JobResultsTTL)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Failed to report results of job %s, err=%v", msg.JobID, err)
+	}
+}
@@ -0,0 +1,372 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	apidomain "exam-api/domain"
+	apiqueue "exam-api/gateways/queue"
+	"exam-store/domain"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// recordingStorage keeps the batches the consumer applies, failing every one while err is set.
+// Only the batch calls are implemented, the embedded Storage is nil.
+type recordingStorage struct {
+	domain.Storage
+
+	mu      sync.Mutex
+	err     error
+	saved   []domain.Product
+	updated []domain.ProductDiff
+	deleted []string
+}
+
+func (s *recordingStorage) SaveBatch(products []domain.Product) ([]domain.SavedProduct, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.saved = append(s.saved, products...)
+
+	saved := make([]domain.SavedProduct, len(products))
+	for i := range products {
+		saved[i] = domain.SavedProduct{ID: products[i].Name, Created: true}
+	}
+	return saved, nil
+}
+
+func (s *recordingStorage) GetBatch(ids []string) (map[string]domain.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	products := make(map[string]domain.Product, len(ids))
+	for _, product := range s.saved {
+		if wanted[product.Name] {
+			products[product.Name] = product
+		}
+	}
+	return products, nil
+}
+
+func (s *recordingStorage) UpdateBatch(diffs []domain.ProductDiff) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.updated = append(s.updated, diffs...)
+
+	ids := make([]string, len(diffs))
+	for i := range diffs {
+		ids[i] = diffs[i].ID
+	}
+	return ids, nil
+}
+
+func (s *recordingStorage) DeleteBatch(ids []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.deleted = append(s.deleted, ids...)
+	return ids, nil
+}
+
+func (s *recordingStorage) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *recordingStorage) savedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.saved)
+}
+
+type queueFixture struct {
+	server   *miniredis.Miniredis
+	client   *redis.Client
+	producer *apiqueue.RedisRepo
+	storage  *recordingStorage
+	consumer *Consumer
+}
+
+// newQueueFixture connects the producer of the api service and a consumer to the same miniredis
+func newQueueFixture(t *testing.T) *queueFixture {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	storage := &recordingStorage{}
+	return &queueFixture{
+		server:   server,
+		client:   client,
+		producer: apiqueue.NewRedisRepo(client),
+		storage:  storage,
+		consumer: NewConsumer(client, storage),
+	}
+}
+
+func (f *queueFixture) listLen(t *testing.T, key string) int64 {
+	t.Helper()
+
+	n, err := f.client.LLen(context.Background(), key).Result()
+	if err != nil {
+		t.Fatalf("LLEN %s: %v", key, err)
+	}
+	return n
+}
+
+func TestConsumerAppliesQueuedBatchesInOrder(t *testing.T) {
+	f := newQueueFixture(t)
+
+	if err := f.producer.Add([]apidomain.Product{
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 3, Tags: []string{"computers"}},
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Currency: "EUR"},
+	}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := f.producer.Update([]apidomain.ProductDiff{
+		{ID: "laptop", Diff: apidomain.ProductPatch{Price: apidomain.Value(450000)}},
+	}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := f.producer.Delete([]string{"mouse"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	processed, err := f.consumer.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if processed != 3 {
+		t.Fatalf("Drain processed %d messages, want 3", processed)
+	}
+
+	wantSaved := []domain.Product{
+		{Name: "Laptop", Manufacturer: "Acme", Price: 499999, Stock: 3, Tags: []string{"computers"}},
+		{Name: "Mouse", Manufacturer: "Acme", Price: 5950, Currency: "EUR"},
+	}
+	if !reflect.DeepEqual(f.storage.saved, wantSaved) {
+		t.Errorf("saved %+v, want %+v", f.storage.saved, wantSaved)
+	}
+
+	wantUpdated := []domain.ProductDiff{{ID: "laptop", Diff: domain.ProductPatch{Price: domain.Value(450000)}}}
+	if !reflect.DeepEqual(f.storage.updated, wantUpdated) {
+		t.Errorf("updated %+v, want %+v", f.storage.updated, wantUpdated)
+	}
+
+	if !reflect.DeepEqual(f.storage.deleted, []string{"mouse"}) {
+		t.Errorf("deleted %v, want [mouse]", f.storage.deleted)
+	}
+
+	if n := f.listLen(t, domain.ProductQueueKey); n != 0 {
+		t.Errorf("queue holds %d messages, want 0", n)
+	}
+	if n := f.listLen(t, domain.ProductProcessingKey); n != 0 {
+		t.Errorf("processing list holds %d messages, want 0", n)
+	}
+}
+
+func TestConsumerKeepsBatchesThatFail(t *testing.T) {
+	f := newQueueFixture(t)
+	f.storage.setErr(errors.New("database is down"))
+
+	if err := f.producer.Delete([]string{"laptop"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := f.consumer.Drain(context.Background()); err == nil {
+		t.Fatal("Drain succeeded while storage fails, want an error")
+	}
+	if n := f.listLen(t, domain.ProductQueueKey); n != 1 {
+		t.Fatalf("queue holds %d messages after a failure, want the batch queued again", n)
+	}
+	if n := f.listLen(t, domain.ProductProcessingKey); n != 0 {
+		t.Errorf("processing list holds %d messages, want 0", n)
+	}
+
+	f.storage.setErr(nil)
+	processed, err := f.consumer.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if processed != 1 || !reflect.DeepEqual(f.storage.deleted, []string{"laptop"}) {
+		t.Errorf("processed %d messages deleting %v, want the batch applied once", processed, f.storage.deleted)
+	}
+}
+
+func TestConsumerDropsMalformedMessages(t *testing.T) {
+	f := newQueueFixture(t)
+	ctx := context.Background()
+
+	f.client.LPush(ctx, domain.ProductQueueKey, "not json")
+	f.client.LPush(ctx, domain.ProductQueueKey, `{"operation":"archive"}`)
+
+	processed, err := f.consumer.Drain(ctx)
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if processed != 2 {
+		t.Errorf("Drain processed %d messages, want 2", processed)
+	}
+	if n := f.listLen(t, domain.ProductQueueKey) + f.listLen(t, domain.ProductProcessingKey); n != 0 {
+		t.Errorf("%d malformed messages are still queued, want them dropped", n)
+	}
+}
+
+func TestConsumerRunRequeuesUnfinishedBatches(t *testing.T) {
+	f := newQueueFixture(t)
+
+	// a batch taken by a consumer that stopped before applying it
+	if err := f.producer.Add([]apidomain.Product{{Name: "Laptop", Manufacturer: "Acme"}}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := f.client.RPopLPush(context.Background(), domain.ProductQueueKey, domain.ProductProcessingKey).Err(); err != nil {
+		t.Fatalf("RPOPLPUSH: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.consumer.Run(ctx)
+
+	// and one queued while the consumer runs
+	if err := f.producer.Add([]apidomain.Product{{Name: "Mouse", Manufacturer: "Acme"}}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.storage.savedCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("consumer saved %d products, want 2", f.storage.savedCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if f.storage.saved[0].Name != "Laptop" || f.storage.saved[1].Name != "Mouse" {
+		t.Errorf("saved %+v, want the unfinished batch first", f.storage.saved)
+	}
+}
+
+func TestConsumerReportsJobResults(t *testing.T) {
+	f := newQueueFixture(t)
+	ctx := context.Background()
+
+	if err := f.producer.Push(apidomain.QueueMessage{
+		Operation: apidomain.QueueOperationDelete,
+		IDs:       []string{"laptop", "mouse"},
+		JobID:     "job",
+		Offset:    100,
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	if _, err := f.consumer.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if ttl := f.server.TTL(domain.JobResultsKey("job")); ttl <= 0 {
+		t.Errorf("job results have ttl %v, want them to expire", ttl)
+	}
+
+	results, err := f.producer.JobResults(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("JobResults: %v", err)
+	}
+	want := []apidomain.BatchItemResult{
+		{Index: 100, ID: "laptop", Status: 200},
+		{Index: 101, ID: "mouse", Status: 200},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("JobResults = %+v, want %+v", results, want)
+	}
+}
+
+func TestConsumerDropsBatchesThatCanNeverBeApplied(t *testing.T) {
+	f := newQueueFixture(t)
+	ctx := context.Background()
+	f.storage.setErr(domain.WrapError(domain.ErrValidation, errors.New("value too long for type character varying(64)")))
+
+	if err := f.producer.Push(apidomain.QueueMessage{
+		Operation: apidomain.QueueOperationUpdate,
+		Diffs: []apidomain.ProductDiff{
+			{ID: "laptop", Diff: apidomain.ProductPatch{Price: apidomain.Value(1)}},
+			{ID: "mouse", Diff: apidomain.ProductPatch{Price: apidomain.Value(2)}},
+		},
+		JobID:  "job",
+		Offset: 10,
+	}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	processed, err := f.consumer.Drain(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("Drain = %d, %v, want the batch dropped", processed, err)
+	}
+	if n := f.listLen(t, domain.ProductQueueKey) + f.listLen(t, domain.ProductProcessingKey); n != 0 {
+		t.Errorf("%d messages are still queued, want the invalid batch dropped", n)
+	}
+
+	results, err := f.producer.JobResults(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("JobResults: %v", err)
+	}
+	want := []apidomain.BatchItemResult{
+		{Index: 10, ID: "laptop", Status: 400, Error: "update batch: value too long for type character varying(64)"},
+		{Index: 11, ID: "mouse", Status: 400, Error: "update batch: value too long for type character varying(64)"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("JobResults = %+v, want %+v", results, want)
+	}
+}
+
+func TestConsumerGivesUpBatchesThatKeepFailing(t *testing.T) {
+	f := newQueueFixture(t)
+	ctx := context.Background()
+	f.storage.setErr(errors.New("database is down"))
+
+	if err := f.producer.Push(apidomain.QueueMessage{Operation: apidomain.QueueOperationDelete, IDs: []string{"laptop"}, JobID: "job"}); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		if _, err := f.consumer.Drain(ctx); err == nil {
+			t.Fatalf("attempt %d succeeded while storage fails, want the batch queued again", attempt)
+		}
+		if n := f.listLen(t, domain.ProductQueueKey); n != 1 {
+			t.Fatalf("queue holds %d messages after attempt %d, want the batch queued again", n, attempt)
+		}
+	}
+	if processed, err := f.consumer.Drain(ctx); err != nil || processed != 1 {
+		t.Fatalf("last attempt = %d, %v, want the batch given up", processed, err)
+	}
+	if n := f.listLen(t, domain.ProductQueueKey) + f.listLen(t, domain.ProductProcessingKey); n != 0 {
+		t.Errorf("%d messages are still queued after %d attempts, want the batch dropped", n, maxAttempts)
+	}
+
+	results, err := f.producer.JobResults(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("JobResults: %v", err)
+	}
+	want := []apidomain.BatchItemResult{{Index: 0, ID: "laptop", Status: 503, Error: "delete batch: database is down"}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("JobResults = %+v, want %+v", results, want)
+	}
+}
@@ -0,0 +1,76 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+)
+
+// translateError turns errors of the driver into the kinds of domain errors, so a unique violation
+// reaches the handlers as ErrAlreadyExists instead of an opaque pq error. Other errors, sql.ErrNoRows
+// among them, are returned as they are.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return exam_api_domain.WrapError(exam_api_domain.ErrUnavailable, err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return exam_api_domain.WrapError(exam_api_domain.ErrAlreadyExists, err)
+	case "foreign_key_violation", "serialization_failure", "deadlock_detected":
+		return exam_api_domain.WrapError(exam_api_domain.ErrConflict, err)
+	case "check_violation", "not_null_violation", "invalid_text_representation", "string_data_right_truncation", "numeric_value_out_of_range":
+		return exam_api_domain.WrapError(exam_api_domain.ErrValidation, err)
+	case "admin_shutdown", "crash_shutdown", "cannot_connect_now", "too_many_connections":
+		return exam_api_domain.WrapError(exam_api_domain.ErrUnavailable, err)
+	}
+	if pqErr.Code.Class() == "08" {
+		// connection exception
+		return exam_api_domain.WrapError(exam_api_domain.ErrUnavailable, err)
+	}
+	return err
+}
+
+// sqlQuerier is satisfied by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// translating runs the statements of the repository and translates the errors they fail with
+type translating struct {
+	q sqlQuerier
+}
+
+func (t translating) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	return rows, translateError(err)
+}
+
+func (t translating) QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner {
+	return translatedRow{row: t.q.QueryRowContext(ctx, query, args...)}
+}
+
+// rowScanner is the single row a statement returns
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type translatedRow struct {
+	row *sql.Row
+}
+
+func (r translatedRow) Scan(dest ...interface{}) error {
+	return translateError(r.row.Scan(dest...))
+}
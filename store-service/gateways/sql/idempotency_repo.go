@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	exam_api_domain "exam-store/domain"
+	"time"
+)
+
+var _ exam_api_domain.IdempotencyStore = (*IdempotencyRepository)(nil)
+
+const (
+	// a key is claimed by inserting it, or by taking over a row whose ttl has passed.
+	// Otherwise the stored row is returned, no row at all means the claim raced with another one.
+	sqlClaimKeyStmt = `WITH claimed AS (
+						INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+						VALUES ($1, $2, now() + $3 * interval '1 second')
+						ON CONFLICT (key) DO UPDATE
+						SET fingerprint = EXCLUDED.fingerprint, status = NULL, header = NULL, body = NULL,
+							created_at = now(), expires_at = EXCLUDED.expires_at
+						WHERE idempotency_keys.expires_at <= now()
+						RETURNING key
+					)
+					SELECT true, '', NULL::integer, NULL::jsonb, NULL::bytea FROM claimed
+					UNION ALL
+					SELECT false, fingerprint, status, header, body FROM idempotency_keys
+					WHERE key = $1 AND NOT EXISTS (SELECT 1 FROM claimed)`
+
+	sqlCompleteKeyStmt = `UPDATE idempotency_keys SET status = $2, header = $3, body = $4 WHERE key = $1`
+
+	sqlReleaseKeyStmt = `DELETE FROM idempotency_keys WHERE key = $1 AND status IS NULL`
+
+	sqlExpireKeysStmt = `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+)
+
+// IdempotencyRepository keeps the responses to writes carrying an Idempotency-Key in postgres
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Begin(key, fingerprint string, ttl time.Duration) (*exam_api_domain.IdempotentResponse, error) {
+	var claimed bool
+	var storedFingerprint string
+	var status sql.NullInt64
+	var header, body []byte
+	err := r.db.QueryRowContext(context.Background(), sqlClaimKeyStmt, key, fingerprint, ttl.Seconds()).
+		Scan(&claimed, &storedFingerprint, &status, &header, &body)
+	if err == sql.ErrNoRows {
+		return nil, exam_api_domain.ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claimed:
+		return nil, nil
+	case storedFingerprint != fingerprint:
+		return nil, exam_api_domain.ErrIdempotencyKeyReused
+	case !status.Valid:
+		return nil, exam_api_domain.ErrIdempotencyKeyInProgress
+	}
+
+	response := exam_api_domain.IdempotentResponse{Status: int(status.Int64), Body: body}
+	if err := json.Unmarshal(header, &response.Header); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (r *IdempotencyRepository) Complete(key string, response exam_api_domain.IdempotentResponse) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(context.Background(), sqlCompleteKeyStmt, key, response.Status, string(header), response.Body)
+	return err
+}
+
+func (r *IdempotencyRepository) Release(key string) error {
+	_, err := r.db.ExecContext(context.Background(), sqlReleaseKeyStmt, key)
+	return err
+}
+
+func (r *IdempotencyRepository) ExpireIdempotencyKeys(now time.Time) (int, error) {
+	result, err := r.db.ExecContext(context.Background(), sqlExpireKeysStmt, now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired, err := result.RowsAffected()
+	return int(expired), err
+}
@@ -4,48 +4,167 @@ import (
 	"context"
 	"database/sql"
 	exam_api_domain "exam-store/domain"
-	"fmt"
 
 	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	sqlCreateStmt = `INSERT INTO products (id, name, manufacturer, price, stock, tags)
-					VALUES ($1, $2, $3, $4, $5, $6) 
-					RETURNING id, name, manufacturer, price, stock, tags`
+	// a product already stored under its id or one it had under an earlier id strategy is not created again,
+	// a trashed product keeps its id until it is purged
+	sqlCreateStmt = `WITH existing AS (
+						SELECT id FROM products
+						WHERE id = $1 OR id = ANY($8)
+						ORDER BY id = $1 DESC
+						LIMIT 1
+					), created AS (
+						INSERT INTO products (id, name, manufacturer, price, currency, stock, tags)
+						SELECT $1::varchar(64), $2::varchar(64), $3::varchar(64), $4::integer, $7::char(3), $5::integer, $6::varchar(64)[]
+						WHERE NOT EXISTS (SELECT 1 FROM existing)
+						ON CONFLICT (id) DO NOTHING
+						RETURNING id, name, manufacturer, price, currency, stock, tags
+					), levelled AS (
+						INSERT INTO stock_levels (product_id, warehouse_id, quantity)
+						SELECT id, 'main', COALESCE(stock, 0) FROM created
+					), recorded AS (
+						INSERT INTO stock_movements (product_id, delta, reason)
+						SELECT id, stock, 'initial' FROM created WHERE COALESCE(stock, 0) <> 0
+					)
+					SELECT id, true FROM existing
+					UNION ALL
+					SELECT id, false FROM created`
 
-	sqlGetByIDStmts = `SELECT id, name, manufacturer, price, stock, tags
+	// statements taking a product id find a renamed product under the ids it had before through resolve_product_id
+	sqlGetByIDStmts = `SELECT id, name, manufacturer, COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version, reserved, COALESCE(stock, 0) - reserved
 					FROM products 
-					WHERE id = $1`
-
-	sqlDeleteByIDStmt = `DELETE FROM products WHERE id = $1 
-					RETURNING id, name, manufacturer, price, stock, tags`
-	sqlUpdateByIDStmts = `UPDATE products
-						SET 
-						    price = $2,
-						    stock = $3,
-						    tags = $4
-						WHERE id = $1
-						RETURNING id, name, manufacturer, price, stock, tags`
+					WHERE id = resolve_product_id($1) AND deleted_at IS NULL`
+
+	sqlExistsStmt = `SELECT EXISTS (SELECT 1 FROM products WHERE id = resolve_product_id($1) AND deleted_at IS NULL)`
+
+	// deleting moves a product to the trash and releases its pending reservations,
+	// its stock levels and ledger are kept so that a restore loses nothing
+	sqlDeleteByIDStmt = `WITH trashed AS (
+							UPDATE products
+							SET deleted_at = now(), reserved = 0
+							WHERE id = resolve_product_id($1) AND deleted_at IS NULL
+							RETURNING id
+						), released AS (
+							UPDATE reservations
+							SET status = 'released'
+							FROM trashed
+							WHERE reservations.product_id = trashed.id AND reservations.status = 'pending'
+						), freed AS (
+							UPDATE stock_levels
+							SET reserved = 0
+							FROM trashed
+							WHERE stock_levels.product_id = trashed.id
+						)
+						SELECT id FROM trashed`
+	sqlDeleteByIDVersionStmt = `WITH trashed AS (
+							UPDATE products
+							SET deleted_at = now(), reserved = 0
+							WHERE id = resolve_product_id($1) AND version = $2 AND deleted_at IS NULL
+							RETURNING id
+						), released AS (
+							UPDATE reservations
+							SET status = 'released'
+							FROM trashed
+							WHERE reservations.product_id = trashed.id AND reservations.status = 'pending'
+						), freed AS (
+							UPDATE stock_levels
+							SET reserved = 0
+							FROM trashed
+							WHERE stock_levels.product_id = trashed.id
+						)
+						SELECT id FROM trashed`
+	// the locked self join exposes the stock before the update, so its change can be recorded.
+	// A new stock is applied to the main warehouse, which must keep at least what it has reserved.
+	sqlUpdateByIDStmts = `WITH updated AS (
+							UPDATE products
+							SET
+							    price = CASE WHEN $2 THEN $3 ELSE products.price END,
+							    stock = CASE WHEN $4 THEN $5 ELSE products.stock END,
+							    tags = CASE WHEN $6 THEN $7 ELSE products.tags END,
+							    version = products.version + 1
+							FROM (SELECT p.id, p.stock, l.quantity, l.reserved
+									FROM products p
+									JOIN stock_levels l ON l.product_id = p.id AND l.warehouse_id = 'main'
+									WHERE p.id = resolve_product_id($1) AND p.deleted_at IS NULL FOR UPDATE) AS old
+							WHERE products.id = old.id AND ($8::bigint IS NULL OR products.version = $8)
+								AND (NOT $4 OR old.quantity + $5 - COALESCE(old.stock, 0) >= old.reserved)
+							RETURNING products.id, products.version,
+								COALESCE(products.stock, 0) - COALESCE(old.stock, 0) AS delta
+						), levelled AS (
+							UPDATE stock_levels
+							SET quantity = stock_levels.quantity + updated.delta
+							FROM updated
+							WHERE stock_levels.product_id = updated.id AND stock_levels.warehouse_id = 'main'
+								AND updated.delta <> 0
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, delta, reason)
+							SELECT id, delta, 'adjustment' FROM updated WHERE delta <> 0
+						)
+						SELECT version FROM updated`
 )
 
+// querier runs statements on either a *sql.DB or a *sql.Tx,
+// so the same statements run inside and outside a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner
+}
+
 type ProductRepository struct {
-	db *sql.DB
+	db querier
+	// conn is nil for a repository bound to a transaction
+	conn *sql.DB
+	// ids assigns the id of a new product
+	ids exam_api_domain.IDGenerator
 }
 
-func NewProductRepository(db *sql.DB) *ProductRepository {
+func NewProductRepository(db *sql.DB, ids exam_api_domain.IDGenerator) *ProductRepository {
 	mr := ProductRepository{
-		db: db,
+		db:   translating{q: db},
+		conn: db,
+		ids:  ids,
 	}
 
 	return &mr
 }
 
+// Atomically runs fn against a repository bound to a single database transaction.
+// The transaction is committed if fn succeeds and rolled back if it returns an error.
+// Calls made on a repository already bound to a transaction join it.
+func (p *ProductRepository) Atomically(fn func(tx exam_api_domain.Storage) error) error {
+	if p.conn == nil {
+		return fn(p)
+	}
+
+	tx, err := p.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if err := fn(&ProductRepository{db: translating{q: tx}, ids: p.ids}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Errorf("Failed to rollback transaction with err=%v", rollbackErr)
+		}
+		return err
+	}
+
+	return translateError(tx.Commit())
+}
+
 func (p *ProductRepository) Save(product exam_api_domain.Product) (string, bool, error) {
 	ctx := context.Background()
-	id := product.GetHash()
-	row := p.db.QueryRowContext(
+	id, err := p.ids.NewID(product)
+	if err != nil {
+		return "", false, err
+	}
+
+	var savedID string
+	var alreadyInDatabase bool
+	err = p.db.QueryRowContext(
 		ctx,
 		sqlCreateStmt,
 		[]byte(id),
@@ -53,13 +172,18 @@ func (p *ProductRepository) Save(product exam_api_domain.Product) (string, bool,
 		[]byte(product.Manufacturer),
 		product.Price,
 		product.Stock,
-		pq.Array(product.Tags))
-	if row.Err() != nil {
-		return "", false, row.Err()
+		pq.Array(product.Tags),
+		product.CurrencyCode(),
+		pq.Array(p.ids.PreviousIDs(product))).Scan(&savedID, &alreadyInDatabase)
+	if err == sql.ErrNoRows {
+		// a concurrent save inserted the same id first
+		return id, true, nil
+	}
+	if err != nil {
+		return "", false, err
 	}
 
-	return id, false, row.Err()
-
+	return savedID, alreadyInDatabase, nil
 }
 
 func (p *ProductRepository) Get(id string) (exam_api_domain.Product, bool, error) {
@@ -83,7 +207,7 @@ func (p *ProductRepository) Get(id string) (exam_api_domain.Product, bool, error
 		var prodID string
 		product := exam_api_domain.Product{}
 
-		if err := rows.Scan(&prodID, &product.Name, &product.Manufacturer, &product.Price, &product.Stock, pq.Array(&product.Tags)); err != nil {
+		if err := rows.Scan(&prodID, &product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock, pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available); err != nil {
 			return exam_api_domain.Product{}, false, err
 		}
 
@@ -92,26 +216,43 @@ func (p *ProductRepository) Get(id string) (exam_api_domain.Product, bool, error
 
 	if len(products) != 1 {
 		// no rows selected
-		return exam_api_domain.Product{}, false, fmt.Errorf("no items")
+		return exam_api_domain.Product{}, false, nil
 	}
 
 	return products[0], true, err
 }
 
-func (p *ProductRepository) Update(id string, diff exam_api_domain.Product) (bool, error) {
+func (p *ProductRepository) Update(id string, diff exam_api_domain.ProductDiff) (bool, error) {
 	ctx := context.Background()
-	row := p.db.QueryRowContext(
+
+	var expected interface{}
+	if diff.Version != nil {
+		expected = *diff.Version
+	}
+
+	// members absent from the patch keep their column value,
+	// a null member carries the zero value and resets the column
+	patch := diff.Diff
+	var version int64
+	err := p.db.QueryRowContext(
 		ctx,
 		sqlUpdateByIDStmts,
 		[]byte(id),
-		diff.Price,
-		diff.Stock,
-		pq.Array(diff.Tags))
-	if row.Err() != nil {
-		return false, row.Err()
+		patch.Price.Set,
+		patch.Price.Value,
+		patch.Stock.Set,
+		patch.Stock.Value,
+		patch.Tags.Set,
+		pq.Array(patch.Tags.Value),
+		expected).Scan(&version)
+	if err == sql.ErrNoRows {
+		return p.updateMissed(id, diff)
+	}
+	if err != nil {
+		return false, err
 	}
 
-	return true, row.Err()
+	return true, nil
 }
 
 func (p *ProductRepository) Delete(id string) (bool, error) {
@@ -120,8 +261,61 @@ func (p *ProductRepository) Delete(id string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if rows.Err() != nil {
-		return false, rows.Err()
+	defer closeRows(rows)
+
+	// a product that is missing or already trashed returns no row
+	trashed, err := scanIDs(rows)
+	if err != nil {
+		return false, err
 	}
+	return len(trashed) == 1, nil
+}
+
+func (p *ProductRepository) DeleteIfVersion(id string, version int64) (bool, error) {
+	ctx := context.Background()
+
+	var deletedID string
+	err := p.db.QueryRowContext(ctx, sqlDeleteByIDVersionStmt, id, version).Scan(&deletedID)
+	if err == sql.ErrNoRows {
+		return false, p.versionMismatch(id, true)
+	}
+	if err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
+
+// updateMissed is called when an update matched no row. The product is either missing,
+// at another version than the diff expects or has more reserved in main than the new stock leaves.
+func (p *ProductRepository) updateMissed(id string, diff exam_api_domain.ProductDiff) (bool, error) {
+	products, err := p.GetBatch([]string{id})
+	if err != nil {
+		return false, err
+	}
+	current, exists := products[id]
+	if !exists {
+		return false, nil
+	}
+	if diff.Version != nil && *diff.Version != current.Version {
+		return false, exam_api_domain.ErrVersionMismatch
+	}
+	return true, exam_api_domain.ErrInsufficientStock
+}
+
+// versionMismatch is called when a conditional write matched no row.
+// It tells a missing product (nil) apart from one at another version (ErrVersionMismatch).
+func (p *ProductRepository) versionMismatch(id string, checked bool) error {
+	if !checked {
+		return nil
+	}
+
+	var exists bool
+	if err := p.db.QueryRowContext(context.Background(), sqlExistsStmt, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return exam_api_domain.ErrVersionMismatch
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// Batches are sent to postgres as a single jsonb parameter and expanded with
+// jsonb_to_recordset, so one statement handles any number of rows without
+// running into the bind parameter limit.
+const (
+	// every row of the batch is reported back by its item, either with the id of the product
+	// it is already stored as or, if the statement created it, with its own id
+	sqlCreateBatchStmt = `WITH input AS (
+						SELECT *
+						FROM jsonb_to_recordset($1::jsonb)
+							AS p(item integer, id varchar(64), name varchar(64), manufacturer varchar(64), price integer,
+								currency char(3), stock integer, tags varchar(64)[], aliases varchar(64)[])
+					), existing AS (
+						SELECT DISTINCT ON (input.item) input.item, products.id
+						FROM input
+						JOIN products ON products.id = input.id OR products.id = ANY(input.aliases)
+						ORDER BY input.item, products.id = input.id DESC
+					), created AS (
+						INSERT INTO products (id, name, manufacturer, price, currency, stock, tags)
+						SELECT id, name, manufacturer, price, currency, stock, tags
+						FROM input
+						WHERE NOT EXISTS (SELECT 1 FROM existing WHERE existing.item = input.item)
+						ON CONFLICT (id) DO NOTHING
+						RETURNING id, stock
+					), levelled AS (
+						INSERT INTO stock_levels (product_id, warehouse_id, quantity)
+						SELECT id, 'main', COALESCE(stock, 0) FROM created
+					), recorded AS (
+						INSERT INTO stock_movements (product_id, delta, reason)
+						SELECT id, stock, 'initial' FROM created WHERE COALESCE(stock, 0) <> 0
+					)
+					SELECT item, id, false FROM existing
+					UNION ALL
+					SELECT input.item, created.id, true FROM created JOIN input ON input.id = created.id`
+
+	// batches report every product under the id it was asked for, which may be one it had before a rename
+	sqlGetByIDsStmt = `SELECT requested.id, name, manufacturer, COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version, reserved,
+						COALESCE(stock, 0) - reserved
+					FROM unnest($1::varchar[]) AS requested(id)
+					JOIN products ON products.id = resolve_product_id(requested.id)
+					WHERE deleted_at IS NULL`
+
+	sqlUpdateBatchStmt = `WITH input AS (
+							SELECT d.*, resolve_product_id(d.id) AS product_id
+							FROM jsonb_to_recordset($1::jsonb)
+								AS d(id varchar(64), price integer, stock integer, tags varchar(64)[], version bigint,
+									set_price boolean, set_stock boolean, set_tags boolean)
+						), updated AS (
+							UPDATE products
+							SET
+							    price = CASE WHEN d.set_price THEN d.price ELSE products.price END,
+							    stock = CASE WHEN d.set_stock THEN d.stock ELSE products.stock END,
+							    tags = CASE WHEN d.set_tags THEN d.tags ELSE products.tags END,
+							    version = products.version + 1
+							FROM input AS d,
+								(SELECT p.id, p.stock, l.quantity, l.reserved
+									FROM products p
+									JOIN stock_levels l ON l.product_id = p.id AND l.warehouse_id = 'main'
+									WHERE p.id IN (SELECT product_id FROM input)
+										AND p.deleted_at IS NULL
+									FOR UPDATE) AS old
+							WHERE products.id = d.product_id AND old.id = d.product_id
+								AND (d.version IS NULL OR products.version = d.version)
+								AND (NOT d.set_stock OR old.quantity + d.stock - COALESCE(old.stock, 0) >= old.reserved)
+							RETURNING products.id, d.id AS requested, COALESCE(products.stock, 0) - COALESCE(old.stock, 0) AS delta
+						), levelled AS (
+							UPDATE stock_levels
+							SET quantity = stock_levels.quantity + updated.delta
+							FROM updated
+							WHERE stock_levels.product_id = updated.id AND stock_levels.warehouse_id = 'main'
+								AND updated.delta <> 0
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, delta, reason)
+							SELECT id, delta, 'adjustment' FROM updated WHERE delta <> 0
+						)
+						SELECT requested FROM updated`
+
+	sqlDeleteByIDsStmt = `WITH requested AS (
+							SELECT id, resolve_product_id(id) AS product_id
+							FROM unnest($1::varchar[]) AS r(id)
+						), trashed AS (
+							UPDATE products
+							SET deleted_at = now(), reserved = 0
+							WHERE id IN (SELECT product_id FROM requested) AND deleted_at IS NULL
+							RETURNING id
+						), released AS (
+							UPDATE reservations
+							SET status = 'released'
+							FROM trashed
+							WHERE reservations.product_id = trashed.id AND reservations.status = 'pending'
+						), freed AS (
+							UPDATE stock_levels
+							SET reserved = 0
+							FROM trashed
+							WHERE stock_levels.product_id = trashed.id
+						)
+						SELECT requested.id FROM requested JOIN trashed ON trashed.id = requested.product_id`
+)
+
+// productRow is the jsonb representation of a row used by the batch statements.
+// Item is the position of the product in the batch and Aliases the ids it may be stored under already.
+type productRow struct {
+	Item         int      `json:"item"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Price        int      `json:"price"`
+	Currency     string   `json:"currency"`
+	Stock        int      `json:"stock"`
+	Tags         []string `json:"tags"`
+	Version      *int64   `json:"version,omitempty"`
+	Aliases      []string `json:"aliases,omitempty"`
+}
+
+// patchRow is the jsonb representation of a ProductDiff used by the update batch
+// statement, the set flags tell the statement which columns the patch touches
+type patchRow struct {
+	ID       string   `json:"id"`
+	Price    int      `json:"price"`
+	Stock    int      `json:"stock"`
+	Tags     []string `json:"tags"`
+	Version  *int64   `json:"version,omitempty"`
+	SetPrice bool     `json:"set_price"`
+	SetStock bool     `json:"set_stock"`
+	SetTags  bool     `json:"set_tags"`
+}
+
+func (p *ProductRepository) SaveBatch(products []exam_api_domain.Product) ([]exam_api_domain.SavedProduct, error) {
+	saved := make([]exam_api_domain.SavedProduct, len(products))
+	rows := make([]productRow, 0, len(products))
+	for i, product := range products {
+		id, err := p.ids.NewID(product)
+		if err != nil {
+			log.Errorf("Failed to assign an id to product %d of batch, err=%v", i, err)
+			saved[i].Err = err
+			continue
+		}
+
+		saved[i].ID = id
+		rows = append(rows, productRow{
+			Item:         i,
+			ID:           id,
+			Name:         product.Name,
+			Manufacturer: product.Manufacturer,
+			Price:        product.Price,
+			Currency:     product.CurrencyCode(),
+			Stock:        product.Stock,
+			Tags:         product.Tags,
+			Aliases:      p.ids.PreviousIDs(product),
+		})
+	}
+
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	result, err := p.db.QueryContext(ctx, sqlCreateBatchStmt, string(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(result)
+
+	for result.Next() {
+		var item int
+		var outcome exam_api_domain.SavedProduct
+		if err := result.Scan(&item, &outcome.ID, &outcome.Created); err != nil {
+			return nil, err
+		}
+		saved[item] = outcome
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	// an id given twice in the batch is only created by its first product
+	created := make(map[string]bool, len(saved))
+	for i := range saved {
+		if saved[i].Created && created[saved[i].ID] {
+			saved[i].Created = false
+		}
+		created[saved[i].ID] = created[saved[i].ID] || saved[i].Created
+	}
+	return saved, nil
+}
+
+func (p *ProductRepository) GetBatch(ids []string) (map[string]exam_api_domain.Product, error) {
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, sqlGetByIDsStmt, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	products := make(map[string]exam_api_domain.Product, len(ids))
+	for rows.Next() {
+		var prodID string
+		product := exam_api_domain.Product{}
+
+		if err := rows.Scan(&prodID, &product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock, pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available); err != nil {
+			return nil, err
+		}
+
+		products[prodID] = product
+	}
+
+	return products, rows.Err()
+}
+
+func (p *ProductRepository) UpdateBatch(diffs []exam_api_domain.ProductDiff) ([]string, error) {
+	rows := make([]patchRow, 0, len(diffs))
+	for _, diff := range diffs {
+		rows = append(rows, patchRow{
+			ID:       diff.ID,
+			Price:    diff.Diff.Price.Value,
+			Stock:    diff.Diff.Stock.Value,
+			Tags:     diff.Diff.Tags.Value,
+			Version:  diff.Version,
+			SetPrice: diff.Diff.Price.Set,
+			SetStock: diff.Diff.Stock.Set,
+			SetTags:  diff.Diff.Tags.Set,
+		})
+	}
+
+	return p.queryIDs(sqlUpdateBatchStmt, rows)
+}
+
+func (p *ProductRepository) DeleteBatch(ids []string) ([]string, error) {
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, sqlDeleteByIDsStmt, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	return scanIDs(rows)
+}
+
+// queryIDs runs a batch statement over rows and returns the ids it reported back
+func (p *ProductRepository) queryIDs(stmt string, rows interface{}) ([]string, error) {
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	result, err := p.db.QueryContext(ctx, stmt, string(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(result)
+
+	return scanIDs(result)
+}
+
+func scanIDs(rows *sql.Rows) ([]string, error) {
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		log.Errorf("Failed to close rows with err=%v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// product_history is written by triggers on products, see schema.sql
+const (
+	sqlHistoryStmt = `SELECT id, product_id, event, name, manufacturer, COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version, reserved,
+						COALESCE(stock, 0) - reserved, recorded_at
+						FROM product_history
+						WHERE product_id = resolve_product_id($1)
+						ORDER BY id DESC
+						LIMIT $2`
+
+	sqlProductAsOfStmt = `SELECT id, product_id, event, name, manufacturer, COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version, reserved,
+						COALESCE(stock, 0) - reserved, recorded_at
+						FROM product_history
+						WHERE product_id = resolve_product_id($1) AND recorded_at <= $2
+						ORDER BY recorded_at DESC, id DESC
+						LIMIT 1`
+)
+
+func (p *ProductRepository) History(productID string, limit int) ([]exam_api_domain.ProductRevision, bool, error) {
+	rows, err := p.db.QueryContext(context.Background(), sqlHistoryStmt, productID, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeRows(rows)
+
+	revisions := make([]exam_api_domain.ProductRevision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return revisions, len(revisions) > 0, nil
+}
+
+func (p *ProductRepository) GetAsOf(id string, at time.Time) (exam_api_domain.Product, bool, error) {
+	revision, err := scanRevision(p.db.QueryRowContext(context.Background(), sqlProductAsOfStmt, id, at))
+	if err == sql.ErrNoRows {
+		return exam_api_domain.Product{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.Product{}, false, err
+	}
+
+	if revision.Event == exam_api_domain.HistoryDeleted {
+		return exam_api_domain.Product{}, false, nil
+	}
+	return revision.Product, true, nil
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRevision(row scanner) (exam_api_domain.ProductRevision, error) {
+	revision := exam_api_domain.ProductRevision{}
+	product := &revision.Product
+	err := row.Scan(&revision.ID, &revision.ProductID, &revision.Event, &product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock,
+		pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available, &revision.RecordedAt)
+	return revision, err
+}
@@ -0,0 +1,88 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+	"time"
+)
+
+func TestGetAsOfFollowsTheHistory(t *testing.T) {
+	p := newTestRepository(t)
+	// revisions are stamped with the start of their transaction, the pauses keep the stamps apart
+	pause := func() { time.Sleep(5 * time.Millisecond) }
+
+	id := mustSave(t, p, exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 1000, Stock: 5})
+	pause()
+	if _, err := p.Update(id, exam_api_domain.ProductDiff{Diff: exam_api_domain.ProductPatch{Price: exam_api_domain.Value(1200)}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	pause()
+	if _, err := p.Delete(id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	pause()
+	if _, err := p.Restore(id); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	pause()
+	if _, err := p.Delete(id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	pause()
+	if _, err := p.Purge(id); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	// the history outlives the purge of the product
+	revisions, found, err := p.History(id, 10)
+	if err != nil || !found {
+		t.Fatalf("History = %v, %v", found, err)
+	}
+	events := []exam_api_domain.HistoryEvent{exam_api_domain.HistoryDeleted, exam_api_domain.HistoryRestored, exam_api_domain.HistoryDeleted,
+		exam_api_domain.HistoryUpdated, exam_api_domain.HistoryCreated}
+	if len(revisions) != len(events) {
+		t.Fatalf("History = %+v, want %d revisions", revisions, len(events))
+	}
+	for i, event := range events {
+		if revisions[i].Event != event {
+			t.Errorf("revision %d is %s, want %s", i, revisions[i].Event, event)
+		}
+	}
+	created, updated, deleted, restored, deletedAgain := revisions[4], revisions[3], revisions[2], revisions[1], revisions[0]
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantFound bool
+		wantPrice int
+	}{
+		{name: "before creation", at: created.RecordedAt.Add(-time.Microsecond)},
+		{name: "at creation", at: created.RecordedAt, wantFound: true, wantPrice: 1000},
+		{name: "after update", at: updated.RecordedAt, wantFound: true, wantPrice: 1200},
+		{name: "after delete", at: deleted.RecordedAt},
+		{name: "after restore", at: restored.RecordedAt, wantFound: true, wantPrice: 1200},
+		{name: "after the last delete", at: deletedAgain.RecordedAt.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, found, err := p.GetAsOf(id, tt.at)
+			if err != nil {
+				t.Fatalf("GetAsOf: %v", err)
+			}
+			if found != tt.wantFound || product.Price != tt.wantPrice {
+				t.Errorf("GetAsOf = %+v, %v, want price %d, %v", product, found, tt.wantPrice, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestGetAsOfOfAnUnknownProductFindsNothing(t *testing.T) {
+	p := newTestRepository(t)
+
+	if _, found, err := p.GetAsOf("unknown", time.Now()); found || err != nil {
+		t.Errorf("GetAsOf = %v, %v, want nothing found", found, err)
+	}
+	if _, found, err := p.History("unknown", 10); found || err != nil {
+		t.Errorf("History = %v, %v, want nothing found", found, err)
+	}
+}
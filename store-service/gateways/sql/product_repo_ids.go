@@ -0,0 +1,78 @@
+package sql
+
+import (
+	"context"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+)
+
+const (
+	sqlProductNamesStmt = `SELECT id, name, COALESCE(manufacturer, '') FROM products`
+
+	// an id already taken by a product or an alias is left alone, the first product to claim it keeps it
+	sqlAliasIDsStmt = `WITH aliased AS (
+							INSERT INTO product_aliases (alias, product_id)
+							SELECT a.alias, a.product_id
+							FROM unnest($1::varchar[], $2::varchar[]) AS a(alias, product_id)
+							WHERE NOT EXISTS (SELECT 1 FROM products WHERE products.id = a.alias)
+							ON CONFLICT (alias) DO NOTHING
+							RETURNING alias
+						)
+						SELECT COUNT(*) FROM aliased`
+)
+
+// AliasCurrentIDs makes every product stored under the id an earlier strategy gave it reachable
+// under the id the repository's strategy derives for it, and returns how many aliases it added.
+// Products keep the id they are stored under, so running it again adds nothing.
+func (p *ProductRepository) AliasCurrentIDs() (int, error) {
+	if !p.ids.Derived() {
+		return 0, nil
+	}
+
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, sqlProductNamesStmt)
+	if err != nil {
+		return 0, err
+	}
+	defer closeRows(rows)
+
+	var aliases, productIDs []string
+	for rows.Next() {
+		var id string
+		product := exam_api_domain.Product{}
+		if err := rows.Scan(&id, &product.Name, &product.Manufacturer); err != nil {
+			return 0, err
+		}
+		if !containsID(p.ids.PreviousIDs(product), id) {
+			continue
+		}
+		current, err := p.ids.NewID(product)
+		if err != nil {
+			return 0, err
+		}
+		if current != id {
+			aliases = append(aliases, current)
+			productIDs = append(productIDs, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(aliases) == 0 {
+		return 0, nil
+	}
+
+	var added int
+	err = p.db.QueryRowContext(ctx, sqlAliasIDsStmt, pq.Array(aliases), pq.Array(productIDs)).Scan(&added)
+	return added, err
+}
+
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
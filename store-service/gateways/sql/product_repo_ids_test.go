@@ -0,0 +1,52 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+)
+
+func TestAliasCurrentIDsReachesSHA1ProductsUnderTheirSHA256IDs(t *testing.T) {
+	sha1Repo := newTestRepository(t)
+	product := exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 3}
+	sha1ID := mustSave(t, sha1Repo, product)
+
+	sha256IDs, err := exam_api_domain.NewIDGenerator(exam_api_domain.IDStrategySHA256)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	p := NewProductRepository(sha1Repo.conn, sha256IDs)
+	sha256ID, _ := sha256IDs.NewID(product)
+
+	if _, found, _ := p.Get(sha256ID); found {
+		t.Fatal("product found under its sha256 id before it is aliased")
+	}
+	if added, err := p.AliasCurrentIDs(); err != nil || added != 1 {
+		t.Fatalf("AliasCurrentIDs = %d, %v, want 1 alias added", added, err)
+	}
+	if added, err := p.AliasCurrentIDs(); err != nil || added != 0 {
+		t.Errorf("AliasCurrentIDs again = %d, %v, want nothing added", added, err)
+	}
+
+	if got, found, err := p.Get(sha256ID); err != nil || !found || got.Stock != 3 {
+		t.Errorf("Get(%s) = %+v, %v, %v, want the product stored under %s", sha256ID, got, found, err, sha1ID)
+	}
+	if _, found, _ := p.Get(sha1ID); !found {
+		t.Error("product no longer found under its sha1 id")
+	}
+	if id, exists, err := p.Save(product); err != nil || !exists || id != sha1ID {
+		t.Errorf("Save = %s, %v, %v, want the product found stored under %s", id, exists, err, sha1ID)
+	}
+}
+
+func TestAliasCurrentIDsSkipsStrategiesNotDerivingIDs(t *testing.T) {
+	sha1Repo := newTestRepository(t)
+	mustSave(t, sha1Repo, exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme"})
+
+	ulids, err := exam_api_domain.NewIDGenerator(exam_api_domain.IDStrategyULID)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	if added, err := NewProductRepository(sha1Repo.conn, ulids).AliasCurrentIDs(); err != nil || added != 0 {
+		t.Errorf("AliasCurrentIDs = %d, %v, want nothing added", added, err)
+	}
+}
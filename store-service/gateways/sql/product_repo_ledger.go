@@ -0,0 +1,68 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+)
+
+// Every statement changing the stock of a product records the change in stock_movements
+// within the same statement, so the ledger cannot miss a change or record one that was rolled back.
+const (
+	sqlStockMovementsStmt = `SELECT id, product_id, warehouse_id, delta, reason, COALESCE(actor, ''), COALESCE(reference, ''), created_at
+						FROM stock_movements
+						WHERE product_id = resolve_product_id($1) AND ($2 = '' OR reason = $2)
+						ORDER BY id DESC
+						LIMIT $3`
+
+	sqlReconcileStockStmt = `SELECT id, COALESCE(stock, 0),
+							COALESCE((SELECT SUM(delta) FROM stock_movements WHERE product_id = products.id), 0)
+						FROM products
+						WHERE id = resolve_product_id($1) AND deleted_at IS NULL`
+)
+
+func (p *ProductRepository) StockMovements(productID string, reason exam_api_domain.MovementReason, limit int) ([]exam_api_domain.StockMovement, bool, error) {
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, sqlStockMovementsStmt, productID, string(reason), limit)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeRows(rows)
+
+	movements := make([]exam_api_domain.StockMovement, 0)
+	for rows.Next() {
+		movement := exam_api_domain.StockMovement{}
+		if err := rows.Scan(&movement.ID, &movement.ProductID, &movement.Warehouse, &movement.Delta, &movement.Reason, &movement.Actor, &movement.Reference, &movement.CreatedAt); err != nil {
+			return nil, false, err
+		}
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(movements) > 0 {
+		return movements, true, nil
+	}
+
+	// an empty ledger may belong to a product that never had stock
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, sqlExistsStmt, productID).Scan(&exists); err != nil {
+		return nil, false, err
+	}
+	return movements, exists, nil
+}
+
+func (p *ProductRepository) ReconcileStock(productID string) (exam_api_domain.StockReconciliation, bool, error) {
+	var id string
+	var stock, ledgerStock int
+	err := p.db.QueryRowContext(context.Background(), sqlReconcileStockStmt, productID).Scan(&id, &stock, &ledgerStock)
+	if err == sql.ErrNoRows {
+		return exam_api_domain.StockReconciliation{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.StockReconciliation{}, false, err
+	}
+
+	return exam_api_domain.NewStockReconciliation(id, stock, ledgerStock), true, nil
+}
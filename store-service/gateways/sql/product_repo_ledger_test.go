@@ -0,0 +1,184 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+)
+
+func TestEveryStockChangeWritesOneMovement(t *testing.T) {
+	p := newTestRepository(t)
+	if _, err := p.CreateWarehouse(exam_api_domain.Warehouse{ID: "north", Name: "North"}); err != nil {
+		t.Fatalf("CreateWarehouse: %v", err)
+	}
+	var laptop, mouse string
+
+	tests := []struct {
+		name    string
+		product *string
+		change  func() error
+		want    []exam_api_domain.StockMovement
+	}{
+		{
+			name:    "save",
+			product: &laptop,
+			change: func() (err error) {
+				laptop, _, err = p.Save(exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 10})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: 10, Reason: exam_api_domain.MovementInitial}},
+		},
+		{
+			name:    "batch save",
+			product: &mouse,
+			change: func() error {
+				saved, err := p.SaveBatch([]exam_api_domain.Product{{Name: "Mouse", Manufacturer: "Acme", Stock: 6}})
+				if err == nil {
+					mouse, err = saved[0].ID, saved[0].Err
+				}
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: 6, Reason: exam_api_domain.MovementInitial}},
+		},
+		{
+			name:    "update of the stock",
+			product: &laptop,
+			change: func() error {
+				_, err := p.Update(laptop, exam_api_domain.ProductDiff{Diff: exam_api_domain.ProductPatch{Stock: exam_api_domain.Value(12)}})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: 2, Reason: exam_api_domain.MovementAdjustment}},
+		},
+		{
+			name:    "update leaving the stock alone",
+			product: &laptop,
+			change: func() error {
+				_, err := p.Update(laptop, exam_api_domain.ProductDiff{Diff: exam_api_domain.ProductPatch{Price: exam_api_domain.Value(999)}})
+				return err
+			},
+		},
+		{
+			name:    "batch update of the stock",
+			product: &mouse,
+			change: func() error {
+				_, err := p.UpdateBatch([]exam_api_domain.ProductDiff{{ID: mouse, Diff: exam_api_domain.ProductPatch{Stock: exam_api_domain.Value(4)}}})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: -2, Reason: exam_api_domain.MovementAdjustment}},
+		},
+		{
+			name:    "adjustment with a reason",
+			product: &laptop,
+			change: func() error {
+				_, _, err := p.AdjustStock(exam_api_domain.StockAdjustment{ID: laptop, Delta: -3, Reason: exam_api_domain.MovementSale, Actor: "till-1", Reference: "order-7"})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: -3, Reason: exam_api_domain.MovementSale, Actor: "till-1", Reference: "order-7"}},
+		},
+		{
+			name:    "adjustment without a reason",
+			product: &laptop,
+			change: func() error {
+				_, _, err := p.AdjustStock(exam_api_domain.StockAdjustment{ID: laptop, Delta: 1})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: 1, Reason: exam_api_domain.MovementAdjustment}},
+		},
+		{
+			name:    "batch adjustment",
+			product: &mouse,
+			change: func() error {
+				_, err := p.AdjustStockBatch([]exam_api_domain.StockAdjustment{{ID: mouse, Delta: 5, Reason: exam_api_domain.MovementReturn}})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: 5, Reason: exam_api_domain.MovementReturn}},
+		},
+		{
+			name:    "restock of another warehouse",
+			product: &laptop,
+			change: func() error {
+				_, _, err := p.AdjustStock(exam_api_domain.StockAdjustment{ID: laptop, Delta: 4, Warehouse: "north", Reason: exam_api_domain.MovementRestock})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "north", Delta: 4, Reason: exam_api_domain.MovementRestock}},
+		},
+		{
+			name:    "released reservation",
+			product: &laptop,
+			change: func() error {
+				reservation, _, err := p.Reserve(exam_api_domain.ReservationRequest{ProductID: laptop, Quantity: 2, TTLSeconds: 60})
+				if err != nil {
+					return err
+				}
+				_, _, err = p.ReleaseReservation(reservation.ID)
+				return err
+			},
+		},
+		{
+			name:    "confirmed reservation",
+			product: &laptop,
+			change: func() error {
+				reservation, _, err := p.Reserve(exam_api_domain.ReservationRequest{ProductID: laptop, Quantity: 2, TTLSeconds: 60})
+				if err != nil {
+					return err
+				}
+				_, _, err = p.ConfirmReservation(reservation.ID)
+				return err
+			},
+			want: []exam_api_domain.StockMovement{{Warehouse: "main", Delta: -2, Reason: exam_api_domain.MovementSale}},
+		},
+		{
+			name:    "transfer",
+			product: &laptop,
+			change: func() error {
+				_, _, err := p.TransferStock(exam_api_domain.StockTransfer{ID: laptop, From: "main", To: "north", Quantity: 3, Reference: "truck-2"})
+				return err
+			},
+			want: []exam_api_domain.StockMovement{
+				{Warehouse: "main", Delta: -3, Reason: exam_api_domain.MovementTransfer, Reference: "truck-2"},
+				{Warehouse: "north", Delta: 3, Reason: exam_api_domain.MovementTransfer, Reference: "truck-2"},
+			},
+		},
+	}
+
+	seen := make(map[string]int)
+	for _, tt := range tests {
+		if err := tt.change(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		id := *tt.product
+		movements, _, err := p.StockMovements(id, "", 100)
+		if err != nil {
+			t.Fatalf("%s: StockMovements: %v", tt.name, err)
+		}
+		written := movements[:len(movements)-seen[id]]
+		seen[id] = len(movements)
+		if len(written) != len(tt.want) {
+			t.Errorf("%s wrote %d movements, want %d", tt.name, len(written), len(tt.want))
+			continue
+		}
+		// a transfer writes both of its movements at once, so they are matched by warehouse
+		for _, want := range tt.want {
+			found := false
+			for _, got := range written {
+				if got.Warehouse == want.Warehouse && got.Delta == want.Delta && got.Reason == want.Reason &&
+					got.Actor == want.Actor && (want.Reference == "" || got.Reference == want.Reference) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s wrote %+v, want %+v among them", tt.name, written, want)
+			}
+		}
+	}
+
+	for _, id := range []string{laptop, mouse} {
+		reconciliation, _, err := p.ReconcileStock(id)
+		if err != nil {
+			t.Fatalf("ReconcileStock(%s): %v", id, err)
+		}
+		if !reconciliation.Consistent {
+			t.Errorf("ReconcileStock = %+v, want the ledger to add up to the stock", reconciliation)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package sql
+
+import (
+	"context"
+	exam_api_domain "exam-store/domain"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const sqlListStmt = `SELECT id, name, COALESCE(manufacturer, ''), COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version,
+					reserved, COALESCE(stock, 0) - reserved
+					FROM products`
+
+// idColumn is the tie breaker of every listing. Like names, ids are compared byte by byte
+// with the "C" collation so listings are ordered the same way as by the memory store.
+const idColumn = `id COLLATE "C"`
+
+// sortColumns maps sort keys to the expression they order by; id needs no extra column
+var sortColumns = map[string]string{
+	exam_api_domain.SortByID:    "",
+	exam_api_domain.SortByName:  `name COLLATE "C"`,
+	exam_api_domain.SortByPrice: "COALESCE(price, 0)",
+	exam_api_domain.SortByStock: "COALESCE(stock, 0)",
+}
+
+func (p *ProductRepository) List(query exam_api_domain.ProductQuery) (exam_api_domain.ProductPage, error) {
+	stmt, args, err := buildListQuery(query)
+	if err != nil {
+		return exam_api_domain.ProductPage{}, err
+	}
+
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return exam_api_domain.ProductPage{}, err
+	}
+	defer closeRows(rows)
+
+	items := make([]exam_api_domain.ListedProduct, 0)
+	for rows.Next() {
+		item := exam_api_domain.ListedProduct{}
+		if err := rows.Scan(&item.ID, &item.Name, &item.Manufacturer, &item.Price, &item.Currency, &item.Stock, pq.Array(&item.Tags), &item.Version, &item.Reserved, &item.Available); err != nil {
+			return exam_api_domain.ProductPage{}, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return exam_api_domain.ProductPage{}, err
+	}
+
+	return query.Page(items), nil
+}
+
+// buildListQuery translates the query into a keyset paginated select.
+// One row more than the limit is fetched to know whether there is a next page.
+func buildListQuery(query exam_api_domain.ProductQuery) (string, []interface{}, error) {
+	column, ok := sortColumns[query.SortBy]
+	if !ok {
+		return "", nil, exam_api_domain.NewError(exam_api_domain.ErrValidation, "", fmt.Sprintf("cannot sort by %q", query.SortBy))
+	}
+
+	// trashed products are never listed
+	where := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.Manufacturer != "" {
+		where = append(where, "manufacturer = "+arg(query.Manufacturer))
+	}
+	if query.Tag != "" {
+		where = append(where, arg(query.Tag)+" = ANY(tags)")
+	}
+	if query.NamePrefix != "" {
+		where = append(where, "starts_with(name, "+arg(query.NamePrefix)+")")
+	}
+	if query.MinPrice != nil {
+		where = append(where, "COALESCE(price, 0) >= "+arg(*query.MinPrice))
+	}
+	if query.MaxPrice != nil {
+		where = append(where, "COALESCE(price, 0) <= "+arg(*query.MaxPrice))
+	}
+	if query.MinStock != nil {
+		where = append(where, "COALESCE(stock, 0) >= "+arg(*query.MinStock))
+	}
+	if query.MaxStock != nil {
+		where = append(where, "COALESCE(stock, 0) <= "+arg(*query.MaxStock))
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.Cursor != "" {
+		cursor, err := query.DecodeCursor()
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch query.SortBy {
+		case exam_api_domain.SortByID:
+			where = append(where, idColumn+" "+comparison+" "+arg(cursor.ID))
+		case exam_api_domain.SortByName:
+			where = append(where, fmt.Sprintf("(%s, %s) %s (%s, %s)", column, idColumn, comparison, arg(cursor.Name), arg(cursor.ID)))
+		default:
+			where = append(where, fmt.Sprintf("(%s, %s) %s (%s, %s)", column, idColumn, comparison, arg(cursor.Value), arg(cursor.ID)))
+		}
+	}
+
+	order := idColumn + " " + direction
+	if column != "" {
+		order = column + " " + direction + ", " + order
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = exam_api_domain.DefaultListLimit
+	}
+
+	stmt := sqlListStmt
+	if len(where) > 0 {
+		stmt += "\n\t\t\t\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	stmt += "\n\t\t\t\t\tORDER BY " + order + "\n\t\t\t\t\tLIMIT " + arg(limit+1)
+	return stmt, args, nil
+}
@@ -0,0 +1,139 @@
+package sql
+
+import (
+	"errors"
+	exam_api_domain "exam-store/domain"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildListQuery(t *testing.T) {
+	minPrice, maxPrice, minStock, maxStock := 100, 900, 1, 50
+	cursor := func(q exam_api_domain.ProductQuery, id, name string, price int) exam_api_domain.ProductQuery {
+		q.Cursor = exam_api_domain.NewCursor(q, exam_api_domain.ListedProduct{ID: id, Product: exam_api_domain.Product{Name: name, Price: price}}).Encode()
+		return q
+	}
+
+	tests := []struct {
+		name  string
+		query exam_api_domain.ProductQuery
+		where []string
+		order string
+		args  []interface{}
+	}{
+		{
+			name:  "no filter",
+			query: exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByID},
+			where: []string{"deleted_at IS NULL"},
+			order: `ORDER BY id COLLATE "C" ASC`,
+			args:  []interface{}{exam_api_domain.DefaultListLimit + 1},
+		},
+		{
+			name: "every filter",
+			query: exam_api_domain.ProductQuery{
+				Manufacturer: "Acme", Tag: "computers", NamePrefix: "Lap",
+				MinPrice: &minPrice, MaxPrice: &maxPrice, MinStock: &minStock, MaxStock: &maxStock,
+				SortBy: exam_api_domain.SortByID, Limit: 10,
+			},
+			where: []string{
+				"deleted_at IS NULL", "manufacturer = $1", "$2 = ANY(tags)", "starts_with(name, $3)",
+				"COALESCE(price, 0) >= $4", "COALESCE(price, 0) <= $5", "COALESCE(stock, 0) >= $6", "COALESCE(stock, 0) <= $7",
+			},
+			order: `ORDER BY id COLLATE "C" ASC`,
+			args:  []interface{}{"Acme", "computers", "Lap", 100, 900, 1, 50, 11},
+		},
+		{
+			name:  "after an id",
+			query: cursor(exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByID, Limit: 2}, "m1", "", 0),
+			where: []string{`id COLLATE "C" > $1`},
+			order: `ORDER BY id COLLATE "C" ASC`,
+			args:  []interface{}{"m1", 3},
+		},
+		{
+			name:  "after a name",
+			query: cursor(exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByName, Limit: 2}, "m1", "Mouse", 0),
+			where: []string{`(name COLLATE "C", id COLLATE "C") > ($1, $2)`},
+			order: `ORDER BY name COLLATE "C" ASC, id COLLATE "C" ASC`,
+			args:  []interface{}{"Mouse", "m1", 3},
+		},
+		{
+			name:  "before a price",
+			query: cursor(exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByPrice, Descending: true, Limit: 2}, "m1", "", 5950),
+			where: []string{`(COALESCE(price, 0), id COLLATE "C") < ($1, $2)`},
+			order: `ORDER BY COALESCE(price, 0) DESC, id COLLATE "C" DESC`,
+			args:  []interface{}{5950, "m1", 3},
+		},
+	}
+	for _, tt := range tests {
+		stmt, args, err := buildListQuery(tt.query)
+		if err != nil {
+			t.Errorf("%s: buildListQuery: %v", tt.name, err)
+			continue
+		}
+		for _, condition := range append(tt.where, tt.order) {
+			if !strings.Contains(stmt, condition) {
+				t.Errorf("%s: statement %q does not contain %q", tt.name, stmt, condition)
+			}
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: args %v, want %v", tt.name, args, tt.args)
+		}
+	}
+}
+
+func TestBuildListQueryRejectsInvalidQueries(t *testing.T) {
+	byName := exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByName}
+	otherOrder := byName
+	otherOrder.Cursor = exam_api_domain.NewCursor(exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByPrice}, exam_api_domain.ListedProduct{ID: "m1"}).Encode()
+
+	tests := []struct {
+		name  string
+		query exam_api_domain.ProductQuery
+		err   error
+	}{
+		{"unknown sort", exam_api_domain.ProductQuery{SortBy: "colour"}, exam_api_domain.ErrValidation},
+		{"not base64", exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByName, Cursor: "!!!"}, exam_api_domain.ErrMalformedCursor},
+		{"not json", exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByName, Cursor: "bm90IGpzb24"}, exam_api_domain.ErrMalformedCursor},
+		{"another sort order", otherOrder, exam_api_domain.ErrCursorMismatch},
+	}
+	for _, tt := range tests {
+		if _, _, err := buildListQuery(tt.query); !errors.Is(err, tt.err) || !errors.Is(err, exam_api_domain.ErrValidation) {
+			t.Errorf("%s: buildListQuery = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestListPagesThroughNamesByteByByte(t *testing.T) {
+	p := newTestRepository(t)
+	names := []string{"apple", "Zebra", "éclair", "banana", "Banana"}
+	for _, name := range names {
+		mustSave(t, p, exam_api_domain.Product{Name: name, Manufacturer: "Acme"})
+	}
+
+	for _, descending := range []bool{false, true} {
+		want := []string{"Banana", "Zebra", "apple", "banana", "éclair"}
+		if descending {
+			want = []string{"éclair", "banana", "apple", "Zebra", "Banana"}
+		}
+
+		query := exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByName, Descending: descending, Limit: 2}
+		var got []string
+		for pages := 0; pages < len(names); pages++ {
+			page, err := p.List(query)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for _, item := range page.Items {
+				got = append(got, item.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("descending %v: listed %v, want %v", descending, got, want)
+		}
+	}
+}
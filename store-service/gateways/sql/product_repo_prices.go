@@ -0,0 +1,184 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+)
+
+// Price changes are applied by the sweep below, which writes the price through products
+// so the change moves the version on and lands in product_history like any other update.
+const (
+	// a request without a currency keeps the one the product is priced in now
+	sqlSchedulePriceStmt = `INSERT INTO price_changes (id, product_id, price, currency, effective_from, status, created_at)
+						SELECT $1, id, $3, COALESCE(NULLIF($5, ''), currency), $4, 'pending', now()
+						FROM products
+						WHERE id = resolve_product_id($2) AND deleted_at IS NULL
+						RETURNING id, product_id, price, currency, effective_from, status, created_at, applied_at`
+
+	sqlGetPriceChangeStmt = `SELECT id, product_id, price, currency, effective_from, status, created_at, applied_at
+						FROM price_changes
+						WHERE id = $1`
+
+	sqlPriceScheduleStmt = `SELECT id, product_id, price, currency, effective_from, status, created_at, applied_at
+						FROM price_changes
+						WHERE product_id = resolve_product_id($1)
+						ORDER BY effective_from, created_at, id`
+
+	sqlCancelPriceChangeStmt = `UPDATE price_changes
+						SET status = 'cancelled'
+						WHERE id = $1 AND status = 'pending'
+						RETURNING id, product_id, price, currency, effective_from, status, created_at, applied_at`
+
+	// the previous price is looked up over every revision, so only those changing the price or its currency are kept
+	sqlPriceHistoryStmt = `SELECT price, currency, version, recorded_at
+						FROM (
+							SELECT id, COALESCE(price, 0) AS price, currency, version, recorded_at,
+								LAG(COALESCE(price, 0)) OVER (ORDER BY recorded_at, id) AS previous_price,
+								LAG(currency) OVER (ORDER BY recorded_at, id) AS previous_currency
+							FROM product_history
+							WHERE product_id = resolve_product_id($1)
+						) AS revisions
+						WHERE (previous_price, previous_currency) IS DISTINCT FROM (price, currency)
+						ORDER BY recorded_at DESC, id DESC
+						LIMIT $2`
+
+	sqlHasHistoryStmt = `SELECT EXISTS (SELECT 1 FROM product_history WHERE product_id = resolve_product_id($1))`
+
+	// window functions cannot be combined with FOR UPDATE, so the due changes are locked first and ranked after.
+	// Only the latest due change of a product is applied, the others are superseded.
+	// Products in the trash keep their changes pending until they are restored.
+	sqlApplyPriceChangesStmt = `WITH locked AS (
+							SELECT c.id, c.product_id, c.price, c.currency, c.effective_from, c.created_at
+							FROM price_changes c
+							JOIN products p ON p.id = c.product_id AND p.deleted_at IS NULL
+							WHERE c.status = 'pending' AND c.effective_from <= now()
+							FOR UPDATE OF c SKIP LOCKED
+						), due AS (
+							SELECT id, product_id, price, currency,
+								ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY effective_from DESC, created_at DESC, id DESC) AS rank
+							FROM locked
+						), priced AS (
+							UPDATE products
+							SET price = due.price, currency = due.currency, version = products.version + 1
+							FROM due
+							WHERE products.id = due.product_id AND due.rank = 1
+							RETURNING products.id
+						), closed AS (
+							UPDATE price_changes
+							SET
+							    status = CASE WHEN due.rank = 1 THEN 'applied' ELSE 'superseded' END,
+							    applied_at = CASE WHEN due.rank = 1 THEN now() END
+							FROM due
+							WHERE price_changes.id = due.id
+						)
+						SELECT COUNT(*) FROM priced`
+)
+
+func (p *ProductRepository) SchedulePrice(request exam_api_domain.PriceChangeRequest) (exam_api_domain.PriceChange, bool, error) {
+	change, err := scanPriceChange(p.db.QueryRowContext(context.Background(), sqlSchedulePriceStmt,
+		exam_api_domain.NewPriceChangeID(), request.ProductID, request.Price, request.EffectiveFrom, request.Currency))
+	if err == sql.ErrNoRows {
+		return exam_api_domain.PriceChange{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.PriceChange{}, false, err
+	}
+	return change, true, nil
+}
+
+func (p *ProductRepository) GetPriceChange(id string) (exam_api_domain.PriceChange, bool, error) {
+	change, err := scanPriceChange(p.db.QueryRowContext(context.Background(), sqlGetPriceChangeStmt, id))
+	if err == sql.ErrNoRows {
+		return exam_api_domain.PriceChange{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.PriceChange{}, false, err
+	}
+	return change, true, nil
+}
+
+func (p *ProductRepository) PriceSchedule(productID string) ([]exam_api_domain.PriceChange, bool, error) {
+	if exists, err := p.productExists(productID); !exists || err != nil {
+		return nil, exists, err
+	}
+
+	rows, err := p.db.QueryContext(context.Background(), sqlPriceScheduleStmt, productID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeRows(rows)
+
+	changes := make([]exam_api_domain.PriceChange, 0)
+	for rows.Next() {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return changes, true, nil
+}
+
+func (p *ProductRepository) CancelPriceChange(id string) (exam_api_domain.PriceChange, bool, error) {
+	change, err := scanPriceChange(p.db.QueryRowContext(context.Background(), sqlCancelPriceChangeStmt, id))
+	if err == sql.ErrNoRows {
+		current, exists, err := p.GetPriceChange(id)
+		if err != nil || !exists {
+			return exam_api_domain.PriceChange{}, false, err
+		}
+		return current, true, exam_api_domain.ErrPriceChangeClosed
+	}
+	if err != nil {
+		return exam_api_domain.PriceChange{}, false, err
+	}
+
+	return change, true, nil
+}
+
+func (p *ProductRepository) PriceHistory(productID string, limit int) ([]exam_api_domain.PricePoint, bool, error) {
+	ctx := context.Background()
+
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, sqlHasHistoryStmt, productID).Scan(&exists); !exists || err != nil {
+		return nil, false, err
+	}
+
+	rows, err := p.db.QueryContext(ctx, sqlPriceHistoryStmt, productID, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeRows(rows)
+
+	points := make([]exam_api_domain.PricePoint, 0)
+	for rows.Next() {
+		point := exam_api_domain.PricePoint{}
+		if err := rows.Scan(&point.Price, &point.Currency, &point.Version, &point.Since); err != nil {
+			return nil, false, err
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return points, true, nil
+}
+
+// ApplyPriceChanges sets the price and currency of every product with a pending change that fell due
+// and returns the number of changes applied
+func (p *ProductRepository) ApplyPriceChanges() (int, error) {
+	var applied int
+	err := p.db.QueryRowContext(context.Background(), sqlApplyPriceChangesStmt).Scan(&applied)
+	return applied, err
+}
+
+func scanPriceChange(row scanner) (exam_api_domain.PriceChange, error) {
+	change := exam_api_domain.PriceChange{}
+	err := row.Scan(&change.ID, &change.ProductID, &change.Price, &change.Currency, &change.EffectiveFrom, &change.Status, &change.CreatedAt, &change.AppliedAt)
+	return change, err
+}
@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+)
+
+const (
+	sqlLockForRenameStmt = `SELECT id, name, manufacturer, COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version, reserved, COALESCE(stock, 0) - reserved
+						FROM products
+						WHERE id = resolve_product_id($1) AND deleted_at IS NULL
+						FOR UPDATE`
+
+	// an id is taken by any other product, trashed or not, and by the aliases of other products
+	sqlIDTakenStmt = `SELECT EXISTS (SELECT 1 FROM products WHERE id = ANY($1) AND id <> $2)
+						OR EXISTS (SELECT 1 FROM product_aliases WHERE alias = ANY($1) AND product_id <> $2)`
+
+	// moving the product to another id carries its stock levels, ledger, reservations, price changes
+	// and aliases along through ON UPDATE CASCADE. Its history is moved here, the trigger then records the rename.
+	sqlRenameStmt = `WITH renamed AS (
+						UPDATE products
+						SET id = $2, name = $3, manufacturer = $4, version = version + 1
+						WHERE id = $1
+						RETURNING id, name, manufacturer, COALESCE(price, 0) AS price, currency, COALESCE(stock, 0) AS stock, tags, version, reserved,
+							COALESCE(stock, 0) - reserved AS available
+					), rekeyed AS (
+						UPDATE product_history
+						SET product_id = $2
+						WHERE product_id = $1 AND $1 <> $2
+					), unaliased AS (
+						DELETE FROM product_aliases
+						WHERE alias = $2
+					), aliased AS (
+						INSERT INTO product_aliases (alias, product_id)
+						SELECT $1::varchar(64), $2::varchar(64) WHERE $1 <> $2
+					)
+					SELECT id, name, manufacturer, price, currency, stock, tags, version, reserved, available FROM renamed`
+)
+
+func (p *ProductRepository) Rename(rename exam_api_domain.ProductRename) (exam_api_domain.RenameResult, bool, error) {
+	var result exam_api_domain.RenameResult
+	found := false
+	err := p.Atomically(func(tx exam_api_domain.Storage) error {
+		repo := tx.(*ProductRepository)
+		ctx := context.Background()
+
+		var id string
+		current := exam_api_domain.Product{}
+		err := repo.db.QueryRowContext(ctx, sqlLockForRenameStmt, rename.ID).Scan(&id, &current.Name, &current.Manufacturer, &current.Price, &current.Currency, &current.Stock, pq.Array(&current.Tags), &current.Version, &current.Reserved, &current.Available)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+
+		if rename.Version != nil && *rename.Version != current.Version {
+			return exam_api_domain.ErrVersionMismatch
+		}
+
+		renamed := rename.Apply(current)
+		newID, err := repo.ids.RenamedID(id, renamed)
+		if err != nil {
+			return err
+		}
+
+		var taken bool
+		candidates := append([]string{newID}, repo.ids.PreviousIDs(renamed)...)
+		if err := repo.db.QueryRowContext(ctx, sqlIDTakenStmt, pq.Array(candidates), id).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return exam_api_domain.ErrIDTaken
+		}
+
+		product := exam_api_domain.Product{}
+		err = repo.db.QueryRowContext(ctx, sqlRenameStmt, id, newID, renamed.Name, renamed.Manufacturer).Scan(&result.ID, &product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock, pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available)
+		if err != nil {
+			return err
+		}
+
+		result.Product = product
+		if newID != id {
+			result.PreviousID = id
+		}
+		return nil
+	})
+	return result, found, err
+}
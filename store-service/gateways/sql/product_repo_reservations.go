@@ -0,0 +1,186 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+	"time"
+)
+
+// The reserved column of a product and of its warehouse levels always holds the sum of their pending reservations.
+// Every statement below moves a reservation and the matching level and product counters together,
+// so they never drift apart and the availability check is a single conditional update.
+const (
+	sqlReserveStmt = `WITH held AS (
+							UPDATE stock_levels
+							SET reserved = reserved + $3
+							WHERE product_id = resolve_product_id($2) AND warehouse_id = $5 AND quantity - reserved >= $3
+								AND EXISTS (SELECT 1 FROM products WHERE id = resolve_product_id($2) AND deleted_at IS NULL)
+							RETURNING product_id
+						), counted AS (
+							UPDATE products
+							SET reserved = products.reserved + $3
+							FROM held
+							WHERE products.id = held.product_id
+						)
+						INSERT INTO reservations (id, product_id, warehouse_id, quantity, status, created_at, expires_at)
+						SELECT $1, held.product_id, $5, $3, 'pending', now(), now() + make_interval(secs => $4)
+						FROM held
+						RETURNING id, product_id, warehouse_id, quantity, status, created_at, expires_at`
+
+	sqlGetReservationStmt = `SELECT id, product_id, warehouse_id, quantity, status, created_at, expires_at
+						FROM reservations
+						WHERE id = $1`
+
+	sqlConfirmReservationStmt = `WITH confirmed AS (
+							UPDATE reservations
+							SET status = 'confirmed'
+							WHERE id = $1 AND status = 'pending' AND expires_at > now()
+							RETURNING id, product_id, warehouse_id, quantity, status, created_at, expires_at
+						), levelled AS (
+							UPDATE stock_levels
+							SET
+							    quantity = stock_levels.quantity - confirmed.quantity,
+							    reserved = stock_levels.reserved - confirmed.quantity
+							FROM confirmed
+							WHERE stock_levels.product_id = confirmed.product_id AND stock_levels.warehouse_id = confirmed.warehouse_id
+						), sold AS (
+							UPDATE products
+							SET
+							    stock = COALESCE(products.stock, 0) - confirmed.quantity,
+							    reserved = products.reserved - confirmed.quantity,
+							    version = products.version + 1
+							FROM confirmed
+							WHERE products.id = confirmed.product_id
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, warehouse_id, delta, reason, reference)
+							SELECT product_id, warehouse_id, -quantity, 'sale', id FROM confirmed
+						)
+						SELECT id, product_id, warehouse_id, quantity, status, created_at, expires_at FROM confirmed`
+
+	sqlReleaseReservationStmt = `WITH released AS (
+							UPDATE reservations
+							SET status = 'released'
+							WHERE id = $1 AND status = 'pending'
+							RETURNING id, product_id, warehouse_id, quantity, status, created_at, expires_at
+						), levelled AS (
+							UPDATE stock_levels
+							SET reserved = stock_levels.reserved - released.quantity
+							FROM released
+							WHERE stock_levels.product_id = released.product_id AND stock_levels.warehouse_id = released.warehouse_id
+						), freed AS (
+							UPDATE products
+							SET reserved = products.reserved - released.quantity
+							FROM released
+							WHERE products.id = released.product_id
+						)
+						SELECT id, product_id, warehouse_id, quantity, status, created_at, expires_at FROM released`
+
+	sqlExpireReservationsStmt = `WITH expired AS (
+							UPDATE reservations
+							SET status = 'expired'
+							WHERE status = 'pending' AND expires_at <= now()
+							RETURNING product_id, warehouse_id, quantity
+						), levelled AS (
+							UPDATE stock_levels
+							SET reserved = stock_levels.reserved - e.quantity
+							FROM (SELECT product_id, warehouse_id, SUM(quantity) AS quantity FROM expired GROUP BY product_id, warehouse_id) AS e
+							WHERE stock_levels.product_id = e.product_id AND stock_levels.warehouse_id = e.warehouse_id
+						), freed AS (
+							UPDATE products
+							SET reserved = products.reserved - e.quantity
+							FROM (SELECT product_id, SUM(quantity) AS quantity FROM expired GROUP BY product_id) AS e
+							WHERE products.id = e.product_id
+						)
+						SELECT COUNT(*) FROM expired`
+
+	sqlPruneReservationsStmt = `DELETE FROM reservations
+						WHERE status <> 'pending' AND expires_at < now() - make_interval(secs => $1)`
+)
+
+// reservationRetention is how long closed reservations are kept around after they expire
+const reservationRetention = time.Hour
+
+func (p *ProductRepository) Reserve(request exam_api_domain.ReservationRequest) (exam_api_domain.Reservation, bool, error) {
+	ctx := context.Background()
+
+	warehouse := request.WarehouseID()
+	if exists, err := p.stockTarget(request.ProductID, warehouse); !exists || err != nil {
+		return exam_api_domain.Reservation{}, exists, err
+	}
+
+	reservation, err := scanReservation(p.db.QueryRowContext(ctx, sqlReserveStmt,
+		exam_api_domain.NewReservationID(), request.ProductID, request.Quantity, request.TTL().Seconds(), warehouse))
+	if err == sql.ErrNoRows {
+		// the product was checked above, so nothing was held because too little of its stock is available
+		return exam_api_domain.Reservation{}, true, exam_api_domain.ErrInsufficientStock
+	}
+	if err != nil {
+		return exam_api_domain.Reservation{}, false, err
+	}
+
+	return reservation, true, nil
+}
+
+func (p *ProductRepository) GetReservation(id string) (exam_api_domain.Reservation, bool, error) {
+	reservation, err := scanReservation(p.db.QueryRowContext(context.Background(), sqlGetReservationStmt, id))
+	if err == sql.ErrNoRows {
+		return exam_api_domain.Reservation{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.Reservation{}, false, err
+	}
+	return reservation, true, nil
+}
+
+func (p *ProductRepository) ConfirmReservation(id string) (exam_api_domain.Reservation, bool, error) {
+	return p.closeReservation(sqlConfirmReservationStmt, id)
+}
+
+func (p *ProductRepository) ReleaseReservation(id string) (exam_api_domain.Reservation, bool, error) {
+	return p.closeReservation(sqlReleaseReservationStmt, id)
+}
+
+// ExpireReservations closes every pending reservation past its expiry, handing its stock back,
+// and prunes closed reservations that expired more than reservationRetention ago.
+// It returns the number of reservations that expired.
+func (p *ProductRepository) ExpireReservations() (int, error) {
+	ctx := context.Background()
+
+	var expired int
+	if err := p.db.QueryRowContext(ctx, sqlExpireReservationsStmt).Scan(&expired); err != nil {
+		return 0, err
+	}
+
+	rows, err := p.db.QueryContext(ctx, sqlPruneReservationsStmt, reservationRetention.Seconds())
+	if err != nil {
+		return expired, err
+	}
+	closeRows(rows)
+
+	return expired, nil
+}
+
+// closeReservation runs a statement moving a pending reservation to its final status.
+// When it matches nothing the reservation is either missing or no longer pending.
+func (p *ProductRepository) closeReservation(stmt string, id string) (exam_api_domain.Reservation, bool, error) {
+	reservation, err := scanReservation(p.db.QueryRowContext(context.Background(), stmt, id))
+	if err == sql.ErrNoRows {
+		current, exists, err := p.GetReservation(id)
+		if err != nil || !exists {
+			return exam_api_domain.Reservation{}, false, err
+		}
+		return current, true, exam_api_domain.ErrReservationClosed
+	}
+	if err != nil {
+		return exam_api_domain.Reservation{}, false, err
+	}
+
+	return reservation, true, nil
+}
+
+func scanReservation(row rowScanner) (exam_api_domain.Reservation, error) {
+	reservation := exam_api_domain.Reservation{}
+	err := row.Scan(&reservation.ID, &reservation.ProductID, &reservation.Warehouse, &reservation.Quantity, &reservation.Status, &reservation.CreatedAt, &reservation.ExpiresAt)
+	return reservation, err
+}
@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"context"
+	exam_api_domain "exam-store/domain"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// sqlSearchStmt ranks products by full-text relevance over the weighted search column
+// (see schema.sql) plus trigram similarity of the name, which catches typos the
+// full-text match misses. $1 is a prefix tsquery, $2 the raw text and $3 the limit.
+const sqlSearchStmt = `SELECT id, name, COALESCE(manufacturer, ''), COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version,
+						reserved, COALESCE(stock, 0) - reserved,
+						ts_rank(search, q) + similarity(immutable_unaccent(lower(name)), immutable_unaccent(lower($2))) AS score
+					FROM products, to_tsquery('simple', immutable_unaccent($1)) AS q
+					WHERE deleted_at IS NULL AND (search @@ q
+						OR immutable_unaccent(lower(name)) % immutable_unaccent(lower($2)))
+					ORDER BY score DESC, id
+					LIMIT $3`
+
+func (p *ProductRepository) Search(text string, limit int) ([]exam_api_domain.SearchResult, error) {
+	results := make([]exam_api_domain.SearchResult, 0)
+
+	tsQuery := prefixTSQuery(text)
+	if tsQuery == "" {
+		return results, nil
+	}
+
+	ctx := context.Background()
+	rows, err := p.db.QueryContext(ctx, sqlSearchStmt, tsQuery, text, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		result := exam_api_domain.SearchResult{}
+		if err := rows.Scan(&result.ID, &result.Name, &result.Manufacturer, &result.Price, &result.Currency, &result.Stock, pq.Array(&result.Tags), &result.Version, &result.Reserved, &result.Available, &result.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// prefixTSQuery turns free text into a tsquery matching any of its words as a prefix.
+// Only letters and digits are kept, so the result is always valid tsquery syntax.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " | ")
+}
@@ -0,0 +1,51 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"lapte", "lapte:*"},
+		{"Lapte UHT", "lapte:* | uht:*"},
+		{"  lapte,   uht  ", "lapte:* | uht:*"},
+		{"lapte & !uht:* | (zahar)", "lapte:* | uht:* | zahar:*"},
+		{"O'Reilly", "o:* | reilly:*"},
+		{"4k tv", "4k:* | tv:*"},
+		{"Lăptărie Şoim", "lăptărie:* | şoim:*"},
+		{"", ""},
+		{"!!! -- ?", ""},
+	}
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.text); got != tt.want {
+			t.Errorf("prefixTSQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanksNameMatchesFirst(t *testing.T) {
+	p := newTestRepository(t)
+	byTag := mustSave(t, p, exam_api_domain.Product{Name: "Branza", Manufacturer: "Napolact", Tags: []string{"lapte"}})
+	byName := mustSave(t, p, exam_api_domain.Product{Name: "Lapte", Manufacturer: "Napolact"})
+	mustSave(t, p, exam_api_domain.Product{Name: "Paine", Manufacturer: "Vel Pitar"})
+
+	results, err := p.Search("Lâpte", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != byName || results[1].ID != byTag {
+		t.Errorf("Search = %+v, want the name match ranked above the tag match", results)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("scores %v and %v, want the name match to score higher", results[0].Score, results[1].Score)
+	}
+
+	results, err = p.Search("?!", 10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search of punctuation = %+v, %v, want no results", results, err)
+	}
+}
@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+)
+
+// Stock is adjusted relative to the stored value of the warehouse level in a single conditional statement,
+// so concurrent adjustments never read a stale stock and can never take away reserved stock.
+// The same statement moves the product totals along and records the adjustment in the stock ledger.
+const (
+	// a warehouse only gets a level for a product once stock is added to it
+	sqlEnsureLevelStmt = `INSERT INTO stock_levels (product_id, warehouse_id)
+						SELECT id, $2 FROM products WHERE id = resolve_product_id($1) AND deleted_at IS NULL
+						ON CONFLICT (product_id, warehouse_id) DO NOTHING`
+
+	sqlAdjustStockStmt = `WITH levelled AS (
+							UPDATE stock_levels
+							SET quantity = quantity + $2
+							WHERE product_id = resolve_product_id($1) AND warehouse_id = $6 AND quantity - reserved + $2 >= 0
+								AND EXISTS (SELECT 1 FROM products WHERE id = resolve_product_id($1) AND deleted_at IS NULL)
+							RETURNING product_id
+						), adjusted AS (
+							UPDATE products
+							SET
+							    stock = COALESCE(products.stock, 0) + $2,
+							    version = products.version + 1
+							FROM levelled
+							WHERE products.id = levelled.product_id
+							RETURNING products.id, products.name, products.manufacturer, COALESCE(products.price, 0) AS price, products.currency,
+								products.stock, products.tags, products.version, products.reserved
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, warehouse_id, delta, reason, actor, reference)
+							SELECT id, $6, $2, $3, NULLIF($4, ''), NULLIF($5, '') FROM adjusted WHERE $2 <> 0
+						)
+						SELECT name, manufacturer, price, currency, stock, tags, version, reserved, stock - reserved FROM adjusted`
+
+	sqlEnsureLevelsBatchStmt = `INSERT INTO stock_levels (product_id, warehouse_id)
+						SELECT products.id, d.warehouse
+						FROM jsonb_to_recordset($1::jsonb) AS d(id varchar(64), warehouse varchar(64), delta integer)
+						JOIN products ON products.id = resolve_product_id(d.id) AND products.deleted_at IS NULL
+						WHERE d.delta > 0
+						ON CONFLICT (product_id, warehouse_id) DO NOTHING`
+
+	sqlAdjustStockBatchStmt = `WITH levelled AS (
+							UPDATE stock_levels
+							SET quantity = stock_levels.quantity + d.delta
+							FROM jsonb_to_recordset($1::jsonb)
+								AS d(id varchar(64), delta integer, warehouse varchar(64), reason varchar(16), actor varchar(64), reference varchar(64))
+							WHERE stock_levels.product_id = resolve_product_id(d.id) AND stock_levels.warehouse_id = d.warehouse
+								AND stock_levels.quantity - stock_levels.reserved + d.delta >= 0
+								AND EXISTS (SELECT 1 FROM products WHERE id = stock_levels.product_id AND deleted_at IS NULL)
+							RETURNING stock_levels.product_id AS id, d.id AS requested, d.delta, d.warehouse, d.reason, d.actor, d.reference
+						), adjusted AS (
+							UPDATE products
+							SET
+							    stock = COALESCE(products.stock, 0) + levelled.delta,
+							    version = products.version + 1
+							FROM levelled
+							WHERE products.id = levelled.id
+							RETURNING levelled.requested, products.name, products.manufacturer, COALESCE(products.price, 0) AS price, products.currency,
+								products.stock, products.tags, products.version, products.reserved
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, warehouse_id, delta, reason, actor, reference)
+							SELECT id, warehouse, delta, reason, NULLIF(actor, ''), NULLIF(reference, '') FROM levelled WHERE delta <> 0
+						)
+						SELECT requested, name, manufacturer, price, currency, stock, tags, version, reserved, stock - reserved FROM adjusted`
+)
+
+func (p *ProductRepository) AdjustStock(adjustment exam_api_domain.StockAdjustment) (exam_api_domain.Product, bool, error) {
+	ctx := context.Background()
+
+	id, warehouse := adjustment.ID, adjustment.WarehouseID()
+	if exists, err := p.stockTarget(id, warehouse); !exists || err != nil {
+		return exam_api_domain.Product{}, exists, err
+	}
+
+	if adjustment.Delta > 0 {
+		if err := p.exec(ctx, sqlEnsureLevelStmt, id, warehouse); err != nil {
+			return exam_api_domain.Product{}, false, err
+		}
+	}
+
+	product := exam_api_domain.Product{}
+	err := p.db.QueryRowContext(ctx, sqlAdjustStockStmt, id, adjustment.Delta,
+		string(adjustment.MovementReason()), adjustment.Actor, adjustment.Reference, warehouse).
+		Scan(&product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock, pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available)
+	if err == sql.ErrNoRows {
+		// the product was checked above, so nothing was updated because too little of its stock is available
+		return exam_api_domain.Product{}, true, exam_api_domain.ErrInsufficientStock
+	}
+	if err != nil {
+		return exam_api_domain.Product{}, false, err
+	}
+
+	return product, true, nil
+}
+
+func (p *ProductRepository) AdjustStockBatch(adjustments []exam_api_domain.StockAdjustment) (map[string]exam_api_domain.Product, error) {
+	// reasons and warehouses are defaulted here so the ledger never holds an empty one
+	normalized := make([]exam_api_domain.StockAdjustment, 0, len(adjustments))
+	for _, adjustment := range adjustments {
+		adjustment.Reason = adjustment.MovementReason()
+		adjustment.Warehouse = adjustment.WarehouseID()
+		normalized = append(normalized, adjustment)
+	}
+
+	payload, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := p.exec(ctx, sqlEnsureLevelsBatchStmt, string(payload)); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.QueryContext(ctx, sqlAdjustStockBatchStmt, string(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	products := make(map[string]exam_api_domain.Product, len(adjustments))
+	for rows.Next() {
+		var prodID string
+		product := exam_api_domain.Product{}
+
+		if err := rows.Scan(&prodID, &product.Name, &product.Manufacturer, &product.Price, &product.Currency, &product.Stock, pq.Array(&product.Tags), &product.Version, &product.Reserved, &product.Available); err != nil {
+			return nil, err
+		}
+
+		products[prodID] = product
+	}
+
+	return products, rows.Err()
+}
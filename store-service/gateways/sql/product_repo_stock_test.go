@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"errors"
+	exam_api_domain "exam-store/domain"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentDecrementsNeverTakeStockBelowZero(t *testing.T) {
+	p := newTestRepository(t)
+	id := mustSave(t, p, exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 50})
+
+	var mu sync.Mutex
+	taken, refused := 0, 0
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 80; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product, _, err := p.AdjustStock(exam_api_domain.StockAdjustment{ID: id, Delta: -1})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && product.Stock >= 0:
+				taken++
+			case errors.Is(err, exam_api_domain.ErrInsufficientStock):
+				refused++
+			default:
+				t.Errorf("AdjustStock = %+v, %v", product, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken != 50 || refused != 30 {
+		t.Errorf("%d decrements taken and %d refused, want 50 and 30", taken, refused)
+	}
+	if product, _, _ := p.Get(id); product.Stock != 0 {
+		t.Errorf("stock %d, want 0", product.Stock)
+	}
+	if levels, _, _ := p.StockLevels(id); len(levels) != 1 || levels[0].Quantity != 0 {
+		t.Errorf("stock levels %+v, want main at 0", levels)
+	}
+}
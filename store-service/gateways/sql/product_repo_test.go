@@ -0,0 +1,36 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+)
+
+func TestUpdateProductInsertedOutsideTheRepository(t *testing.T) {
+	p := newTestRepository(t)
+	if _, err := p.conn.Exec(`INSERT INTO products (id, name, tags) VALUES ('lapte', 'Lapte', ARRAY['lactate', 'uht'])`); err != nil {
+		t.Fatalf("inserting product: %v", err)
+	}
+
+	found, err := p.Update("lapte", exam_api_domain.ProductDiff{Diff: exam_api_domain.ProductPatch{
+		Price: exam_api_domain.Value(750),
+		Stock: exam_api_domain.Value(20),
+	}})
+	if err != nil || !found {
+		t.Fatalf("Update = %v, %v, want the product updated", found, err)
+	}
+
+	product, _, err := p.Get("lapte")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if product.Price != 750 || product.Stock != 20 || product.Available != 20 {
+		t.Errorf("product %+v, want price 750 and 20 in stock", product)
+	}
+	levels, _, err := p.StockLevels("lapte")
+	if err != nil {
+		t.Fatalf("StockLevels: %v", err)
+	}
+	if len(levels) != 1 || levels[0].Warehouse != exam_api_domain.DefaultWarehouse || levels[0].Quantity != 20 {
+		t.Errorf("levels %+v, want all 20 in %s", levels, exam_api_domain.DefaultWarehouse)
+	}
+}
@@ -0,0 +1,81 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// A trashed product has deleted_at set and is left out of every other statement.
+// Purging deletes the row for good, cascading to its stock levels, ledger and reservations.
+const (
+	sqlTrashListStmt = `SELECT id, name, COALESCE(manufacturer, ''), COALESCE(price, 0), currency, COALESCE(stock, 0), tags, version,
+						reserved, COALESCE(stock, 0) - reserved, deleted_at
+					FROM products
+					WHERE deleted_at IS NOT NULL
+					ORDER BY deleted_at DESC, id`
+
+	sqlRestoreStmt = `UPDATE products SET deleted_at = NULL
+					WHERE id = resolve_product_id($1) AND deleted_at IS NOT NULL
+					RETURNING id`
+
+	sqlPurgeStmt = `DELETE FROM products
+					WHERE id = resolve_product_id($1) AND deleted_at IS NOT NULL
+					RETURNING id`
+
+	sqlPurgeTrashStmt = `WITH purged AS (
+							DELETE FROM products
+							WHERE deleted_at < $1
+							RETURNING id
+						)
+						SELECT COUNT(*) FROM purged`
+)
+
+func (p *ProductRepository) Trash() ([]exam_api_domain.TrashedProduct, error) {
+	rows, err := p.db.QueryContext(context.Background(), sqlTrashListStmt)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	products := make([]exam_api_domain.TrashedProduct, 0)
+	for rows.Next() {
+		item := exam_api_domain.TrashedProduct{}
+		if err := rows.Scan(&item.ID, &item.Name, &item.Manufacturer, &item.Price, &item.Currency, &item.Stock, pq.Array(&item.Tags), &item.Version,
+			&item.Reserved, &item.Available, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, item)
+	}
+	return products, rows.Err()
+}
+
+func (p *ProductRepository) Restore(id string) (bool, error) {
+	return p.trashWrite(sqlRestoreStmt, id)
+}
+
+func (p *ProductRepository) Purge(id string) (bool, error) {
+	return p.trashWrite(sqlPurgeStmt, id)
+}
+
+func (p *ProductRepository) PurgeTrash(before time.Time) (int, error) {
+	var purged int
+	err := p.db.QueryRowContext(context.Background(), sqlPurgeTrashStmt, before).Scan(&purged)
+	return purged, err
+}
+
+// trashWrite runs a statement on a trashed product, returning false if it is not in the trash
+func (p *ProductRepository) trashWrite(stmt string, id string) (bool, error) {
+	var writtenID string
+	err := p.db.QueryRowContext(context.Background(), stmt, id).Scan(&writtenID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package sql
+
+import (
+	exam_api_domain "exam-store/domain"
+	"testing"
+	"time"
+)
+
+// listedIDs returns the ids of every product the listing shows
+func listedIDs(t *testing.T, p *ProductRepository) []string {
+	t.Helper()
+
+	page, err := p.List(exam_api_domain.ProductQuery{SortBy: exam_api_domain.SortByID, Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	ids := make([]string, 0, len(page.Items))
+	for _, item := range page.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestTrashedProductsAreHiddenUntilRestored(t *testing.T) {
+	p := newTestRepository(t)
+	laptop := mustSave(t, p, exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Price: 1000, Stock: 5})
+	mouse := mustSave(t, p, exam_api_domain.Product{Name: "Mouse", Manufacturer: "Acme", Price: 50, Stock: 2})
+	reservation, _, err := p.Reserve(exam_api_domain.ReservationRequest{ProductID: laptop, Quantity: 2, TTLSeconds: 60})
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	if found, err := p.Delete(laptop); err != nil || !found {
+		t.Fatalf("Delete = %v, %v", found, err)
+	}
+
+	if _, found, _ := p.Get(laptop); found {
+		t.Error("Get finds the trashed product")
+	}
+	if ids := listedIDs(t, p); len(ids) != 1 || ids[0] != mouse {
+		t.Errorf("List shows %v, want only %s", ids, mouse)
+	}
+	if results, _ := p.Search("laptop", 10); len(results) != 0 {
+		t.Errorf("Search finds %+v, want nothing", results)
+	}
+	if found, _ := p.Update(laptop, exam_api_domain.ProductDiff{Diff: exam_api_domain.ProductPatch{Price: exam_api_domain.Value(900)}}); found {
+		t.Error("Update changed the trashed product")
+	}
+	if got, _, _ := p.GetReservation(reservation.ID); got.Status != exam_api_domain.ReservationReleased {
+		t.Errorf("reservation %s, want it released with the delete", got.Status)
+	}
+	trash, _ := p.Trash()
+	if len(trash) != 1 || trash[0].ID != laptop || trash[0].Reserved != 0 || trash[0].Stock != 5 {
+		t.Fatalf("Trash = %+v, want the laptop with its stock and nothing reserved", trash)
+	}
+
+	if found, err := p.Restore(laptop); err != nil || !found {
+		t.Fatalf("Restore = %v, %v", found, err)
+	}
+	if found, _ := p.Restore(laptop); found {
+		t.Error("Restore of a product no longer in the trash succeeded")
+	}
+
+	if product, found, _ := p.Get(laptop); !found || product.Price != 1000 || product.Stock != 5 || product.Available != 5 {
+		t.Errorf("Get = %+v, %v, want the product as it was deleted", product, found)
+	}
+	if ids := listedIDs(t, p); len(ids) != 2 {
+		t.Errorf("List shows %v, want both products", ids)
+	}
+	if results, _ := p.Search("laptop", 10); len(results) != 1 || results[0].ID != laptop {
+		t.Errorf("Search finds %+v, want the restored product", results)
+	}
+	if trash, _ := p.Trash(); len(trash) != 0 {
+		t.Errorf("Trash = %+v, want it empty", trash)
+	}
+}
+
+func TestPurgeTrashKeepsProductsDeletedWithinRetention(t *testing.T) {
+	p := newTestRepository(t)
+	old := mustSave(t, p, exam_api_domain.Product{Name: "Laptop", Manufacturer: "Acme", Stock: 5})
+	recent := mustSave(t, p, exam_api_domain.Product{Name: "Mouse", Manufacturer: "Acme", Stock: 2})
+	kept := mustSave(t, p, exam_api_domain.Product{Name: "Keyboard", Manufacturer: "Acme", Stock: 1})
+
+	if _, err := p.Delete(old); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// deleted_at is stamped with the start of the transaction, the pause keeps the stamps apart
+	time.Sleep(5 * time.Millisecond)
+	if _, err := p.Delete(recent); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	trash, _ := p.Trash()
+	if len(trash) != 2 || trash[0].ID != recent {
+		t.Fatalf("Trash = %+v, want the most recently deleted product first", trash)
+	}
+	// the cutoff is taken from the database so that it does not depend on the clocks agreeing
+	cutoff := trash[0].DeletedAt
+
+	purged, err := p.PurgeTrash(cutoff)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash = %d, %v, want 1 purged", purged, err)
+	}
+	if trash, _ := p.Trash(); len(trash) != 1 || trash[0].ID != recent {
+		t.Errorf("Trash = %+v, want only the product deleted at the cutoff", trash)
+	}
+	if found, _ := p.Restore(old); found {
+		t.Error("Restore brought back a purged product")
+	}
+	if _, found, _ := p.StockMovements(old, "", 10); found {
+		t.Error("the ledger of a purged product is kept")
+	}
+	if _, found, _ := p.History(old, 10); !found {
+		t.Error("the history of a purged product is dropped")
+	}
+	if _, found, _ := p.Get(kept); !found {
+		t.Error("PurgeTrash removed a product that was never deleted")
+	}
+
+	if found, _ := p.Purge(kept); found {
+		t.Error("Purge removed a product that is not in the trash")
+	}
+	if found, err := p.Purge(recent); err != nil || !found {
+		t.Errorf("Purge = %v, %v", found, err)
+	}
+	if trash, _ := p.Trash(); len(trash) != 0 {
+		t.Errorf("Trash = %+v, want it empty", trash)
+	}
+}
@@ -0,0 +1,191 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	exam_api_domain "exam-store/domain"
+
+	"github.com/lib/pq"
+)
+
+// Every product has a level in the main warehouse from the moment it is created,
+// other warehouses get one the first time stock is added or transferred to them.
+// Statements moving stock between levels lock the levels they read, the totals of the product follow along.
+const (
+	sqlCreateWarehouseStmt = `INSERT INTO warehouses (id, name, location)
+						VALUES ($1, $2, NULLIF($3, ''))
+						ON CONFLICT (id) DO NOTHING
+						RETURNING id`
+
+	sqlGetWarehouseStmt = `SELECT id, name, COALESCE(location, '') FROM warehouses WHERE id = $1`
+
+	sqlListWarehousesStmt = `SELECT id, name, COALESCE(location, '') FROM warehouses ORDER BY id`
+
+	sqlStockTargetStmt = `SELECT EXISTS (SELECT 1 FROM products WHERE id = resolve_product_id($1) AND deleted_at IS NULL),
+							(SELECT COUNT(*) FROM warehouses WHERE id = ANY($2)) = cardinality($2::varchar[])`
+
+	sqlWarehouseExistsStmt = `SELECT EXISTS (SELECT 1 FROM warehouses WHERE id = $1)`
+
+	sqlStockLevelsStmt = `SELECT product_id, warehouse_id, quantity, reserved, quantity - reserved
+						FROM stock_levels
+						WHERE product_id = resolve_product_id($1)
+						ORDER BY warehouse_id`
+
+	sqlWarehouseStockStmt = `SELECT product_id, warehouse_id, quantity, reserved, quantity - reserved
+						FROM stock_levels
+						WHERE warehouse_id = $1
+						ORDER BY product_id`
+
+	// only available stock moves, what is reserved stays where the reservation was made.
+	// The totals of the product do not change, so neither does its version.
+	sqlTransferStockStmt = `WITH taken AS (
+							UPDATE stock_levels
+							SET quantity = quantity - $4
+							WHERE product_id = resolve_product_id($1) AND warehouse_id = $2 AND quantity - reserved >= $4
+								AND EXISTS (SELECT 1 FROM products WHERE id = resolve_product_id($1) AND deleted_at IS NULL)
+							RETURNING product_id, warehouse_id, quantity, reserved
+						), given AS (
+							INSERT INTO stock_levels (product_id, warehouse_id, quantity)
+							SELECT product_id, $3, $4 FROM taken
+							ON CONFLICT (product_id, warehouse_id) DO UPDATE
+							SET quantity = stock_levels.quantity + EXCLUDED.quantity
+							RETURNING product_id, warehouse_id, quantity, reserved
+						), recorded AS (
+							INSERT INTO stock_movements (product_id, warehouse_id, delta, reason, actor, reference)
+							SELECT product_id, warehouse_id, -$4::integer, 'transfer', NULLIF($5, ''), NULLIF($6, '') FROM taken
+							UNION ALL
+							SELECT product_id, warehouse_id, $4, 'transfer', NULLIF($5, ''), NULLIF($6, '') FROM given
+						)
+						SELECT product_id, warehouse_id, quantity, reserved, quantity - reserved FROM taken
+						UNION ALL
+						SELECT product_id, warehouse_id, quantity, reserved, quantity - reserved FROM given`
+)
+
+func (p *ProductRepository) CreateWarehouse(warehouse exam_api_domain.Warehouse) (bool, error) {
+	var id string
+	err := p.db.QueryRowContext(context.Background(), sqlCreateWarehouseStmt, warehouse.ID, warehouse.Name, warehouse.Location).Scan(&id)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (p *ProductRepository) GetWarehouse(id string) (exam_api_domain.Warehouse, bool, error) {
+	warehouse := exam_api_domain.Warehouse{}
+	err := p.db.QueryRowContext(context.Background(), sqlGetWarehouseStmt, id).Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location)
+	if err == sql.ErrNoRows {
+		return exam_api_domain.Warehouse{}, false, nil
+	}
+	if err != nil {
+		return exam_api_domain.Warehouse{}, false, err
+	}
+	return warehouse, true, nil
+}
+
+func (p *ProductRepository) ListWarehouses() ([]exam_api_domain.Warehouse, error) {
+	rows, err := p.db.QueryContext(context.Background(), sqlListWarehousesStmt)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	warehouses := make([]exam_api_domain.Warehouse, 0)
+	for rows.Next() {
+		warehouse := exam_api_domain.Warehouse{}
+		if err := rows.Scan(&warehouse.ID, &warehouse.Name, &warehouse.Location); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+	return warehouses, rows.Err()
+}
+
+func (p *ProductRepository) StockLevels(productID string) ([]exam_api_domain.StockLevel, bool, error) {
+	exists, err := p.productExists(productID)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	levels, err := p.queryLevels(sqlStockLevelsStmt, productID)
+	return levels, true, err
+}
+
+func (p *ProductRepository) WarehouseStock(warehouseID string) ([]exam_api_domain.StockLevel, bool, error) {
+	var exists bool
+	if err := p.db.QueryRowContext(context.Background(), sqlWarehouseExistsStmt, warehouseID).Scan(&exists); err != nil || !exists {
+		return nil, false, err
+	}
+
+	levels, err := p.queryLevels(sqlWarehouseStockStmt, warehouseID)
+	return levels, true, err
+}
+
+func (p *ProductRepository) TransferStock(transfer exam_api_domain.StockTransfer) ([]exam_api_domain.StockLevel, bool, error) {
+	if exists, err := p.stockTarget(transfer.ID, transfer.From, transfer.To); !exists || err != nil {
+		return nil, exists, err
+	}
+
+	levels, err := p.queryLevels(sqlTransferStockStmt, transfer.ID, transfer.From, transfer.To, transfer.Quantity, transfer.Actor, transfer.Reference)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(levels) == 0 {
+		// the product was checked above, so nothing moved because the source has too little available
+		return nil, true, exam_api_domain.ErrInsufficientStock
+	}
+	return levels, true, nil
+}
+
+// stockTarget checks the product and warehouses a stock operation names before it runs.
+// It returns false if the product is missing and ErrUnknownWarehouse if any warehouse is.
+func (p *ProductRepository) stockTarget(productID string, warehouses ...string) (bool, error) {
+	var productExists, warehousesExist bool
+	err := p.db.QueryRowContext(context.Background(), sqlStockTargetStmt, productID, pq.Array(warehouses)).Scan(&productExists, &warehousesExist)
+	if err != nil || !productExists {
+		return false, err
+	}
+	if !warehousesExist {
+		return true, exam_api_domain.ErrUnknownWarehouse
+	}
+	return true, nil
+}
+
+func (p *ProductRepository) productExists(id string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(context.Background(), sqlExistsStmt, id).Scan(&exists)
+	return exists, err
+}
+
+// exec runs a statement that returns no rows, the querier only offers queries
+func (p *ProductRepository) exec(ctx context.Context, stmt string, args ...interface{}) error {
+	rows, err := p.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+func (p *ProductRepository) queryLevels(stmt string, args ...interface{}) ([]exam_api_domain.StockLevel, error) {
+	rows, err := p.db.QueryContext(context.Background(), stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	levels := make([]exam_api_domain.StockLevel, 0)
+	for rows.Next() {
+		level := exam_api_domain.StockLevel{}
+		if err := rows.Scan(&level.ProductID, &level.Warehouse, &level.Quantity, &level.Reserved, &level.Available); err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, rows.Err()
+}
@@ -3,16 +3,23 @@ module exam-store
 go 1.18
 
 require (
+	exam-api v0.0.0-00010101000000-000000000000
+	github.com/alicebob/miniredis/v2 v2.23.0
 	github.com/banzaicloud/logrus-runtime-formatter v0.0.0-20190729070250-5ae5475bae5e
 	github.com/emicklei/go-restful/v3 v3.9.0
+	github.com/go-redis/redis/v8 v8.11.5
 	github.com/lib/pq v1.10.6
 	github.com/sirupsen/logrus v1.9.0
 )
 
 require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20210529063254-f4c35e4016d9 // indirect
 	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 )
 
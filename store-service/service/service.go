@@ -1,21 +1,35 @@
 package service
 
 import (
+	"context"
 	"exam-store/api"
+	"exam-store/gateways/queue"
 	"exam-store/gateways/sql"
 	"net/http"
 	"os"
+	"time"
 
 	runtime "github.com/banzaicloud/logrus-runtime-formatter"
 	"github.com/emicklei/go-restful/v3"
+	"github.com/go-redis/redis/v8"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// trashSweepInterval is how often products past the trash retention are purged
+	trashSweepInterval = time.Minute
+	// idempotencySweepInterval is how often expired idempotency keys are forgotten
+	idempotencySweepInterval = time.Minute
+)
+
 type Service struct {
+	config Config
 }
 
 func NewService() *Service {
-	return &Service{}
+	return &Service{
+		config: LoadConfig(),
+	}
 }
 
 func (s *Service) StartWebService() {
@@ -30,6 +44,7 @@ func (s *Service) StartWebService() {
 
 	ws := new(restful.WebService)
 	restful.Add(ws)
+	restful.DefaultContainer.ServiceErrorHandler(api.WriteServiceError)
 
 	db, err := sql.CreatePostgresConnection(
 		"0.0.0.0:5432", "",
@@ -40,11 +55,112 @@ func (s *Service) StartWebService() {
 		log.Errorf("Failed creating connection=%+v", err)
 	}
 
-	storage := sql.NewProductRepository(db)
+	storage := sql.NewProductRepository(db, s.config.IDs)
+	// products stored under the ids of an earlier strategy stay reachable under the ones the current strategy gives them
+	if aliased, err := storage.AliasCurrentIDs(); err != nil {
+		log.Errorf("Failed to alias products to their current ids, err=%v", err)
+	} else if aliased > 0 {
+		log.Infof("Aliased %d products to their current ids", aliased)
+	}
 
-	apiManager := api.NewAPI(storage)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: s.config.RedisAddr,
+	})
+	consumer := queue.NewConsumer(redisClient, storage)
+	go consumer.Run(context.Background())
+	go sweepReservations(storage, s.config.ReservationSweepInterval)
+	go sweepPrices(storage, s.config.PriceSweepInterval)
+	go sweepTrash(storage, s.config.TrashRetention)
+
+	keys := sql.NewIdempotencyRepository(db)
+	go sweepIdempotencyKeys(keys)
+
+	apiManager := api.NewAPI(storage, s.config.ExchangeRates, s.config.IDs, keys, s.config.IdempotencyTTL)
 	apiManager.RegisterRoutes(ws)
 
 	log.Printf("Started store service on port 8081")
 	log.Fatal(http.ListenAndServe(":8081", nil))
 }
+
+// sweepReservations hands the stock of expired reservations back every interval
+func sweepReservations(storage *sql.ProductRepository, interval time.Duration) {
+	if interval <= 0 {
+		log.Errorf("Reservation sweep interval must be positive, reservations will only expire on confirmation")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := storage.ExpireReservations()
+		if err != nil {
+			log.Errorf("Failed to expire reservations, err=%v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Infof("Expired %d reservations", expired)
+		}
+	}
+}
+
+// sweepPrices applies the price changes that fell due every interval
+func sweepPrices(storage *sql.ProductRepository, interval time.Duration) {
+	if interval <= 0 {
+		log.Errorf("Price sweep interval must be positive, scheduled price changes will not be applied")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		applied, err := storage.ApplyPriceChanges()
+		if err != nil {
+			log.Errorf("Failed to apply price changes, err=%v", err)
+			continue
+		}
+		if applied > 0 {
+			log.Infof("Applied %d scheduled price changes", applied)
+		}
+	}
+}
+
+// sweepTrash purges products that have been in the trash for longer than retention
+func sweepTrash(storage *sql.ProductRepository, retention time.Duration) {
+	if retention <= 0 {
+		log.Infof("Trash retention is not positive, deleted products are kept until purged")
+		return
+	}
+
+	ticker := time.NewTicker(trashSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		purged, err := storage.PurgeTrash(now.Add(-retention))
+		if err != nil {
+			log.Errorf("Failed to purge trash, err=%v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Infof("Purged %d products from trash", purged)
+		}
+	}
+}
+
+// sweepIdempotencyKeys forgets the idempotency keys whose ttl has passed
+func sweepIdempotencyKeys(keys *sql.IdempotencyRepository) {
+	ticker := time.NewTicker(idempotencySweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		expired, err := keys.ExpireIdempotencyKeys(now)
+		if err != nil {
+			log.Errorf("Failed to expire idempotency keys, err=%v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Infof("Expired %d idempotency keys", expired)
+		}
+	}
+}